@@ -1,11 +1,13 @@
 package dcrlibwallet
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/hdkeychain/v2"
 	"github.com/decred/dcrwallet/errors/v2"
 )
 
@@ -128,6 +130,94 @@ func (wallet *Wallet) SpendableForAccount(account int32) (int64, error) {
 	return int64(bals.Spendable), nil
 }
 
+// AccountXPub returns the extended public key of `account` so that the
+// account can be watched from another wallet or shared with the cosigners of
+// a multisig account. The private passphrase is required to export the xpub
+// of a spending wallet, watch-only wallets do not have one.
+func (wallet *Wallet) AccountXPub(account int32, privPass []byte) (string, error) {
+	ctx := wallet.shutdownContext()
+
+	if !wallet.IsWatchingOnlyWallet() {
+		lock := make(chan time.Time, 1)
+		defer func() {
+			for i := range privPass {
+				privPass[i] = 0
+			}
+			lock <- time.Time{} // send matters, not the value
+		}()
+
+		err := wallet.unlock(ctx, privPass, lock)
+		if err != nil {
+			log.Error(err)
+			return "", err
+		}
+	}
+
+	err := wallet.checkAccountXPubsFinal(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	xpub, err := wallet.internal.MasterPubKey(ctx, uint32(account))
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	return xpub.String(), nil
+}
+
+// checkAccountXPubsFinal returns an error if the account xpubs of the wallet
+// may still change. Wallets using the legacy coin type are upgraded to the
+// SLIP0044 coin type during address discovery if no address was used, which
+// changes the xpub of account 0.
+func (wallet *Wallet) checkAccountXPubsFinal(ctx context.Context) error {
+	if wallet.IsWatchingOnlyWallet() || wallet.IsSynced() {
+		return nil
+	}
+
+	coinType, err := wallet.internal.CoinType(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+	if coinType != wallet.chainParams.SLIP0044CoinType {
+		return errors.New(ErrAddressDiscoveryNotDone)
+	}
+
+	return nil
+}
+
+// ImportWatchOnlyAccount adds an account named `accountName` that watches the
+// addresses of `extendedPublicKey` to this watch-only wallet and returns its
+// number. Transactions of the account that were mined before it was imported
+// are only found after a rescan.
+func (wallet *Wallet) ImportWatchOnlyAccount(accountName, extendedPublicKey string) (int32, error) {
+	if !wallet.IsWatchingOnlyWallet() {
+		return 0, errors.New(ErrInvalid)
+	}
+
+	xpub, err := hdkeychain.NewKeyFromString(extendedPublicKey, wallet.chainParams)
+	if err != nil || xpub.IsPrivate() {
+		return 0, errors.New(ErrInvalid)
+	}
+
+	ctx := wallet.shutdownContext()
+	err = wallet.internal.ImportXpubAccount(ctx, accountName, xpub)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, errors.Exist) {
+			return 0, errors.New(ErrExist)
+		}
+		return 0, translateError(err)
+	}
+
+	accountNumber, err := wallet.internal.AccountNumber(ctx, accountName)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return int32(accountNumber), nil
+}
+
 func (wallet *Wallet) NextAccount(accountName string, privPass []byte) (int32, error) {
 	lock := make(chan time.Time, 1)
 	defer func() {
@@ -138,10 +228,10 @@ func (wallet *Wallet) NextAccount(accountName string, privPass []byte) (int32, e
 	}()
 
 	ctx := wallet.shutdownContext()
-	err := wallet.internal.Unlock(ctx, privPass, lock)
+	err := wallet.unlock(ctx, privPass, lock)
 	if err != nil {
 		log.Error(err)
-		return 0, errors.New(ErrInvalidPassphrase)
+		return 0, err
 	}
 
 	accountNumber, err := wallet.internal.NextAccount(ctx, accountName)
@@ -0,0 +1,131 @@
+package dcrlibwallet
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrwallet/errors/v2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	bip39WordListSize      = 2048
+	bip39PBKDF2Iterations  = 2048
+	bip39SeedSize          = 64
+	bip39BitsPerWord       = 11
+	bip39MinMnemonicLength = 12
+	bip39MaxMnemonicLength = 24
+)
+
+var (
+	// bip39WordList and bip39WordIndexes are the word list set with
+	// SetBIP39WordList, nil if the english word list is used.
+	bip39Mu          sync.RWMutex
+	bip39WordList    []string
+	bip39WordIndexes map[string]int
+
+	bip39EnglishOnce        sync.Once
+	bip39EnglishWords       []string
+	bip39EnglishWordIndexes map[string]int
+)
+
+// SetBIP39WordList replaces the english word list of BIP0039 that BIP0039
+// mnemonics of seeds created by other wallets are decoded with, for wallets
+// that create mnemonics from the word list of another language. `wordList`
+// holds the 2048 words of the list in order, separated by whitespace.
+func SetBIP39WordList(wordList string) error {
+	words, wordIndexes, err := parseBIP39WordList(wordList)
+	if err != nil {
+		return err
+	}
+
+	bip39Mu.Lock()
+	bip39WordList = words
+	bip39WordIndexes = wordIndexes
+	bip39Mu.Unlock()
+	return nil
+}
+
+// bip39Words returns the BIP0039 word list set with SetBIP39WordList, or the
+// english word list if none was set, and the index of each word.
+func bip39Words() ([]string, map[string]int) {
+	bip39Mu.RLock()
+	defer bip39Mu.RUnlock()
+	if bip39WordList != nil {
+		return bip39WordList, bip39WordIndexes
+	}
+
+	bip39EnglishOnce.Do(func() {
+		var err error
+		bip39EnglishWords, bip39EnglishWordIndexes, err = parseBIP39WordList(bip39EnglishWordList)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return bip39EnglishWords, bip39EnglishWordIndexes
+}
+
+// parseBIP39WordList returns the words of `wordList`, normalized to lower
+// case, and the index of each word.
+func parseBIP39WordList(wordList string) ([]string, map[string]int, error) {
+	words := strings.Fields(wordList)
+	if len(words) != bip39WordListSize {
+		return nil, nil, errors.New(ErrInvalid)
+	}
+
+	wordIndexes := make(map[string]int, len(words))
+	for i, word := range words {
+		word = norm.NFKD.String(strings.ToLower(word))
+		if _, ok := wordIndexes[word]; ok {
+			return nil, nil, errors.New(ErrInvalid)
+		}
+		words[i] = word
+		wordIndexes[word] = i
+	}
+
+	return words, wordIndexes, nil
+}
+
+// isBIP39MnemonicLength returns true if a BIP0039 mnemonic can have `n` words,
+// i.e. encode 128 to 256 bits of entropy.
+func isBIP39MnemonicLength(n int) bool {
+	return n >= bip39MinMnemonicLength && n <= bip39MaxMnemonicLength && n%3 == 0
+}
+
+// bip39Entropy returns the entropy encoded by the BIP0039 mnemonic `words` and
+// whether the checksum of the mnemonic matches. The words must be in the word
+// list.
+func bip39Entropy(words []string, wordIndexes map[string]int) ([]byte, bool) {
+	totalBits := len(words) * bip39BitsPerWord
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+
+	// the words are 11 bit big endian numbers, the entropy is followed by
+	// the first bits of its SHA256 hash
+	data := make([]byte, (totalBits+7)/8)
+	for i, word := range words {
+		index := wordIndexes[word]
+		for bit := 0; bit < bip39BitsPerWord; bit++ {
+			if index&(1<<uint(bip39BitsPerWord-1-bit)) != 0 {
+				pos := i*bip39BitsPerWord + bit
+				data[pos/8] |= 1 << uint(7-pos%8)
+			}
+		}
+	}
+
+	entropy := data[:entropyBits/8]
+	hash := sha256.Sum256(entropy)
+	mask := byte(0xff) << uint(8-checksumBits)
+	return entropy, hash[0]&mask == data[entropyBits/8]&mask
+}
+
+// bip39Seed returns the seed of the BIP0039 `mnemonic` protected with the
+// optional `passphrase`.
+func bip39Seed(mnemonic []string, passphrase string) []byte {
+	password := norm.NFKD.String(strings.Join(mnemonic, " "))
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(password), []byte(salt), bip39PBKDF2Iterations, bip39SeedSize, sha512.New)
+}
@@ -0,0 +1,52 @@
+package dcrlibwallet
+
+import (
+	"time"
+
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/planetdecred/dcrlibwallet/spv"
+)
+
+// setBirthday validates and sets the birthday of a new wallet from the unix
+// timestamp `birthday` or the block height `birthdayHeight`, 0 if unknown.
+func (wallet *Wallet) setBirthday(birthday int64, birthdayHeight int32) error {
+	if birthday < 0 || birthdayHeight < 0 || birthday > time.Now().Unix() {
+		return errors.New(ErrInvalid)
+	}
+
+	if birthday > 0 {
+		wallet.Birthday = time.Unix(birthday, 0)
+	}
+	wallet.BirthdayHeight = birthdayHeight
+	return nil
+}
+
+// walletBirthdays returns the birthdays of the wallets that have one, for the
+// spv syncer.
+func (mw *MultiWallet) walletBirthdays() map[int]*spv.Birthday {
+	birthdays := make(map[int]*spv.Birthday)
+	for id, wallet := range mw.wallets {
+		if wallet.BirthdayHeight > 0 || !wallet.Birthday.IsZero() {
+			birthdays[id] = &spv.Birthday{
+				Time:   wallet.Birthday,
+				Height: wallet.BirthdayHeight,
+			}
+		}
+	}
+	return birthdays
+}
+
+// birthdayBlockFound saves the height of the block of the birthday time of a
+// wallet found by the spv syncer.
+func (mw *MultiWallet) birthdayBlockFound(walletID int, height int32) {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return
+	}
+
+	log.Infof("[%d] Birthday block is at height %d", walletID, height)
+	wallet.BirthdayHeight = height
+	if err := mw.db.Save(wallet); err != nil {
+		log.Errorf("[%d] Error saving birthday height: %v", walletID, err)
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+// wallet returns the opened wallet with the id in `arg`.
+func (ctx *cliContext) wallet(arg string) (*dcrlibwallet.Wallet, error) {
+	walletID, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet id %q", arg)
+	}
+
+	wallet := ctx.mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, fmt.Errorf("no wallet with id %d", walletID)
+	}
+	return wallet, nil
+}
+
+func checkArgs(args []string, min, max int, usage string) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	return nil
+}
+
+func createWallet(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 1, 1, commands["create"].usage); err != nil {
+		return err
+	}
+
+	passphrase, err := ctx.promptNewPassphrase()
+	if err != nil {
+		return err
+	}
+
+	wallet, err := ctx.mw.CreateNewWallet(args[0], passphrase, dcrlibwallet.PassphraseTypePass)
+	if err != nil {
+		return err
+	}
+
+	seed, err := wallet.DecryptSeed([]byte(passphrase))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created wallet %d (%s)\n\n", wallet.ID, wallet.Name)
+	fmt.Printf("Write down the seed below and keep it safe, it is needed to restore the wallet:\n\n%s\n", seed)
+	return nil
+}
+
+func restoreWallet(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 1, 2, commands["restore"].usage); err != nil {
+		return err
+	}
+
+	// the optional birthday is a block height or a yyyy-mm-dd date
+	var birthday int64
+	var birthdayHeight int32
+	if len(args) > 1 {
+		if height, err := strconv.ParseInt(args[1], 10, 32); err == nil {
+			birthdayHeight = int32(height)
+		} else if date, err := time.Parse("2006-01-02", args[1]); err == nil {
+			birthday = date.Unix()
+		} else {
+			return fmt.Errorf("invalid birthday %q, expected a block height or a yyyy-mm-dd date", args[1])
+		}
+	}
+
+	// seed shares are entered one per line
+	seed, err := ctx.promptLines("Seed or seed shares, one per line, end with an empty line:\n")
+	if err != nil {
+		return err
+	}
+
+	parsedSeed, err := dcrlibwallet.ParseSeedInput(seed)
+	if err != nil {
+		return err
+	}
+	for _, correction := range parsedSeed.Corrections {
+		fmt.Printf("Word %d (%s) is invalid, did you mean %s?\n", correction.Index+1, correction.Word,
+			strings.Join(correction.Suggestions, ", "))
+	}
+	if parsedSeed.ChecksumMismatch {
+		fmt.Println("The seed checksum does not match, check for wrong or swapped words")
+	}
+	if !parsedSeed.Valid {
+		return fmt.Errorf("invalid %s seed", parsedSeed.Format)
+	}
+
+	var seedPassphrase string
+	if parsedSeed.Format == dcrlibwallet.SeedFormatBIP39 {
+		seedPassphrase, err = ctx.promptPassphrase("Seed passphrase (leave empty if none): ")
+		if err != nil {
+			return err
+		}
+	}
+
+	passphrase, err := ctx.promptNewPassphrase()
+	if err != nil {
+		return err
+	}
+
+	wallet, err := ctx.mw.RestoreWalletWithSeedPassphrase(args[0], seed, seedPassphrase, passphrase,
+		dcrlibwallet.PassphraseTypePass, birthday, birthdayHeight)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored wallet %d (%s), run `sync` to discover its accounts and transactions\n", wallet.ID, wallet.Name)
+	return nil
+}
+
+func createWatchOnlyWallet(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 2, 2, commands["watchonly"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.mw.CreateWatchOnlyWallet(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created watch-only wallet %d (%s)\n", wallet.ID, wallet.Name)
+	return nil
+}
+
+func listWallets(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 0, 0, commands["wallets"].usage); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tTYPE\tBEST BLOCK\tSEED BACKED UP")
+	for _, wallet := range ctx.mw.AllWallets() {
+		walletType := "standard"
+		if wallet.IsWatchingOnlyWallet() {
+			walletType = "watch-only"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%t\n", wallet.ID, wallet.Name, walletType, wallet.GetBestBlock(), len(wallet.EncryptedSeed) == 0)
+	}
+	return tw.Flush()
+}
+
+func listAccounts(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 1, 1, commands["accounts"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	accounts, err := wallet.GetAccountsRaw()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NUMBER\tNAME\tTOTAL\tSPENDABLE\tUNCONFIRMED\tLOCKED BY TICKETS")
+	for _, account := range accounts.Acc {
+		balance := account.Balance
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", account.Number, account.Name,
+			dcrutil.Amount(balance.Total), dcrutil.Amount(balance.Spendable),
+			dcrutil.Amount(balance.UnConfirmed), dcrutil.Amount(balance.LockedByTickets))
+	}
+	return tw.Flush()
+}
+
+func address(ctx *cliContext, args []string) error {
+	flags := flag.NewFlagSet("address", flag.ContinueOnError)
+	newAddress := flags.Bool("new", false, "generate a new address")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if err := checkArgs(args, 1, 2, commands["address"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	var account int64
+	if len(args) == 2 {
+		account, err = strconv.ParseInt(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid account number %q", args[1])
+		}
+	}
+
+	var addr string
+	if *newAddress {
+		addr, err = wallet.NextAddress(int32(account))
+	} else {
+		addr, err = wallet.CurrentAddress(int32(account))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(addr)
+	return nil
+}
+
+func send(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 4, 4, commands["send"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	account, err := strconv.ParseInt(args[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid account number %q", args[1])
+	}
+
+	destination := args[2]
+	if !ctx.mw.IsAddressValid(destination) {
+		return fmt.Errorf("invalid address %s", destination)
+	}
+
+	var atomAmount int64
+	sendMax := strings.EqualFold(args[3], "max")
+	if !sendMax {
+		dcrAmount, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %q", args[3])
+		}
+
+		amount, err := dcrutil.NewAmount(dcrAmount)
+		if err != nil {
+			return err
+		}
+		atomAmount = int64(amount)
+	}
+
+	txAuthor := ctx.mw.NewUnsignedTx(wallet, int32(account))
+	txAuthor.AddSendDestination(destination, atomAmount, sendMax)
+
+	feeAndSize, err := txAuthor.EstimateFeeAndSize()
+	if err != nil {
+		return err
+	}
+
+	if sendMax {
+		maxAmount, err := txAuthor.EstimateMaxSendAmount()
+		if err != nil {
+			return err
+		}
+		atomAmount = maxAmount.AtomValue
+	}
+
+	fmt.Printf("Sending %s to %s with a fee of %s (%d bytes)\n", dcrutil.Amount(atomAmount), destination,
+		dcrutil.Amount(feeAndSize.Fee.AtomValue), feeAndSize.EstimatedSignedSize)
+
+	passphrase, err := ctx.promptPassphrase("Private passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	txHash, err := txAuthor.Broadcast([]byte(passphrase))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent transaction %x\n", reverse(txHash))
+	return nil
+}
+
+func history(ctx *cliContext, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	offset := flags.Int("offset", 0, "number of transactions to skip")
+	limit := flags.Int("limit", 20, "maximum number of transactions to list, 0 for all")
+	filter := flags.Int("filter", int(dcrlibwallet.TxFilterAll), "transaction filter")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if err := checkArgs(args, 1, 1, commands["history"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	transactions, err := wallet.GetTransactionsRaw(int32(*offset), int32(*limit), int32(*filter), true)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tHASH\tTYPE\tDIRECTION\tAMOUNT\tFEE\tHEIGHT")
+	for _, tx := range transactions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04"),
+			tx.Hash, tx.Type, direction(tx.Direction), dcrutil.Amount(tx.Amount), dcrutil.Amount(tx.Fee), tx.BlockHeight)
+	}
+	return tw.Flush()
+}
+
+func direction(txDirection int32) string {
+	switch txDirection {
+	case dcrlibwallet.TxDirectionSent:
+		return "sent"
+	case dcrlibwallet.TxDirectionReceived:
+		return "received"
+	case dcrlibwallet.TxDirectionTransferred:
+		return "transferred"
+	default:
+		return "-"
+	}
+}
+
+func spvSync(ctx *cliContext, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	keep := flags.Bool("keep", false, "keep running after sync completes, displaying new transactions and blocks")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	listener := newSyncListener()
+	err := ctx.mw.AddSyncProgressListener(listener, "cli")
+	if err != nil {
+		return err
+	}
+	if *keep {
+		err = ctx.mw.AddTxAndBlockEventListener(listener, "cli")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ctx.mw.SpvSync()
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	for {
+		select {
+		case err := <-listener.done:
+			if err != nil || !*keep {
+				ctx.mw.CancelSync()
+				return err
+			}
+		case <-interrupt:
+			fmt.Println("\nStopping sync")
+			ctx.mw.CancelSync()
+			return nil
+		}
+	}
+}
+
+func signMessage(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 3, 3, commands["sign"].usage); err != nil {
+		return err
+	}
+
+	wallet, err := ctx.wallet(args[0])
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := ctx.promptPassphrase("Private passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	signature, err := wallet.SignMessage([]byte(passphrase), args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(base64.StdEncoding.EncodeToString(signature))
+	return nil
+}
+
+func verifyMessage(ctx *cliContext, args []string) error {
+	if err := checkArgs(args, 3, 3, commands["verify"].usage); err != nil {
+		return err
+	}
+
+	valid, err := ctx.mw.VerifyMessage(args[0], args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid signature")
+	}
+	fmt.Println("Signature verified")
+	return nil
+}
+
+// reverse returns a reversed copy of a hash to display it in the usual byte order.
+func reverse(hash []byte) []byte {
+	reversed := make([]byte, len(hash))
+	for i, b := range hash {
+		reversed[len(hash)-1-i] = b
+	}
+	return reversed
+}
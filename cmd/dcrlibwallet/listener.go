@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+// syncListener prints sync progress and, when registered for them,
+// transaction and block events. `done` receives once sync completes or fails.
+type syncListener struct {
+	done chan error
+}
+
+func newSyncListener() *syncListener {
+	return &syncListener{
+		done: make(chan error, 1),
+	}
+}
+
+func (l *syncListener) finish(err error) {
+	select {
+	case l.done <- err:
+	default:
+	}
+}
+
+func (l *syncListener) OnSyncStarted(wasRestarted bool) {
+	if wasRestarted {
+		fmt.Println("Sync restarted")
+	} else {
+		fmt.Println("Sync started")
+	}
+}
+
+func (l *syncListener) OnPeerConnectedOrDisconnected(numberOfConnectedPeers int32) {
+	fmt.Printf("Connected peers: %d\n", numberOfConnectedPeers)
+}
+
+func (l *syncListener) OnHeadersFetchProgress(report *dcrlibwallet.HeadersFetchProgressReport) {
+	fmt.Printf("Fetching headers: block %d of %d, %d%% total, %ds remaining\n", report.CurrentHeaderHeight,
+		report.TotalHeadersToFetch, report.TotalSyncProgress, report.TotalTimeRemainingSeconds)
+}
+
+func (l *syncListener) OnAddressDiscoveryProgress(report *dcrlibwallet.AddressDiscoveryProgressReport) {
+	fmt.Printf("Discovering addresses for wallet %d: %d%%, %d%% total\n", report.WalletID,
+		report.AddressDiscoveryProgress, report.TotalSyncProgress)
+}
+
+func (l *syncListener) OnHeadersRescanProgress(report *dcrlibwallet.HeadersRescanProgressReport) {
+	fmt.Printf("Rescanning wallet %d: block %d of %d, %d%% total\n", report.WalletID,
+		report.CurrentRescanHeight, report.TotalHeadersToScan, report.TotalSyncProgress)
+}
+
+func (l *syncListener) OnSyncCompleted() {
+	fmt.Println("Sync completed")
+	l.finish(nil)
+}
+
+func (l *syncListener) OnSyncCanceled(willRestart bool) {
+	if !willRestart {
+		fmt.Println("Sync canceled")
+		l.finish(nil)
+	}
+}
+
+func (l *syncListener) OnSyncEndedWithError(err error) {
+	l.finish(fmt.Errorf("sync failed: %v", err))
+}
+
+func (l *syncListener) Debug(debugInfo *dcrlibwallet.DebugInfo) {}
+
+func (l *syncListener) OnTxEvent(event *dcrlibwallet.TxEvent) {
+	tx := event.Transaction
+	switch event.Type {
+	case dcrlibwallet.TxEventReceived:
+		fmt.Printf("[wallet %d] received %s in %s\n", event.WalletID, dcrutil.Amount(tx.Amount), tx.Hash)
+	case dcrlibwallet.TxEventSent:
+		fmt.Printf("[wallet %d] sent %s in %s\n", event.WalletID, dcrutil.Amount(tx.Amount), tx.Hash)
+	case dcrlibwallet.TxEventConfirmed:
+		fmt.Printf("[wallet %d] %s has %d confirmation(s)\n", event.WalletID, tx.Hash, event.Confirmations)
+	case dcrlibwallet.TxEventDoubleSpent:
+		fmt.Printf("[wallet %d] %s was double spent\n", event.WalletID, tx.Hash)
+	case dcrlibwallet.TxEventRemovedByReorg:
+		fmt.Printf("[wallet %d] %s was removed by a reorg\n", event.WalletID, tx.Hash)
+	}
+}
+
+func (l *syncListener) OnBlockEvent(event *dcrlibwallet.BlockEvent) {
+	switch event.Type {
+	case dcrlibwallet.BlockEventAttached:
+		fmt.Printf("[wallet %d] block %d attached %s\n", event.WalletID, event.BlockHeight, event.BlockHash)
+	case dcrlibwallet.BlockEventDetached:
+		fmt.Printf("[wallet %d] block %d detached %s\n", event.WalletID, event.BlockHeight, event.BlockHash)
+	}
+}
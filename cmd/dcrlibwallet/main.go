@@ -0,0 +1,244 @@
+// Command dcrlibwallet is a command line wallet built on dcrlibwallet's
+// MultiWallet. It is used to drive the library during development and as an
+// integration test harness without building the mobile apps.
+//
+// Usage:
+//
+//	dcrlibwallet [flags] <command> [arguments]
+//
+// Run `dcrlibwallet help` for the list of commands.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/planetdecred/dcrlibwallet"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type command struct {
+	usage       string
+	description string
+	// needsWallets is set for commands that require the saved wallets to be opened first.
+	needsWallets bool
+	run          func(ctx *cliContext, args []string) error
+}
+
+// commands is populated in init as the command functions refer to it for their usage.
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"create": {
+			usage:       "create <name>",
+			description: "create a new wallet and display its seed",
+			run:         createWallet,
+		},
+		"restore": {
+			usage:       "restore <name> [birthday]",
+			description: "restore a wallet from a seed or seed shares, skipping blocks before a birthday height or yyyy-mm-dd date",
+			run:         restoreWallet,
+		},
+		"watchonly": {
+			usage:       "watchonly <name> <xpub>",
+			description: "create a watch-only wallet from an extended public key",
+			run:         createWatchOnlyWallet,
+		},
+		"wallets": {
+			usage:        "wallets",
+			description:  "list the wallets in the root directory",
+			needsWallets: true,
+			run:          listWallets,
+		},
+		"accounts": {
+			usage:        "accounts <wallet-id>",
+			description:  "list the accounts of a wallet and their balances",
+			needsWallets: true,
+			run:          listAccounts,
+		},
+		"address": {
+			usage:        "address [-new] <wallet-id> [account]",
+			description:  "display the current or a new receive address of an account",
+			needsWallets: true,
+			run:          address,
+		},
+		"send": {
+			usage:        "send <wallet-id> <account> <address> <amount-dcr|max>",
+			description:  "send DCR from an account to an address",
+			needsWallets: true,
+			run:          send,
+		},
+		"history": {
+			usage:        "history [-offset n] [-limit n] [-filter n] <wallet-id>",
+			description:  "list the indexed transactions of a wallet, newest first",
+			needsWallets: true,
+			run:          history,
+		},
+		"sync": {
+			usage:        "sync [-keep]",
+			description:  "sync the wallets with the network using SPV, displaying progress",
+			needsWallets: true,
+			run:          spvSync,
+		},
+		"sign": {
+			usage:        "sign <wallet-id> <address> <message>",
+			description:  "sign a message with the private key of an address",
+			needsWallets: true,
+			run:          signMessage,
+		},
+		"verify": {
+			usage:       "verify <address> <message> <signature>",
+			description: "verify a signed message",
+			run:         verifyMessage,
+		},
+	}
+}
+
+// cliContext holds the MultiWallet and input shared by all commands.
+type cliContext struct {
+	mw    *dcrlibwallet.MultiWallet
+	input *bufio.Reader
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	homeDir, _ := os.UserHomeDir()
+	rootDir := flag.String("root", filepath.Join(homeDir, ".dcrlibwallet"), "wallets root directory")
+	netType := flag.String("net", "testnet3", "network: mainnet, testnet3 or simnet")
+	dbDriver := flag.String("dbdriver", "", "wallet database driver: bdb or badgerdb")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	if flag.NArg() == 0 || flag.Arg(0) == "help" {
+		printUsage()
+		return nil
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", flag.Arg(0))
+	}
+
+	mw, err := dcrlibwallet.NewMultiWallet(*rootDir, *dbDriver, *netType)
+	if err != nil {
+		return err
+	}
+	defer mw.Shutdown()
+
+	ctx := &cliContext{
+		mw:    mw,
+		input: bufio.NewReader(os.Stdin),
+	}
+
+	if cmd.needsWallets {
+		var startupPassphrase string
+		if mw.IsStartupSecuritySet() {
+			startupPassphrase, err = ctx.promptPassphrase("Startup passphrase: ")
+			if err != nil {
+				return err
+			}
+		}
+		if err = mw.OpenWallets([]byte(startupPassphrase)); err != nil {
+			return err
+		}
+	}
+
+	return cmd.run(ctx, flag.Args()[1:])
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [arguments]\n\nFlags:\n", filepath.Base(os.Args[0]))
+	flag.PrintDefaults()
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-55s %s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+// prompt writes `message` to stderr and returns the next line read from stdin.
+func (ctx *cliContext) prompt(message string) (string, error) {
+	fmt.Fprint(os.Stderr, message)
+	line, err := ctx.input.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptLines prompts for lines until an empty line or the end of the input
+// and returns them joined with newlines.
+func (ctx *cliContext) promptLines(message string) (string, error) {
+	fmt.Fprint(os.Stderr, message)
+	var lines []string
+	for {
+		line, err := ctx.input.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+		if err == io.EOF && len(lines) > 0 {
+			break
+		} else if err != nil {
+			return "", err
+		} else if line == "" {
+			break
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// promptPassphrase is like prompt but does not echo the passphrase when
+// stdin is a terminal.
+func (ctx *cliContext) promptPassphrase(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return ctx.prompt(message)
+	}
+
+	fmt.Fprint(os.Stderr, message)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
+
+// promptNewPassphrase prompts for a new passphrase twice and returns it if both entries match.
+func (ctx *cliContext) promptNewPassphrase() (string, error) {
+	passphrase, err := ctx.promptPassphrase("New private passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := ctx.promptPassphrase("Confirm private passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase != confirm {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
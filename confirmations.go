@@ -0,0 +1,157 @@
+package dcrlibwallet
+
+import (
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+const (
+	RequiredConfirmationsConfigKey = "required_confirmations"
+	FinalConfirmationsConfigKey    = "final_confirmations"
+
+	DefaultFinalConfirmations = 6
+)
+
+// SetRequiredConfirmations sets the number of confirmations this wallet
+// requires before a transaction output can be spent. The value must be at
+// least 1 and not more than the wallet's final confirmations.
+func (wallet *Wallet) SetRequiredConfirmations(confirmations int32) error {
+	if confirmations < 1 || confirmations > wallet.FinalConfirmations() {
+		return errors.New(ErrInvalid)
+	}
+
+	wallet.SetInt32ConfigValueForKey(RequiredConfirmationsConfigKey, confirmations)
+	return nil
+}
+
+// FinalConfirmations returns the number of confirmations after which a
+// transaction of this wallet is considered final and no longer tracked.
+func (wallet *Wallet) FinalConfirmations() int32 {
+	return wallet.ReadInt32ConfigValueForKey(FinalConfirmationsConfigKey, DefaultFinalConfirmations)
+}
+
+// SetFinalConfirmations sets the number of confirmations after which a
+// transaction of this wallet is considered final. The value must not be less
+// than the wallet's required confirmations.
+func (wallet *Wallet) SetFinalConfirmations(confirmations int32) error {
+	if confirmations < wallet.confirmationTarget() {
+		return errors.New(ErrInvalid)
+	}
+
+	wallet.SetInt32ConfigValueForKey(FinalConfirmationsConfigKey, confirmations)
+	return nil
+}
+
+// confirmationTarget returns the wallet's required confirmations ignoring
+// the spend unconfirmed setting.
+func (wallet *Wallet) confirmationTarget() int32 {
+	return wallet.ReadInt32ConfigValueForKey(RequiredConfirmationsConfigKey, DefaultRequiredConfirmations)
+}
+
+// confirmationThresholds returns the sorted, distinct confirmation counts at
+// which a confirmed event is published for a transaction of this wallet.
+func (wallet *Wallet) confirmationThresholds() []int32 {
+	thresholds := []int32{1}
+	for _, confirmations := range []int32{wallet.confirmationTarget(), wallet.FinalConfirmations()} {
+		if confirmations > thresholds[len(thresholds)-1] {
+			thresholds = append(thresholds, confirmations)
+		}
+	}
+
+	return thresholds
+}
+
+// trackTxConfirmations saves the block height of a newly mined transaction so
+// that confirmed events can be published as the transaction gets more
+// confirmations, including across restarts.
+func (mw *MultiWallet) trackTxConfirmations(walletID int, txHash string, blockHeight int32) error {
+	var txConfirmation TxConfirmation
+	err := mw.db.Select(q.Eq("WalletID", walletID), q.Eq("Hash", txHash)).First(&txConfirmation)
+	if err == storm.ErrNotFound {
+		return mw.db.Save(&TxConfirmation{
+			WalletID:    walletID,
+			Hash:        txHash,
+			BlockHeight: blockHeight,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	if txConfirmation.BlockHeight == blockHeight {
+		return nil
+	}
+	return mw.db.UpdateField(&txConfirmation, "BlockHeight", blockHeight)
+}
+
+// untrackReorgedTxConfirmations marks tracked transactions mined at or above
+// `forkHeight` as unmined until they are mined again in the new main chain.
+func (mw *MultiWallet) untrackReorgedTxConfirmations(walletID int, forkHeight int32) error {
+	var txConfirmations []TxConfirmation
+	err := mw.db.Select(q.Eq("WalletID", walletID), q.Gte("BlockHeight", forkHeight)).Find(&txConfirmations)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	for i := range txConfirmations {
+		err = mw.db.UpdateField(&txConfirmations[i], "BlockHeight", BlockHeightInvalid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishTxConfirmations publishes a confirmed event for each confirmation
+// threshold newly reached by the tracked transactions of `wallet` at
+// `tipHeight`. Transactions are no longer tracked once they reach the wallet's
+// final confirmations.
+func (mw *MultiWallet) publishTxConfirmations(wallet *Wallet, tipHeight int32) {
+	var txConfirmations []TxConfirmation
+	err := mw.db.Select(q.Eq("WalletID", wallet.ID), q.Gt("BlockHeight", BlockHeightInvalid)).Find(&txConfirmations)
+	if err != nil {
+		if err != storm.ErrNotFound {
+			log.Errorf("[%d] Error reading tracked txs: %v", wallet.ID, err)
+		}
+		return
+	}
+
+	thresholds := wallet.confirmationThresholds()
+	finalConfirmations := thresholds[len(thresholds)-1]
+
+	for i := range txConfirmations {
+		txConfirmation := &txConfirmations[i]
+		confirmations := tipHeight - txConfirmation.BlockHeight + 1
+
+		notifiedConfirmations := txConfirmation.NotifiedConfirmations
+		for _, threshold := range thresholds {
+			if threshold <= notifiedConfirmations || threshold > confirmations {
+				continue
+			}
+
+			tx := &Transaction{}
+			err = wallet.txDB.FindOne("Hash", txConfirmation.Hash, tx)
+			if err != nil {
+				log.Errorf("[%d] Error reading tracked tx %s: %v", wallet.ID, txConfirmation.Hash, err)
+				break
+			}
+
+			mw.publishTxEvent(&TxEvent{
+				Type:          TxEventConfirmed,
+				WalletID:      wallet.ID,
+				Transaction:   tx,
+				Confirmations: threshold,
+			})
+			notifiedConfirmations = threshold
+		}
+
+		if notifiedConfirmations >= finalConfirmations {
+			err = mw.db.DeleteStruct(txConfirmation)
+		} else if notifiedConfirmations != txConfirmation.NotifiedConfirmations {
+			err = mw.db.UpdateField(txConfirmation, "NotifiedConfirmations", notifiedConfirmations)
+		}
+		if err != nil {
+			log.Errorf("[%d] Error updating tracked tx %s: %v", wallet.ID, txConfirmation.Hash, err)
+		}
+	}
+}
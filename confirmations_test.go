@@ -0,0 +1,89 @@
+package dcrlibwallet
+
+import (
+	"github.com/asdine/storm/q"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TxConfirmations", func() {
+	var mw *MultiWallet
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	trackedTx := func(walletID int, hash string) TxConfirmation {
+		var txConfirmation TxConfirmation
+		err := mw.db.Select(q.Eq("WalletID", walletID), q.Eq("Hash", hash)).First(&txConfirmation)
+		Expect(err).To(BeNil())
+		return txConfirmation
+	}
+
+	It("tracks each mined transaction once per wallet", func() {
+		Expect(mw.trackTxConfirmations(1, "aaaa", 100)).To(Succeed())
+		Expect(mw.trackTxConfirmations(1, "aaaa", 100)).To(Succeed())
+		Expect(mw.trackTxConfirmations(2, "aaaa", 100)).To(Succeed())
+
+		count, err := mw.db.Count(&TxConfirmation{})
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(2))
+	})
+
+	It("updates the block height of a transaction mined again after a reorg", func() {
+		Expect(mw.trackTxConfirmations(1, "aaaa", 100)).To(Succeed())
+		Expect(mw.trackTxConfirmations(1, "bbbb", 98)).To(Succeed())
+
+		Expect(mw.untrackReorgedTxConfirmations(1, 99)).To(Succeed())
+		Expect(trackedTx(1, "aaaa").BlockHeight).To(Equal(BlockHeightInvalid))
+		Expect(trackedTx(1, "bbbb").BlockHeight).To(Equal(int32(98)))
+
+		Expect(mw.trackTxConfirmations(1, "aaaa", 101)).To(Succeed())
+		Expect(trackedTx(1, "aaaa").BlockHeight).To(Equal(int32(101)))
+	})
+
+	It("notifies listeners as transactions reach each confirmation threshold", func() {
+		wallet, err := mw.CreateNewWallet("wallet", "passphrase", PassphraseTypePass)
+		Expect(err).To(BeNil())
+		Expect(wallet.SetRequiredConfirmations(3)).To(Succeed())
+
+		_, err = wallet.txDB.SaveOrUpdate(&Transaction{}, &Transaction{WalletID: wallet.ID, Hash: "aaaa", BlockHeight: 100})
+		Expect(err).To(BeNil())
+		Expect(mw.trackTxConfirmations(wallet.ID, "aaaa", 100)).To(Succeed())
+
+		recorder := &txEventRecorder{}
+		Expect(mw.AddTxAndBlockEventListener(recorder, "confirmations")).To(Succeed())
+		confirmations := func() []int32 {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+
+			var confirmations []int32
+			for _, event := range recorder.events {
+				Expect(event.Type).To(Equal(TxEventConfirmed))
+				Expect(event.WalletID).To(Equal(wallet.ID))
+				Expect(event.Transaction.Hash).To(Equal("aaaa"))
+				confirmations = append(confirmations, event.Confirmations)
+			}
+			return confirmations
+		}
+
+		mw.publishTxConfirmations(wallet, 100)
+		mw.publishTxConfirmations(wallet, 101)
+		Expect(confirmations()).To(Equal([]int32{1}))
+		Expect(trackedTx(wallet.ID, "aaaa").NotifiedConfirmations).To(Equal(int32(1)))
+
+		mw.publishTxConfirmations(wallet, 106)
+		Expect(confirmations()).To(Equal([]int32{1, 3, DefaultFinalConfirmations}))
+
+		By("Forgetting transactions once they are final")
+		count, err := mw.db.Count(&TxConfirmation{})
+		Expect(err).To(BeNil())
+		Expect(count).To(BeZero())
+		mw.publishTxConfirmations(wallet, 107)
+		Expect(confirmations()).To(HaveLen(3))
+	})
+})
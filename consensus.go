@@ -0,0 +1,220 @@
+package dcrlibwallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrwallet/errors/v2"
+	w "github.com/decred/dcrwallet/wallet/v3"
+)
+
+const (
+	// VSPUserPubKeyAddrConfigKey holds the pubkey address that was last
+	// used to register this wallet with the VSP at `VSPTicketsHostConfigKey`.
+	// It is required to update the wallet's vote preferences on the VSP.
+	VSPUserPubKeyAddrConfigKey = "vsp_user_pubkey_addr"
+
+	// VSPTicketsHostConfigKey holds the host of the VSP that this wallet's
+	// tickets were last purchased from.
+	VSPTicketsHostConfigKey = "vsp_tickets_host"
+
+	// VSPAPITokenConfigKey holds the API token used to authenticate this
+	// wallet's requests to the VSP at `VSPTicketsHostConfigKey`.
+	VSPAPITokenConfigKey = "vsp_api_token"
+)
+
+// AllVoteAgendas returns all consensus agendas for the current stake version
+// along with the wallet's current vote preference for each agenda, json-encoded.
+func (wallet *Wallet) AllVoteAgendas() (string, error) {
+	agendas, err := wallet.AllVoteAgendasRaw()
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(agendas)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+// AllVoteAgendasRaw returns all consensus agendas for the current stake version
+// along with the wallet's current vote preference for each agenda.
+func (wallet *Wallet) AllVoteAgendasRaw() ([]*Agenda, error) {
+	choices, _, err := wallet.internal.AgendaChoices(wallet.shutdownContext())
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	version, deployments := w.CurrentAgendas(wallet.chainParams)
+	agendas := make([]*Agenda, len(deployments))
+	for i := range deployments {
+		d := &deployments[i]
+
+		votingPreference := "abstain"
+		for _, choice := range choices {
+			if choice.AgendaID == d.Vote.Id {
+				votingPreference = choice.ChoiceID
+				break
+			}
+		}
+
+		agendas[i] = &Agenda{
+			AgendaID:         d.Vote.Id,
+			Description:      d.Vote.Description,
+			Mask:             uint32(d.Vote.Mask),
+			Choices:          agendaChoices(d.Vote.Choices),
+			VotingPreference: votingPreference,
+			StartTime:        int64(d.StartTime),
+			ExpireTime:       int64(d.ExpireTime),
+			StakeVersion:     version,
+		}
+	}
+
+	return agendas, nil
+}
+
+// VoteBits returns the vote bits that tickets owned by this wallet
+// would currently vote with, based on the saved agenda preferences.
+func (wallet *Wallet) VoteBits() (int32, error) {
+	_, voteBits, err := wallet.internal.AgendaChoices(wallet.shutdownContext())
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int32(voteBits), nil
+}
+
+// SetVoteChoice sets the wallet's vote preference for the agenda with
+// the specified `agendaID` to `choiceID` and saves the preference to the
+// wallet database. If a VSP is in use, the new vote bits are also sent to
+// the VSP so that tickets voted by the VSP use the updated preference, the
+// previous preference is restored if the VSP can't be updated.
+func (wallet *Wallet) SetVoteChoice(agendaID, choiceID string) error {
+	ctx := wallet.shutdownContext()
+
+	choices, _, err := wallet.internal.AgendaChoices(ctx)
+	if err != nil {
+		return translateError(err)
+	}
+	oldChoiceID := "abstain"
+	for _, choice := range choices {
+		if choice.AgendaID == agendaID {
+			oldChoiceID = choice.ChoiceID
+			break
+		}
+	}
+
+	voteBits, err := wallet.internal.SetAgendaChoices(ctx, w.AgendaChoice{
+		AgendaID: agendaID,
+		ChoiceID: choiceID,
+	})
+	if err != nil {
+		return translateError(err)
+	}
+
+	vspHost := wallet.ReadStringConfigValueForKey(VSPTicketsHostConfigKey, "")
+	pubKeyAddr := wallet.ReadStringConfigValueForKey(VSPUserPubKeyAddrConfigKey, "")
+	if vspHost == "" || pubKeyAddr == "" {
+		// no vsp in use for this wallet, nothing to propagate
+		return nil
+	}
+
+	apiToken := wallet.ReadStringConfigValueForKey(VSPAPITokenConfigKey, "")
+	err = CallVSPSetVoteBitsAPI(vspHost, apiToken, pubKeyAddr, voteBits)
+	if err != nil {
+		log.Errorf("[%d] error updating vote bits on vsp: %v", wallet.ID, err)
+
+		// keep the wallet voting like the vsp
+		_, rollbackErr := wallet.internal.SetAgendaChoices(ctx, w.AgendaChoice{
+			AgendaID: agendaID,
+			ChoiceID: oldChoiceID,
+		})
+		if rollbackErr != nil {
+			log.Errorf("[%d] error restoring vote choice: %v", wallet.ID, rollbackErr)
+		}
+
+		return fmt.Errorf("vsp connection error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// CallVSPSetVoteBitsAPI updates the vote bits used by the VSP at `vspHost`
+// for tickets belonging to the user identified by `pubKeyAddr`, authenticating
+// with the user's `apiToken` on the VSP.
+func CallVSPSetVoteBitsAPI(vspHost, apiToken, pubKeyAddr string, voteBits uint16) error {
+	apiUrl := fmt.Sprintf("%s/api/v2/voting", strings.TrimSuffix(vspHost, "/"))
+	data := url.Values{}
+	data.Set("UserPubKeyAddr", pubKeyAddr)
+	data.Set("VoteBits", fmt.Sprintf("%d", voteBits))
+
+	req, err := http.NewRequest("POST", apiUrl, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResponse struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&apiResponse)
+	if err != nil {
+		return err
+	}
+
+	if apiResponse.Status != "success" {
+		return errors.New(apiResponse.Message)
+	}
+
+	return nil
+}
+
+func agendaChoices(choices []chaincfg.Choice) []*AgendaChoice {
+	agendaChoices := make([]*AgendaChoice, len(choices))
+	for i, choice := range choices {
+		agendaChoices[i] = &AgendaChoice{
+			ID:          choice.Id,
+			Description: choice.Description,
+			Bits:        uint32(choice.Bits),
+			IsAbstain:   choice.IsAbstain,
+			IsNo:        choice.IsNo,
+		}
+	}
+	return agendaChoices
+}
+
+// voteChoices decodes the choice cast for each agenda defined by
+// the stake version `voteVersion` from the provided vote bits.
+func voteChoices(voteVersion uint32, voteBits uint16, netParams *chaincfg.Params) (choices []*VoteChoice) {
+	for _, deployment := range netParams.Deployments[voteVersion] {
+		vote := deployment.Vote
+		bits := voteBits & vote.Mask
+		for _, choice := range vote.Choices {
+			if choice.Bits == bits {
+				choices = append(choices, &VoteChoice{
+					AgendaID: vote.Id,
+					ChoiceID: choice.Id,
+				})
+				break
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package dcrlibwallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	w "github.com/decred/dcrwallet/wallet/v3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consensus agendas", func() {
+	var (
+		mw     *MultiWallet
+		wallet *Wallet
+	)
+
+	BeforeEach(func() {
+		var err error
+		mw = newTestMultiWallet("testnet3")
+
+		wallet, err = mw.CreateNewWallet("wallet", "passphrase", PassphraseTypePass)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("lists the current agendas with the wallet's vote preferences", func() {
+		version, deployments := w.CurrentAgendas(wallet.chainParams)
+		Expect(deployments).NotTo(BeEmpty())
+
+		agendas, err := wallet.AllVoteAgendasRaw()
+		Expect(err).To(BeNil())
+		Expect(agendas).To(HaveLen(len(deployments)))
+		for i, agenda := range agendas {
+			Expect(agenda.AgendaID).To(Equal(deployments[i].Vote.Id))
+			Expect(agenda.StakeVersion).To(Equal(version))
+			Expect(agenda.Choices).To(HaveLen(len(deployments[i].Vote.Choices)))
+			Expect(agenda.VotingPreference).To(Equal("abstain"))
+		}
+
+		agenda := agendas[0]
+		var yes *AgendaChoice
+		for _, choice := range agenda.Choices {
+			if !choice.IsAbstain && !choice.IsNo {
+				yes = choice
+			}
+		}
+		Expect(yes).NotTo(BeNil())
+
+		Expect(wallet.SetVoteChoice(agenda.AgendaID, yes.ID)).To(Succeed())
+		agendas, err = wallet.AllVoteAgendasRaw()
+		Expect(err).To(BeNil())
+		Expect(agendas[0].VotingPreference).To(Equal(yes.ID))
+
+		voteBits, err := wallet.VoteBits()
+		Expect(err).To(BeNil())
+		Expect(uint32(voteBits) & agenda.Mask).To(Equal(yes.Bits))
+	})
+
+	It("sends vote preferences to the VSP the tickets were purchased from", func() {
+		var requests []*http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			requests = append(requests, r)
+			w.Write([]byte(`{"status":"success"}`))
+		}))
+		defer server.Close()
+
+		_, deployments := w.CurrentAgendas(wallet.chainParams)
+		vote := deployments[0].Vote
+
+		// no vsp is saved for wallets without vsp tickets
+		mw.SetStringConfigValueForKey(VSPHostConfigKey, server.URL)
+		Expect(wallet.SetVoteChoice(vote.Id, "no")).To(Succeed())
+		Expect(requests).To(BeEmpty())
+
+		wallet.SetStringConfigValueForKey(VSPTicketsHostConfigKey, server.URL)
+		wallet.SetStringConfigValueForKey(VSPAPITokenConfigKey, "api-token")
+		wallet.SetStringConfigValueForKey(VSPUserPubKeyAddrConfigKey, "pubkeyaddr")
+		Expect(wallet.SetVoteChoice(vote.Id, "yes")).To(Succeed())
+
+		voteBits, err := wallet.VoteBits()
+		Expect(err).To(BeNil())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Path).To(Equal("/api/v2/voting"))
+		Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer api-token"))
+		Expect(requests[0].PostForm.Get("UserPubKeyAddr")).To(Equal("pubkeyaddr"))
+		Expect(requests[0].PostForm.Get("VoteBits")).To(Equal(strconv.Itoa(int(voteBits))))
+	})
+
+	It("keeps the previous vote preference if the VSP can't be updated", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"error","message":"vsp unavailable"}`))
+		}))
+		defer server.Close()
+
+		_, deployments := w.CurrentAgendas(wallet.chainParams)
+		vote := deployments[0].Vote
+		Expect(wallet.SetVoteChoice(vote.Id, "no")).To(Succeed())
+		voteBits, err := wallet.VoteBits()
+		Expect(err).To(BeNil())
+
+		wallet.SetStringConfigValueForKey(VSPTicketsHostConfigKey, server.URL)
+		wallet.SetStringConfigValueForKey(VSPUserPubKeyAddrConfigKey, "pubkeyaddr")
+		Expect(wallet.SetVoteChoice(vote.Id, "yes")).NotTo(Succeed())
+
+		agendas, err := wallet.AllVoteAgendasRaw()
+		Expect(err).To(BeNil())
+		Expect(agendas[0].VotingPreference).To(Equal("no"))
+		Expect(wallet.VoteBits()).To(Equal(voteBits))
+	})
+
+	It("decodes the vote choices from vote bits", func() {
+		params := wallet.chainParams
+		version, deployments := w.CurrentAgendas(params)
+		vote := deployments[0].Vote
+
+		for _, choice := range vote.Choices {
+			// bit 0 is the previous block's validity, not an agenda choice
+			choices := voteChoices(version, 1|choice.Bits, params)
+			Expect(choices).To(HaveLen(len(deployments)))
+			Expect(choices[0].AgendaID).To(Equal(vote.Id))
+			Expect(choices[0].ChoiceID).To(Equal(choice.Id))
+		}
+
+		// bits that match none of the choices of an agenda decode to no choice
+		Expect(voteChoices(version, vote.Mask, params)).To(BeEmpty())
+		Expect(voteChoices(version+100, 1, params)).To(BeEmpty())
+	})
+})
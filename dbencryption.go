@@ -0,0 +1,478 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	stormjson "github.com/asdine/storm/codec/json"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/kevinburke/nacl"
+	"github.com/kevinburke/nacl/secretbox"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// When startup security is set, the values saved in the wallets database are
+// encrypted with a key derived from the startup passphrase, so that wallet
+// names, creation times, encrypted seeds and user config can't be read from
+// the database file without the passphrase. The records are only loaded once
+// the database is unlocked by OpenWallets.
+//
+// The values read before OpenWallets, the startup passphrase hash, the salt
+// of the key and the plaintextConfigKeys, are saved in plaintext. Bucket and
+// config key names and the values of storm indexes are not encrypted either,
+// so private fields must not be indexed. The records are then found by
+// decoding and matching every record of their bucket. The indexes of private
+// fields saved by earlier versions are dropped by dropPrivateIndexes.
+
+const (
+	walletsDbEncryptionSaltField = "db-encryption-salt"
+
+	// walletsDbCompactPendingField is set once values are re-encrypted until
+	// the database is compacted, which drops the freed pages that may still
+	// hold the values encrypted with the previous key or in plaintext.
+	walletsDbCompactPendingField = "db-compact-pending"
+
+	dbEncryptionSaltSize = 32
+
+	// stormBucketPrefix prefixes the buckets storm keeps its own data in,
+	// such as the db version, struct metadata and index buckets.
+	stormBucketPrefix = "__storm"
+
+	stormIndexBucketPrefix = stormBucketPrefix + "_index_"
+)
+
+// privateIndexes are the fields of private values that were indexed by earlier
+// versions, by the name of the bucket of their struct.
+var privateIndexes = map[string][]string{
+	"TxConfirmation": {"Hash"},
+	"ProposalVote":   {"WalletID", "Token", "Ticket"},
+	"Webhook":        {"URL"},
+}
+
+// encryptedValuePrefix marks encrypted values, a JSON value never starts with
+// a 0 byte.
+var encryptedValuePrefix = []byte{0, 'e', 'n', 'c', 1}
+
+// plaintextConfigKeys are the user config values needed before the database
+// is unlocked.
+var plaintextConfigKeys = map[string]bool{
+	LogLevelConfigKey:             true,
+	IsStartupSecuritySetConfigKey: true,
+	StartupSecurityTypeConfigKey:  true,
+	UseBiometricConfigKey:         true,
+
+	PassphraseAttemptsBeforeWipeConfigKey: true,
+}
+
+// dbCodec is the storm codec of the wallets database. It encodes values as
+// JSON like the default storm codec and encrypts them when a key is set.
+type dbCodec struct {
+	mu sync.RWMutex
+
+	// encrypted is true if the database is encrypted, values can only be
+	// read and written once key is set.
+	encrypted bool
+	key       nacl.Key
+}
+
+// Name returns the name of the default storm codec. Plaintext values are
+// compatible with it and storm refuses to open buckets saved with a codec of
+// another name.
+func (c *dbCodec) Name() string {
+	return stormjson.Codec.Name()
+}
+
+func (c *dbCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.encrypted {
+		return data, nil
+	}
+	if c.key == nil {
+		return nil, errors.New(ErrDatabaseLocked)
+	}
+	return sealDbValue(c.key, data), nil
+}
+
+func (c *dbCodec) Unmarshal(b []byte, v interface{}) error {
+	c.mu.RLock()
+	data, err := openDbValue(c.key, b)
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// setKey sets the key values are encrypted with, nil for a database that is
+// not encrypted.
+func (c *dbCodec) setKey(key nacl.Key) {
+	c.mu.Lock()
+	c.encrypted = key != nil
+	c.key = key
+	c.mu.Unlock()
+}
+
+// lock marks the database as encrypted with a key that is not known yet.
+func (c *dbCodec) lock() {
+	c.mu.Lock()
+	c.encrypted = true
+	c.key = nil
+	c.mu.Unlock()
+}
+
+func (c *dbCodec) isLocked() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.encrypted && c.key == nil
+}
+
+// IsDatabaseEncrypted returns true if the wallets database is encrypted with
+// the startup passphrase.
+func (mw *MultiWallet) IsDatabaseEncrypted() bool {
+	salt, err := mw.dbEncryptionSalt()
+	return err == nil && salt != nil
+}
+
+// IsDatabaseLocked returns true if the wallets database is encrypted and was
+// not unlocked with the startup passphrase by OpenWallets yet. The wallets are
+// not loaded and most config values can't be read or written until then.
+func (mw *MultiWallet) IsDatabaseLocked() bool {
+	return mw.dbCodec.isLocked()
+}
+
+// plaintextDb returns the wallets database node for the values that are never
+// encrypted.
+func (mw *MultiWallet) plaintextDb() storm.Node {
+	return mw.db.WithCodec(stormjson.Codec)
+}
+
+// configDb returns the wallets database node for the user config value `key`.
+func (mw *MultiWallet) configDb(key string) storm.Node {
+	if plaintextConfigKeys[key] {
+		return mw.plaintextDb()
+	}
+	return mw.db
+}
+
+func (mw *MultiWallet) dbEncryptionSalt() ([]byte, error) {
+	var salt []byte
+	err := mw.plaintextDb().Get(walletsMetadataBucketName, walletsDbEncryptionSaltField, &salt)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// unlockDatabase sets the key of an encrypted wallets database derived from
+// the verified `startupPassphrase` and loads the records that could not be
+// read until then. The databases of installs whose startup passphrase was
+// set before the database could be encrypted are encrypted now.
+func (mw *MultiWallet) unlockDatabase(startupPassphrase []byte) error {
+	salt, err := mw.dbEncryptionSalt()
+	if err != nil {
+		return err
+	}
+
+	if salt == nil {
+		if len(startupPassphrase) == 0 {
+			return nil
+		}
+		log.Info("Encrypting wallets database with the startup passphrase")
+		return mw.changeDatabaseEncryption(nil, startupPassphrase, nil)
+	}
+
+	if !mw.dbCodec.isLocked() {
+		return nil
+	}
+
+	key, err := dbEncryptionKey(startupPassphrase, salt)
+	if err != nil {
+		return err
+	}
+	mw.dbCodec.setKey(key)
+
+	return mw.loadRecords()
+}
+
+// changeDatabaseEncryption re-encrypts the values of the wallets database
+// encrypted with the key of `oldPassphrase`, if any, with a key derived from
+// `newPassphrase` or saves them in plaintext if `newPassphrase` is empty. The
+// passphrases must be verified. `update` is called with the plaintext database
+// node in the same db transaction, to save the new passphrase hash.
+func (mw *MultiWallet) changeDatabaseEncryption(oldPassphrase, newPassphrase []byte, update func(storm.Node) error) error {
+	oldSalt, err := mw.dbEncryptionSalt()
+	if err != nil {
+		return err
+	}
+
+	var oldKey, newKey nacl.Key
+	if oldSalt != nil {
+		if oldKey, err = dbEncryptionKey(oldPassphrase, oldSalt); err != nil {
+			return err
+		}
+	}
+
+	var newSalt []byte
+	if len(newPassphrase) > 0 {
+		newSalt = make([]byte, dbEncryptionSaltSize)
+		if _, err = io.ReadFull(rand.Reader, newSalt); err != nil {
+			return err
+		}
+		if newKey, err = dbEncryptionKey(newPassphrase, newSalt); err != nil {
+			return err
+		}
+	}
+
+	wasLocked := mw.dbCodec.isLocked()
+
+	err = mw.db.Bolt.Update(func(tx *bolt.Tx) error {
+		err := tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if string(name) == walletsMetadataBucketName || strings.HasPrefix(string(name), stormBucketPrefix) {
+				return nil
+			}
+			return reencryptBucket(bucket, string(name) == userConfigBucketName, oldKey, newKey)
+		})
+		if err != nil {
+			return err
+		}
+
+		db := mw.db.WithTransaction(tx).WithCodec(stormjson.Codec)
+		if newSalt != nil {
+			err = db.Set(walletsMetadataBucketName, walletsDbEncryptionSaltField, newSalt)
+		} else {
+			err = db.Delete(walletsMetadataBucketName, walletsDbEncryptionSaltField)
+			if err == storm.ErrNotFound {
+				err = nil
+			}
+		}
+		if err == nil {
+			err = db.Set(walletsMetadataBucketName, walletsDbCompactPendingField, true)
+		}
+		if err == nil && update != nil {
+			err = update(db)
+		}
+		return err
+	})
+	if err != nil {
+		log.Errorf("Error re-encrypting wallets database: %v", err)
+		return err
+	}
+
+	mw.dbCodec.setKey(newKey)
+
+	// drop the plaintext index buckets of the wallet names and creation
+	// times saved while they were indexed
+	if oldKey == nil && newKey != nil {
+		err = mw.db.ReIndex(&Wallet{})
+		if err != nil && err != storm.ErrNotFound {
+			return err
+		}
+	}
+
+	if wasLocked {
+		return mw.loadRecords()
+	}
+	return nil
+}
+
+// dropPrivateIndexes deletes the index buckets of privateIndexes, which hold
+// the indexed values in plaintext, and marks the database for compaction so
+// that the freed pages are dropped too. The values are not decoded, so it can
+// be called before the database is unlocked.
+func dropPrivateIndexes(db *storm.DB) error {
+	return db.Bolt.Update(func(tx *bolt.Tx) error {
+		dropped := false
+		for bucketName, fields := range privateIndexes {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil {
+				continue
+			}
+
+			for _, field := range fields {
+				indexName := []byte(stormIndexBucketPrefix + field)
+				if bucket.Bucket(indexName) == nil {
+					continue
+				}
+				if err := bucket.DeleteBucket(indexName); err != nil {
+					return err
+				}
+				dropped = true
+			}
+		}
+
+		if !dropped {
+			return nil
+		}
+		log.Info("Dropped the indexes of private values from the wallets database")
+		plaintextDb := db.WithTransaction(tx).WithCodec(stormjson.Codec)
+		return plaintextDb.Set(walletsMetadataBucketName, walletsDbCompactPendingField, true)
+	})
+}
+
+// reencryptBucket re-encrypts the values of `bucket` and its nested buckets
+// other than the storm index and metadata buckets.
+func reencryptBucket(bucket *bolt.Bucket, isConfig bool, oldKey, newKey nacl.Key) error {
+	var keys, values, nestedBuckets [][]byte
+	err := bucket.ForEach(func(k, v []byte) error {
+		switch {
+		case v == nil:
+			if !strings.HasPrefix(string(k), stormBucketPrefix) {
+				nestedBuckets = append(nestedBuckets, append([]byte(nil), k...))
+			}
+		case isConfig && plaintextConfigKeys[string(k)]:
+		default:
+			keys = append(keys, append([]byte(nil), k...))
+			values = append(values, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, k := range keys {
+		data, err := openDbValue(oldKey, values[i])
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put(k, sealDbValue(newKey, data)); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range nestedBuckets {
+		if err = reencryptBucket(bucket.Bucket(name), false, oldKey, newKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealDbValue encrypts `data` with `key`, if not nil.
+func sealDbValue(key nacl.Key, data []byte) []byte {
+	if key == nil {
+		return data
+	}
+	return append(append([]byte(nil), encryptedValuePrefix...), secretbox.EasySeal(data, key)...)
+}
+
+// openDbValue decrypts `value` with `key` if it is encrypted.
+func openDbValue(key nacl.Key, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	if key == nil {
+		return nil, errors.New(ErrDatabaseLocked)
+	}
+
+	data, err := secretbox.EasyOpen(value[len(encryptedValuePrefix):], key)
+	if err != nil {
+		return nil, errors.New(ErrInvalidPassphrase)
+	}
+	return data, nil
+}
+
+// dbEncryptionKey derives the key of the wallets database from the startup
+// passphrase.
+func dbEncryptionKey(startupPassphrase, salt []byte) (nacl.Key, error) {
+	const N, r, p = 1 << 15, 8, 1
+
+	hash, err := scrypt.Key(startupPassphrase, salt, N, r, p, nacl.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	key := new([nacl.KeySize]byte)
+	copy(key[:], hash)
+	return key, nil
+}
+
+// compactDatabaseIfPending rewrites the wallets database at `dbPath` if its
+// values were re-encrypted since it was last compacted. The database must not
+// be open.
+func compactDatabaseIfPending(dbPath string) error {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	src, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	var pending bool
+	src.View(func(tx *bolt.Tx) error {
+		if metadata := tx.Bucket([]byte(walletsMetadataBucketName)); metadata != nil {
+			pending = metadata.Get([]byte(walletsDbCompactPendingField)) != nil
+		}
+		return nil
+	})
+	if !pending {
+		return src.Close()
+	}
+
+	log.Info("Compacting wallets database")
+	compactedPath := dbPath + ".compact"
+	dst, err := bolt.Open(compactedPath, 0600, nil)
+	if err != nil {
+		src.Close()
+		return err
+	}
+
+	err = src.View(func(srcTx *bolt.Tx) error {
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			return srcTx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+				dstBucket, err := dstTx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(bucket, dstBucket, string(name) == walletsMetadataBucketName)
+			})
+		})
+	})
+	src.Close()
+	dst.Close()
+	if err != nil {
+		os.Remove(compactedPath)
+		return err
+	}
+
+	return os.Rename(compactedPath, dbPath)
+}
+
+// copyBucket copies the values and nested buckets of `src` to `dst`, except
+// the compact pending flag of the metadata bucket.
+func copyBucket(src, dst *bolt.Bucket, isMetadata bool) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			if isMetadata && string(k) == walletsDbCompactPendingField {
+				return nil
+			}
+			return dst.Put(k, v)
+		}
+
+		dstNested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(src.Bucket(k), dstNested, false)
+	})
+}
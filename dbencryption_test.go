@@ -0,0 +1,148 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Database encryption", func() {
+	const (
+		walletName = "encrypted-wallet-name"
+		peerConfig = "10.11.12.13:19108"
+		passphrase = "passphrase"
+	)
+
+	var mw *MultiWallet
+
+	// dbFileContains returns true if `s` is in the database file of the
+	// multiwallet after it is shut down, reopening it after.
+	dbFileContains := func(s string) bool {
+		mw.Shutdown()
+		content, err := ioutil.ReadFile(filepath.Join(mw.rootDir, walletsDbName))
+		Expect(err).To(BeNil())
+		mw = openTestMultiWallet(testRootDir(mw), "testnet3")
+		return bytes.Contains(content, []byte(s))
+	}
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+		_, err := mw.CreateNewWallet(walletName, passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+		mw.SetStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey, peerConfig)
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("encrypts the database with the startup passphrase", func() {
+		Expect(dbFileContains(walletName)).To(BeTrue())
+		Expect(mw.IsDatabaseEncrypted()).To(BeFalse())
+
+		Expect(mw.SetStartupPassphrase([]byte("startup"), PassphraseTypePass)).To(BeNil())
+		Expect(mw.IsDatabaseEncrypted()).To(BeTrue())
+		Expect(mw.IsDatabaseLocked()).To(BeFalse())
+
+		Expect(dbFileContains(walletName)).To(BeFalse())
+		Expect(dbFileContains(peerConfig)).To(BeFalse())
+
+		By("Loading the wallets once the database is unlocked")
+		Expect(mw.IsDatabaseLocked()).To(BeTrue())
+		Expect(mw.IsStartupSecuritySet()).To(BeTrue())
+		Expect(mw.AllWallets()).To(BeEmpty())
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.ReadStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey)).To(BeEmpty())
+
+		Expect(mw.OpenWallets([]byte("wrong"))).To(MatchError(ErrInvalidPassphrase))
+		Expect(mw.OpenWallets([]byte("startup"))).To(BeNil())
+		Expect(mw.IsDatabaseLocked()).To(BeFalse())
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.AllWallets()[0].Name).To(Equal(walletName))
+		Expect(mw.ReadStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey)).To(Equal(peerConfig))
+		exists, err := mw.WalletNameExists(walletName)
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeTrue())
+
+		By("Rotating the key when the startup passphrase changes")
+		Expect(mw.ChangeStartupPassphrase([]byte("startup"), []byte("changed"), PassphraseTypePass)).To(BeNil())
+		Expect(dbFileContains(walletName)).To(BeFalse())
+		Expect(mw.OpenWallets([]byte("startup"))).To(MatchError(ErrInvalidPassphrase))
+		Expect(mw.OpenWallets([]byte("changed"))).To(BeNil())
+		Expect(mw.ReadStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey)).To(Equal(peerConfig))
+
+		By("Decrypting the database when the startup passphrase is removed")
+		Expect(mw.RemoveStartupPassphrase([]byte("changed"))).To(BeNil())
+		Expect(dbFileContains(walletName)).To(BeTrue())
+		Expect(mw.IsDatabaseLocked()).To(BeFalse())
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.ReadStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey)).To(Equal(peerConfig))
+	})
+
+	It("encrypts the database of existing installs with a startup passphrase", func() {
+		// startup passphrase set before the database could be encrypted
+		hash, err := bcrypt.GenerateFromPassword([]byte("startup"), bcrypt.DefaultCost)
+		Expect(err).To(BeNil())
+		Expect(mw.plaintextDb().Set(walletsMetadataBucketName, walletstartupPassphraseField, hash)).To(BeNil())
+		mw.SetBoolConfigValueForKey(IsStartupSecuritySetConfigKey, true)
+
+		Expect(dbFileContains(walletName)).To(BeTrue())
+		Expect(mw.IsDatabaseLocked()).To(BeFalse())
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+
+		Expect(mw.OpenWallets([]byte("startup"))).To(BeNil())
+		Expect(mw.IsDatabaseEncrypted()).To(BeTrue())
+		Expect(dbFileContains(walletName)).To(BeFalse())
+
+		Expect(mw.OpenWallets([]byte("startup"))).To(BeNil())
+		Expect(mw.AllWallets()[0].Name).To(Equal(walletName))
+	})
+
+	It("does not save private values in plaintext indexes", func() {
+		const (
+			webhookURL = "http://private-webhook.example"
+			txHash     = "private-tx-hash"
+			token      = "private-proposal-token"
+			ticket     = "private-ticket-hash"
+		)
+
+		Expect(mw.SetStartupPassphrase([]byte("startup"), PassphraseTypePass)).To(BeNil())
+		walletID := mw.AllWallets()[0].ID
+
+		_, err := mw.AddWebhook(webhookURL, "secret")
+		Expect(err).To(BeNil())
+		Expect(mw.trackTxConfirmations(walletID, txHash, 10)).To(Succeed())
+		Expect(mw.db.Save(&ProposalVote{WalletID: walletID, Token: token, Ticket: ticket})).To(Succeed())
+
+		for _, value := range []string{webhookURL, txHash, token, ticket} {
+			Expect(dbFileContains(value)).To(BeFalse(), value)
+		}
+
+		By("Dropping the indexes saved by earlier versions")
+		err = mw.db.Bolt.Update(func(tx *bolt.Tx) error {
+			index, err := tx.Bucket([]byte("Webhook")).CreateBucketIfNotExists([]byte(stormIndexBucketPrefix + "URL"))
+			if err != nil {
+				return err
+			}
+			return index.Put([]byte(webhookURL), []byte("1"))
+		})
+		Expect(err).To(BeNil())
+
+		// the indexes are dropped when the database is opened and the freed
+		// pages when it is closed
+		Expect(dbFileContains(webhookURL)).To(BeTrue())
+		Expect(dbFileContains(webhookURL)).To(BeFalse())
+
+		Expect(mw.OpenWallets([]byte("startup"))).To(BeNil())
+		webhooks, err := mw.WebhooksRaw()
+		Expect(err).To(BeNil())
+		Expect(webhooks).To(HaveLen(1))
+		_, err = mw.AddWebhook(webhookURL, "secret")
+		Expect(err).To(MatchError(ErrExist))
+	})
+})
@@ -1,7 +1,10 @@
-package dcrlibwallet_test
+package dcrlibwallet
 
 import (
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -13,3 +16,35 @@ func TestDcrlibwallet(t *testing.T) {
 	rand.Seed(GinkgoRandomSeed())
 	RunSpecs(t, "Dcrlibwallet Suite")
 }
+
+// newTestMultiWallet opens a MultiWallet for `netType` in a new temporary
+// root directory that is deleted by removeTestMultiWallet.
+func newTestMultiWallet(netType string) *MultiWallet {
+	rootDir, err := ioutil.TempDir("", "dcrlibwallet")
+	Expect(err).To(BeNil())
+	return openTestMultiWallet(rootDir, netType)
+}
+
+func openTestMultiWallet(rootDir, netType string) *MultiWallet {
+	mw, err := NewMultiWallet(rootDir, "", netType)
+	Expect(err).To(BeNil())
+	return mw
+}
+
+// reopenTestMultiWallet shuts down mw and opens it again, like an app restart.
+func reopenTestMultiWallet(mw *MultiWallet) *MultiWallet {
+	mw.Shutdown()
+	return openTestMultiWallet(testRootDir(mw), mw.chainParams.Name)
+}
+
+// removeTestMultiWallet shuts down mw and deletes its root directory.
+func removeTestMultiWallet(mw *MultiWallet) {
+	mw.Shutdown()
+	os.RemoveAll(testRootDir(mw))
+}
+
+// testRootDir returns the root directory mw was opened in, mw.rootDir is the
+// network directory in it.
+func testRootDir(mw *MultiWallet) string {
+	return filepath.Dir(mw.rootDir)
+}
@@ -34,7 +34,7 @@ func DecodeTransaction(walletTx *TxInfoFromWallet, netParams *chaincfg.Params) (
 	inputs := decodeTxInputs(msgTx, walletTx.Inputs)
 	outputs := decodeTxOutputs(msgTx, netParams, walletTx.Outputs)
 
-	ssGenVersion, lastBlockValid, voteBits, ticketSpentHash := voteInfo(msgTx)
+	ssGenVersion, lastBlockValid, voteBits, voteChoices, ticketSpentHash := voteInfo(msgTx, netParams)
 
 	// ticketSpentHash will be empty if this isn't a vote tx
 	if stake.IsSSRtx(msgTx) {
@@ -64,6 +64,7 @@ func DecodeTransaction(walletTx *TxInfoFromWallet, netParams *chaincfg.Params) (
 		VoteVersion:     int32(ssGenVersion),
 		LastBlockValid:  lastBlockValid,
 		VoteBits:        voteBits,
+		VoteChoices:     voteChoices,
 		TicketSpentHash: ticketSpentHash,
 	}, nil
 }
@@ -147,12 +148,15 @@ func decodeTxOutputs(mtx *wire.MsgTx, netParams *chaincfg.Params, walletOutputs
 	return
 }
 
-func voteInfo(msgTx *wire.MsgTx) (ssGenVersion uint32, lastBlockValid bool, voteBits string, ticketSpentHash string) {
+func voteInfo(msgTx *wire.MsgTx, netParams *chaincfg.Params) (ssGenVersion uint32, lastBlockValid bool, voteBits string,
+	choices []*VoteChoice, ticketSpentHash string) {
+
 	if stake.IsSSGen(msgTx) {
 		ssGenVersion = stake.SSGenVersion(msgTx)
 		bits := stake.SSGenVoteBits(msgTx)
 		voteBits = fmt.Sprintf("%#04x", bits)
 		lastBlockValid = bits&uint16(BlockValid) != 0
+		choices = voteChoices(ssGenVersion, bits, netParams)
 		ticketSpentHash = msgTx.TxIn[1].PreviousOutPoint.Hash.String()
 	}
 	return
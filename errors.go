@@ -33,6 +33,13 @@ const (
 	ErrAddressDiscoveryNotDone      = "address_discovery_not_done"
 	ErrChangingPassphrase           = "err_changing_passphrase"
 	ErrSavingWallet                 = "err_saving_wallet"
+	ErrMultisigThresholdNotMet      = "multisig_threshold_not_met"
+	ErrSeedSharesThresholdNotMet    = "seed_shares_threshold_not_met"
+	ErrSeedShareChecksum            = "seed_share_checksum_mismatch"
+	ErrDatabaseLocked               = "database_locked"
+	ErrPassphraseLockedOut          = "passphrase_locked_out"
+	ErrPassphraseAttemptsExceeded   = "passphrase_attempts_exceeded"
+	ErrSeedBackupQuizLockedOut      = "seed_backup_quiz_locked_out"
 )
 
 // todo, should update this method to translate more error kinds.
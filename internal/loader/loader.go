@@ -7,6 +7,7 @@ package loader
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -402,6 +403,31 @@ func (l *Loader) UnloadWallet() error {
 	return nil
 }
 
+// CopyDB writes a consistent copy of the loaded wallet database to w in a
+// read transaction.  Returns with errors.Invalid if the wallet has not been
+// loaded or if its database driver does not support copying.
+func (l *Loader) CopyDB(w io.Writer) error {
+	const op errors.Op = "loader.CopyDB"
+
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if l.wallet == nil {
+		return errors.E(op, errors.Invalid, "wallet is unopened")
+	}
+
+	db, ok := l.db.(interface{ Copy(io.Writer) error })
+	if !ok {
+		return errors.E(op, errors.Invalid, "wallet database can't be copied")
+	}
+
+	err := db.Copy(w)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
 // NetworkBackend returns the associated wallet network backend, if any, and a
 // bool describing whether a non-nil network backend was set.
 func (l *Loader) NetworkBackend() (n wallet.NetworkBackend, ok bool) {
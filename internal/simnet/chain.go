@@ -0,0 +1,362 @@
+package simnet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/blockchain/stake/v2"
+	blockchain "github.com/decred/dcrd/blockchain/standalone"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/gcs"
+	"github.com/decred/dcrd/gcs/blockcf"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrd/wire"
+	"github.com/planetdecred/dcrlibwallet/utils"
+)
+
+// chainBlock is a block known to the chain along with its regular cfilter.
+type chainBlock struct {
+	msg    *wire.MsgBlock
+	hash   chainhash.Hash
+	height int32
+	parent *chainBlock
+	filter *gcs.Filter
+}
+
+// Chain is an in-memory block chain that generates blocks passing the header,
+// merkle root and cfilter checks performed by SPV wallets. Blocks are mined
+// with the network's minimum proof of work difficulty and timestamps exactly
+// one target block time apart so the difficulty never retargets.
+//
+// Votes and revocations are not generated, blocks only ever contain the
+// coinbase and the transactions passed to them.
+type Chain struct {
+	params *chaincfg.Params
+
+	mu         sync.RWMutex
+	blocks     map[chainhash.Hash]*chainBlock
+	mainChain  []*chainBlock
+	startTime  time.Time
+	extraNonce uint64
+	fakeInputs uint64
+}
+
+// NewChain returns a Chain holding only the genesis block of `params`.
+// Generated blocks are timestamped from one hour before the current time.
+func NewChain(params *chaincfg.Params) (*Chain, error) {
+	genesis := params.GenesisBlock
+	filter, err := blockcf.Regular(genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	genesisBlock := &chainBlock{
+		msg:    genesis,
+		hash:   genesis.BlockHash(),
+		filter: filter,
+	}
+
+	return &Chain{
+		params:    params,
+		blocks:    map[chainhash.Hash]*chainBlock{genesisBlock.hash: genesisBlock},
+		mainChain: []*chainBlock{genesisBlock},
+		startTime: time.Now().Add(-time.Hour).Truncate(time.Second),
+	}, nil
+}
+
+// Params returns the network parameters of the chain.
+func (c *Chain) Params() *chaincfg.Params {
+	return c.params
+}
+
+// Tip returns the hash and height of the main chain tip.
+func (c *Chain) Tip() (chainhash.Hash, int32) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tip := c.mainChain[len(c.mainChain)-1]
+	return tip.hash, tip.height
+}
+
+// BlockAtHeight returns the main chain block at `height`.
+func (c *Chain) BlockAtHeight(height int32) (*wire.MsgBlock, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if height < 0 || int(height) >= len(c.mainChain) {
+		return nil, false
+	}
+	return c.mainChain[height].msg, true
+}
+
+// Block returns the main chain or side chain block with `hash`.
+func (c *Chain) Block(hash *chainhash.Hash) (*wire.MsgBlock, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	b, ok := c.blocks[*hash]
+	if !ok {
+		return nil, false
+	}
+	return b.msg, true
+}
+
+// CFilter returns the regular cfilter of the block with `hash`.
+func (c *Chain) CFilter(hash *chainhash.Hash) (*gcs.Filter, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	b, ok := c.blocks[*hash]
+	if !ok {
+		return nil, false
+	}
+	return b.filter, true
+}
+
+// Headers returns up to wire.MaxBlockHeadersPerMsg main chain headers after the
+// first locator found in the main chain, stopping at `hashStop`. The genesis
+// block is used as the starting point if no locator is in the main chain.
+func (c *Chain) Headers(locators []*chainhash.Hash, hashStop *chainhash.Hash) []*wire.BlockHeader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var startHeight int32
+	for _, locator := range locators {
+		if b, ok := c.blocks[*locator]; ok && c.isMainChain(b) {
+			startHeight = b.height
+			break
+		}
+	}
+
+	var headers []*wire.BlockHeader
+	for height := startHeight + 1; int(height) < len(c.mainChain); height++ {
+		b := c.mainChain[height]
+		header := b.msg.Header
+		headers = append(headers, &header)
+		if b.hash == *hashStop || len(headers) == wire.MaxBlockHeadersPerMsg {
+			break
+		}
+	}
+	return headers
+}
+
+// headersAfter returns the main chain headers following the block with `hash`,
+// starting from its fork point if the block is no longer in the main chain.
+func (c *Chain) headersAfter(hash *chainhash.Hash) []*wire.BlockHeader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	b, ok := c.blocks[*hash]
+	if !ok {
+		b = c.mainChain[0]
+	}
+	for !c.isMainChain(b) {
+		b = b.parent
+	}
+
+	var headers []*wire.BlockHeader
+	for height := b.height + 1; int(height) < len(c.mainChain); height++ {
+		header := c.mainChain[height].msg.Header
+		headers = append(headers, &header)
+	}
+	return headers
+}
+
+func (c *Chain) isMainChain(b *chainBlock) bool {
+	return int(b.height) < len(c.mainChain) && c.mainChain[b.height] == b
+}
+
+// FundingTx returns a transaction paying `amount` to `address`. The input of
+// the transaction spends a made-up outpoint, SPV wallets cannot tell it apart
+// from a real one.
+func (c *Chain) FundingTx(address string, amount dcrutil.Amount) (*wire.MsgTx, error) {
+	addr, err := dcrutil.DecodeAddress(address, c.params)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.fakeInputs++
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], c.fakeInputs)
+	c.mu.Unlock()
+
+	prevHash := chainhash.Hash(sha256.Sum256(seed[:]))
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular),
+		Sequence:         wire.MaxTxInSequenceNum,
+		ValueIn:          int64(amount),
+		BlockHeight:      wire.NullBlockHeight,
+		BlockIndex:       wire.NullBlockIndex,
+		SignatureScript:  []byte{txscript.OP_TRUE},
+	})
+	tx.AddTxOut(wire.NewTxOut(int64(amount), pkScript))
+	return tx, nil
+}
+
+// connectBlock mines a block with `txs` on top of the main chain tip and makes
+// it the new tip. Stake transactions are placed in the stake tree.
+func (c *Chain) connectBlock(txs []*wire.MsgTx) (*wire.MsgBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parent := c.mainChain[len(c.mainChain)-1]
+	height := parent.height + 1
+
+	c.extraNonce++
+	coinbaseScript, err := txscript.NewScriptBuilder().AddInt64(int64(height)).
+		AddInt64(int64(c.extraNonce)).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	subsidy := c.params.BaseSubsidy
+	coinbase := wire.NewMsgTx()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex, wire.TxTreeRegular),
+		Sequence:         wire.MaxTxInSequenceNum,
+		ValueIn:          subsidy,
+		BlockHeight:      wire.NullBlockHeight,
+		BlockIndex:       wire.NullBlockIndex,
+		SignatureScript:  coinbaseScript,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(subsidy, []byte{txscript.OP_TRUE}))
+
+	block := &wire.MsgBlock{Transactions: []*wire.MsgTx{coinbase}}
+	var freshStake uint8
+	for _, tx := range txs {
+		switch stake.DetermineTxType(tx) {
+		case stake.TxTypeRegular:
+			block.Transactions = append(block.Transactions, tx)
+		case stake.TxTypeSStx:
+			freshStake++
+			fallthrough
+		default:
+			block.STransactions = append(block.STransactions, tx)
+		}
+	}
+
+	parentHeader := &parent.msg.Header
+	block.Header = wire.BlockHeader{
+		Version:      parentHeader.Version,
+		PrevBlock:    parent.hash,
+		MerkleRoot:   blockchain.CalcTxTreeMerkleRoot(block.Transactions),
+		StakeRoot:    blockchain.CalcTxTreeMerkleRoot(block.STransactions),
+		VoteBits:     dcrutil.BlockValid,
+		FreshStake:   freshStake,
+		PoolSize:     c.nextPoolSize(parent),
+		Bits:         c.params.PowLimitBits,
+		SBits:        c.nextStakeDifficulty(parent),
+		Height:       uint32(height),
+		Timestamp:    c.startTime.Add(time.Duration(height) * c.params.TargetTimePerBlock),
+		StakeVersion: parentHeader.StakeVersion,
+	}
+	block.Header.Size = uint32(block.SerializeSize())
+
+	// With the minimum difficulty about every other nonce is a solution.
+	for {
+		hash := block.Header.BlockHash()
+		if blockchain.CheckProofOfWork(&hash, block.Header.Bits, c.params.PowLimit) == nil {
+			break
+		}
+		block.Header.Nonce++
+	}
+
+	filter, err := blockcf.Regular(block)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &chainBlock{
+		msg:    block,
+		hash:   block.BlockHash(),
+		height: height,
+		parent: parent,
+		filter: filter,
+	}
+	c.blocks[b.hash] = b
+	c.mainChain = append(c.mainChain, b)
+
+	log.Debugf("Connected block %v, height %d, %d transaction(s)", &b.hash, height,
+		len(block.Transactions)+len(block.STransactions))
+	return block, nil
+}
+
+// disconnectBlocks removes the main chain blocks above `forkHeight` and returns
+// them. The blocks remain known as side chain blocks.
+func (c *Chain) disconnectBlocks(forkHeight int32) ([]*wire.MsgBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if forkHeight < 0 || int(forkHeight) >= len(c.mainChain) {
+		return nil, fmt.Errorf("fork height %d is not in the main chain", forkHeight)
+	}
+
+	detached := make([]*wire.MsgBlock, 0, len(c.mainChain)-int(forkHeight)-1)
+	for _, b := range c.mainChain[forkHeight+1:] {
+		detached = append(detached, b.msg)
+	}
+	c.mainChain = c.mainChain[:forkHeight+1]
+	return detached, nil
+}
+
+// nextPoolSize returns the ticket pool size of the child of `parent`. Tickets
+// join the pool once mature, they never leave it as no votes are generated.
+func (c *Chain) nextPoolSize(parent *chainBlock) uint32 {
+	poolSize := parent.msg.Header.PoolSize
+	maturedHeight := int(parent.height) + 1 - int(c.params.TicketMaturity) - 1
+	if maturedHeight > 0 {
+		poolSize += uint32(c.mainChain[maturedHeight].msg.Header.FreshStake)
+	}
+	return poolSize
+}
+
+// nextStakeDifficulty returns the ticket price of the child of `parent` as
+// defined by DCP0001, which is active from the genesis block on simnet.
+func (c *Chain) nextStakeDifficulty(parent *chainBlock) int64 {
+	params := c.params
+	nextHeight := int64(parent.height) + 1
+	if nextHeight < int64(params.CoinbaseMaturity)+1 {
+		return params.MinimumStakeDiff
+	}
+
+	curDiff := parent.msg.Header.SBits
+	intervalSize := params.StakeDiffWindowSize
+	if nextHeight%intervalSize != 0 {
+		return curDiff
+	}
+
+	ticketMaturity := int64(params.TicketMaturity)
+	var prevPoolSizeAll int64
+	prevRetargetHeight := nextHeight - intervalSize - 1
+	if prevRetargetHeight >= 0 {
+		prevPoolSizeAll = int64(c.mainChain[prevRetargetHeight].msg.Header.PoolSize) +
+			c.sumPurchasedTickets(prevRetargetHeight, ticketMaturity)
+	}
+	if prevPoolSizeAll == 0 {
+		return curDiff
+	}
+
+	curPoolSizeAll := int64(parent.msg.Header.PoolSize) + c.sumPurchasedTickets(int64(parent.height), ticketMaturity)
+	return utils.CalcNextStakeDiff(params, nextHeight, curDiff, prevPoolSizeAll, curPoolSizeAll)
+}
+
+// sumPurchasedTickets returns the number of tickets purchased in the `count`
+// main chain blocks ending at `height`.
+func (c *Chain) sumPurchasedTickets(height, count int64) int64 {
+	var purchased int64
+	for h := height; h >= 0 && h > height-count; h-- {
+		purchased += int64(c.mainChain[h].msg.Header.FreshStake)
+	}
+	return purchased
+}
@@ -0,0 +1,163 @@
+package simnet
+
+import (
+	"net"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+)
+
+// peerConn is the connection of a wallet to the peer.
+type peerConn struct {
+	peer *Peer
+	c    net.Conn
+	pver uint32
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	sendHeaders bool
+	// bestKnown is the hash of the last header sent to the wallet, new
+	// blocks are announced from there.
+	bestKnown    chainhash.Hash
+	requestedTxs map[chainhash.Hash]struct{}
+}
+
+func (pc *peerConn) write(msg wire.Message) {
+	pc.writeMu.Lock()
+	defer pc.writeMu.Unlock()
+
+	err := wire.WriteMessage(pc.c, msg, pc.pver, pc.peer.chain.params.Net)
+	if err != nil {
+		log.Debugf("Failed to send %s to %v: %v", msg.Command(), pc.c.RemoteAddr(), err)
+		pc.c.Close()
+	}
+}
+
+func (pc *peerConn) handleMessage(msg wire.Message) {
+	chain := pc.peer.chain
+
+	switch m := msg.(type) {
+	case *wire.MsgPing:
+		pc.write(wire.NewMsgPong(m.Nonce))
+
+	case *wire.MsgGetHeaders:
+		headers := chain.Headers(m.BlockLocatorHashes, &m.HashStop)
+		reply := wire.NewMsgHeaders()
+		for _, header := range headers {
+			reply.AddBlockHeader(header)
+		}
+
+		pc.mu.Lock()
+		if len(headers) > 0 {
+			pc.bestKnown = headers[len(headers)-1].BlockHash()
+		} else {
+			pc.bestKnown, _ = chain.Tip()
+		}
+		pc.mu.Unlock()
+
+		pc.write(reply)
+
+	case *wire.MsgSendHeaders:
+		pc.mu.Lock()
+		pc.sendHeaders = true
+		pc.mu.Unlock()
+
+		// announce any block mined since the last getheaders reply
+		pc.announceHeaders()
+
+	case *wire.MsgGetCFilter:
+		if m.FilterType != wire.GCSFilterRegular {
+			log.Debugf("Ignoring request for unsupported filter type %v", m.FilterType)
+			return
+		}
+		filter, ok := chain.CFilter(&m.BlockHash)
+		if !ok {
+			log.Debugf("Ignoring request for cfilter of unknown block %v", &m.BlockHash)
+			return
+		}
+		pc.write(wire.NewMsgCFilter(&m.BlockHash, m.FilterType, filter.NBytes()))
+
+	case *wire.MsgGetData:
+		var notFound []*wire.InvVect
+		for _, inv := range m.InvList {
+			switch inv.Type {
+			case wire.InvTypeBlock:
+				if block, ok := chain.Block(&inv.Hash); ok {
+					pc.write(block)
+				} else {
+					log.Debugf("Ignoring request for unknown block %v", &inv.Hash)
+				}
+			case wire.InvTypeTx:
+				if tx, ok := pc.peer.MempoolTx(&inv.Hash); ok {
+					pc.write(tx)
+				} else {
+					notFound = append(notFound, inv)
+				}
+			}
+		}
+		if len(notFound) > 0 {
+			pc.write(&wire.MsgNotFound{InvList: notFound})
+		}
+
+	case *wire.MsgInv:
+		// request the announced transactions that are not in the mempool
+		getData := wire.NewMsgGetData()
+		pc.mu.Lock()
+		for _, inv := range m.InvList {
+			if inv.Type != wire.InvTypeTx {
+				continue
+			}
+			if _, ok := pc.peer.MempoolTx(&inv.Hash); ok {
+				continue
+			}
+			if _, ok := pc.requestedTxs[inv.Hash]; ok {
+				continue
+			}
+			pc.requestedTxs[inv.Hash] = struct{}{}
+			getData.AddInvVect(inv)
+		}
+		pc.mu.Unlock()
+
+		if len(getData.InvList) > 0 {
+			pc.write(getData)
+		}
+
+	case *wire.MsgTx:
+		txHash := m.TxHash()
+		pc.mu.Lock()
+		_, requested := pc.requestedTxs[txHash]
+		delete(pc.requestedTxs, txHash)
+		pc.mu.Unlock()
+
+		if !requested {
+			log.Debugf("Ignoring unrequested transaction %v", &txHash)
+			return
+		}
+		pc.peer.addToMempool(m, pc)
+	}
+}
+
+// announceHeaders sends the headers of the main chain blocks the wallet has not
+// been told about yet if it requested block announcements with sendheaders.
+func (pc *peerConn) announceHeaders() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if !pc.sendHeaders {
+		return
+	}
+
+	headers := pc.peer.chain.headersAfter(&pc.bestKnown)
+	if len(headers) == 0 {
+		return
+	}
+
+	msg := wire.NewMsgHeaders()
+	for _, header := range headers {
+		msg.AddBlockHeader(header)
+	}
+	pc.bestKnown = headers[len(headers)-1].BlockHash()
+	pc.write(msg)
+}
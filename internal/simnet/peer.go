@@ -0,0 +1,363 @@
+// Package simnet provides an in-process Decred p2p peer serving a generated
+// simnet chain, so that SPV syncing, rescans, reorgs, transaction publishing and
+// ticket purchases can be tested deterministically without network access.
+//
+// The peer serves headers, cfilters, blocks and mempool transactions to the
+// wallets connected to it, announces new blocks with headers messages and
+// accepts the transactions published by the wallets into its mempool. Tests
+// drive the chain by funding addresses, mining blocks and reorganizing it.
+package simnet
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/wire"
+)
+
+const (
+	// handshakeTimeout is the time allowed to exchange version and verack
+	// messages with a connecting wallet.
+	handshakeTimeout = 5 * time.Second
+
+	userAgentName    = "simnet-peer"
+	userAgentVersion = "1.0.0"
+)
+
+// Peer is a Decred peer listening on a loopback tcp address that serves a
+// Chain to SPV wallets.
+type Peer struct {
+	chain    *Chain
+	listener net.Listener
+
+	mu      sync.Mutex
+	conns   map[*peerConn]struct{}
+	mempool []*wire.MsgTx
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewPeer returns a Peer for a new Chain of `params`, listening on a random
+// loopback port. Wallets connect to it as a persistent peer using Addr.
+func NewPeer(params *chaincfg.Params) (*Peer, error) {
+	chain, err := NewChain(params)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Peer{
+		chain:    chain,
+		listener: listener,
+		conns:    make(map[*peerConn]struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.acceptConnections()
+	return p, nil
+}
+
+// Addr returns the host:port the peer is listening on.
+func (p *Peer) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Chain returns the chain served by the peer.
+func (p *Peer) Chain() *Chain {
+	return p.chain
+}
+
+// ConnectedWallets returns the number of wallets connected to the peer.
+func (p *Peer) ConnectedWallets() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// Close stops listening, disconnects all wallets and waits for the
+// connection handlers to return.
+func (p *Peer) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.listener.Close()
+	for pc := range p.conns {
+		pc.c.Close()
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Mempool returns the transactions waiting to be mined.
+func (p *Peer) Mempool() []*wire.MsgTx {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	mempool := make([]*wire.MsgTx, len(p.mempool))
+	copy(mempool, p.mempool)
+	return mempool
+}
+
+// MempoolTx returns the mempool transaction with `hash`.
+func (p *Peer) MempoolTx(hash *chainhash.Hash) (*wire.MsgTx, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, tx := range p.mempool {
+		if tx.TxHash() == *hash {
+			return tx, true
+		}
+	}
+	return nil, false
+}
+
+// SendToAddress adds a transaction paying `amount` to `address` to the mempool,
+// announces it to the connected wallets and returns it.
+func (p *Peer) SendToAddress(address string, amount dcrutil.Amount) (*wire.MsgTx, error) {
+	tx, err := p.chain.FundingTx(address, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	p.addToMempool(tx, nil)
+	return tx, nil
+}
+
+// MineBlocks mines `n` blocks, the first one including all mempool
+// transactions, and announces them to the connected wallets.
+func (p *Peer) MineBlocks(n int) ([]*wire.MsgBlock, error) {
+	p.mu.Lock()
+	txs := p.mempool
+	p.mempool = nil
+	p.mu.Unlock()
+
+	blocks := make([]*wire.MsgBlock, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := p.chain.connectBlock(txs)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+		txs = nil
+	}
+
+	p.announceBlocks()
+	return blocks, nil
+}
+
+// Reorg replaces the main chain blocks above `forkHeight` with `length` new
+// blocks and announces the new chain to the connected wallets. The new chain
+// must be longer than the replaced one for wallets to switch to it. Like dcrd
+// does, the transactions of the replaced blocks are returned to the mempool,
+// the new blocks only contain their coinbase.
+func (p *Peer) Reorg(forkHeight int32, length int) ([]*wire.MsgBlock, error) {
+	_, tipHeight := p.chain.Tip()
+	if int32(length) <= tipHeight-forkHeight {
+		return nil, fmt.Errorf("a chain of %d blocks does not replace the %d blocks above height %d",
+			length, tipHeight-forkHeight, forkHeight)
+	}
+
+	detached, err := p.chain.disconnectBlocks(forkHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []*wire.MsgTx
+	for _, block := range detached {
+		txs = append(txs, block.Transactions[1:]...)
+		txs = append(txs, block.STransactions...)
+	}
+	p.mu.Lock()
+	p.mempool = append(txs, p.mempool...)
+	p.mu.Unlock()
+
+	blocks := make([]*wire.MsgBlock, 0, length)
+	for i := 0; i < length; i++ {
+		block, err := p.chain.connectBlock(nil)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	log.Infof("Reorganized %d block(s) above height %d", len(detached), forkHeight)
+	p.announceBlocks()
+	return blocks, nil
+}
+
+// addToMempool adds `tx` to the mempool and announces it to the connected
+// wallets other than `from`, the wallet the transaction was received from.
+func (p *Peer) addToMempool(tx *wire.MsgTx, from *peerConn) {
+	txHash := tx.TxHash()
+
+	p.mu.Lock()
+	for _, mempoolTx := range p.mempool {
+		if mempoolTx.TxHash() == txHash {
+			p.mu.Unlock()
+			return
+		}
+	}
+	p.mempool = append(p.mempool, tx)
+	conns := p.connections()
+	p.mu.Unlock()
+
+	log.Debugf("Accepted transaction %v", &txHash)
+
+	inv := wire.NewMsgInv()
+	inv.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &txHash))
+	for _, pc := range conns {
+		if pc != from {
+			pc.write(inv)
+		}
+	}
+}
+
+func (p *Peer) announceBlocks() {
+	p.mu.Lock()
+	conns := p.connections()
+	p.mu.Unlock()
+
+	for _, pc := range conns {
+		pc.announceHeaders()
+	}
+}
+
+// connections returns the connected wallets. The caller must hold p.mu.
+func (p *Peer) connections() []*peerConn {
+	conns := make([]*peerConn, 0, len(p.conns))
+	for pc := range p.conns {
+		conns = append(conns, pc)
+	}
+	return conns
+}
+
+func (p *Peer) acceptConnections() {
+	defer p.wg.Done()
+
+	for {
+		c, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.serve(c)
+		}()
+	}
+}
+
+func (p *Peer) serve(c net.Conn) {
+	defer c.Close()
+
+	pc, err := p.handshake(c)
+	if err != nil {
+		log.Debugf("Handshake with %v failed: %v", c.RemoteAddr(), err)
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.conns[pc] = struct{}{}
+	p.mu.Unlock()
+
+	log.Infof("Wallet %v connected", c.RemoteAddr())
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.conns, pc)
+		p.mu.Unlock()
+		log.Infof("Wallet %v disconnected", c.RemoteAddr())
+	}()
+
+	for {
+		msg, _, err := wire.ReadMessage(c, pc.pver, p.chain.params.Net)
+		if _, ok := err.(*wire.MessageError); ok {
+			// the message is not supported, its payload was discarded
+			log.Debugf("Ignoring message from %v: %v", c.RemoteAddr(), err)
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		pc.handleMessage(msg)
+	}
+}
+
+// handshake exchanges version and verack messages with a connecting wallet.
+// The wallet sends its version message first.
+func (p *Peer) handshake(c net.Conn) (*peerConn, error) {
+	dcrnet := p.chain.params.Net
+	err := c.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	msg, _, err := wire.ReadMessage(c, wire.NodeCFVersion, dcrnet)
+	if err != nil {
+		return nil, err
+	}
+	remoteVersion, ok := msg.(*wire.MsgVersion)
+	if !ok {
+		return nil, fmt.Errorf("first message was %s, not version", msg.Command())
+	}
+
+	pver := wire.NodeCFVersion
+	if uint32(remoteVersion.ProtocolVersion) < pver {
+		pver = uint32(remoteVersion.ProtocolVersion)
+	}
+
+	tipHash, tipHeight := p.chain.Tip()
+	version, err := wire.NewMsgVersionFromConn(c, rand.Uint64(), tipHeight)
+	if err != nil {
+		return nil, err
+	}
+	version.ProtocolVersion = int32(pver)
+	version.Services = wire.SFNodeNetwork | wire.SFNodeCF
+	version.AddrMe.Services = version.Services
+	if err = version.AddUserAgent(userAgentName, userAgentVersion); err != nil {
+		return nil, err
+	}
+
+	if err = wire.WriteMessage(c, version, pver, dcrnet); err != nil {
+		return nil, err
+	}
+	if err = wire.WriteMessage(c, wire.NewMsgVerAck(), pver, dcrnet); err != nil {
+		return nil, err
+	}
+
+	msg, _, err = wire.ReadMessage(c, pver, dcrnet)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok = msg.(*wire.MsgVerAck); !ok {
+		return nil, fmt.Errorf("expected verack, received %s", msg.Command())
+	}
+
+	if err = c.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	return &peerConn{
+		peer:         p,
+		c:            c,
+		pver:         pver,
+		bestKnown:    tipHash,
+		requestedTxs: make(map[chainhash.Hash]struct{}),
+	}, nil
+}
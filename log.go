@@ -27,7 +27,9 @@ type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
 	os.Stdout.Write(p)
-	logRotator.Write(p)
+	if logRotator != nil {
+		logRotator.Write(p)
+	}
 	return len(p), nil
 }
 
@@ -98,6 +100,16 @@ func initLogRotator(logFile string) error {
 	return nil
 }
 
+// SubsystemLogger returns the logger registered for the subsystem identified
+// by `tag`, or a disabled logger if no such subsystem exists. It lets packages
+// that import dcrlibwallet, such as rpcserver, log to the shared backend.
+func SubsystemLogger(tag string) slog.Logger {
+	if logger, exists := subsystemLoggers[tag]; exists {
+		return logger
+	}
+	return slog.Disabled
+}
+
 // RegisterLogger should be called before logRotator is initialized.
 func RegisterLogger(tag string) (slog.Logger, error) {
 	if logRotator != nil {
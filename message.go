@@ -9,16 +9,21 @@ import (
 	w "github.com/decred/dcrwallet/wallet/v3"
 )
 
+// SignMessage signs `message` with the key of `address`. Watch-only wallets
+// with an external signer have the signer sign it and ignore `passphrase`.
 func (wallet *Wallet) SignMessage(passphrase []byte, address string, message string) ([]byte, error) {
-	lock := make(chan time.Time, 1)
-	defer func() {
-		lock <- time.Time{}
-	}()
-
 	ctx := wallet.shutdownContext()
-	err := wallet.internal.Unlock(ctx, passphrase, lock)
-	if err != nil {
-		return nil, translateError(err)
+	externalSigner := wallet.currentSigner()
+	if externalSigner == nil {
+		lock := make(chan time.Time, 1)
+		defer func() {
+			lock <- time.Time{}
+		}()
+
+		err := wallet.unlock(ctx, passphrase, lock)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	addr, err := dcrutil.DecodeAddress(address, wallet.chainParams)
@@ -37,6 +42,14 @@ func (wallet *Wallet) SignMessage(passphrase []byte, address string, message str
 		return nil, errors.New(ErrInvalidAddress)
 	}
 
+	if externalSigner != nil {
+		path, err := wallet.keyPath(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return externalSigner.SignMessage(path, message)
+	}
+
 	sig, err = wallet.internal.SignMessage(ctx, message, addr)
 	if err != nil {
 		return nil, translateError(err)
@@ -0,0 +1,982 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/chaincfg/v2/chainec"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/hdkeychain/v2"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrdata/txhelpers"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/decred/dcrwallet/wallet/v3/txauthor"
+	"github.com/decred/dcrwallet/wallet/v3/txrules"
+	"github.com/decred/dcrwallet/wallet/v3/txsizes"
+	"github.com/planetdecred/dcrlibwallet/txhelper"
+)
+
+const (
+	// MaxMultisigCosigners is the largest number of keys a multisig redeem
+	// script can hold without exceeding the maximum size of a script push.
+	MaxMultisigCosigners = 15
+
+	// multisigAddressGap is the number of unused multisig addresses that are
+	// imported into the wallet ahead of the last returned address, so that
+	// payments to addresses shared by other cosigners are tracked.
+	multisigAddressGap = 20
+
+	// multisigExternalBranch and multisigInternalBranch are the branches of
+	// the cosigners' account keys that receiving and change addresses of a
+	// multisig account are derived from.
+	multisigExternalBranch = 0
+	multisigInternalBranch = 1
+
+	// scriptVerifyFlags are the script flags used to check that the inputs of
+	// a tx signed outside of the wallet, by multisig cosigners or an external
+	// signer, are valid before it is broadcast.
+	scriptVerifyFlags = txscript.ScriptDiscourageUpgradableNops |
+		txscript.ScriptVerifyCleanStack |
+		txscript.ScriptVerifyCheckLockTimeVerify |
+		txscript.ScriptVerifyCheckSequenceVerify
+)
+
+// CreateMultisigAccount creates an m-of-n multisig account shared by the
+// cosigners whose account extended public keys are in the json-encoded
+// `xpubs` list. See CreateMultisigAccountRaw.
+func (mw *MultiWallet) CreateMultisigAccount(walletID int, name string, requiredSigs int32, xpubs string,
+	account int32, privPass []byte) (*MultisigAccount, error) {
+
+	var xpubList []string
+	if err := json.Unmarshal([]byte(xpubs), &xpubList); err != nil {
+		log.Error(err)
+		return nil, errors.New(ErrInvalid)
+	}
+
+	return mw.CreateMultisigAccountRaw(walletID, name, requiredSigs, xpubList, account, privPass)
+}
+
+// CreateMultisigAccountRaw creates an m-of-n multisig account shared by the
+// cosigners whose account extended public keys are in `xpubs`. The xpub of
+// `account`, which signs for this wallet, is added to the cosigners if it is
+// not in `xpubs` and the xpubs can be passed in any order, so every cosigner
+// derives the same addresses from the same set of keys.
+//
+// The first addresses of the account are imported into the wallet so that
+// payments to them are tracked during sync. Payments received before the
+// account was created are only found after a rescan.
+func (mw *MultiWallet) CreateMultisigAccountRaw(walletID int, name string, requiredSigs int32, xpubs []string,
+	account int32, privPass []byte) (*MultisigAccount, error) {
+
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+	}()
+
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	var existing MultisigAccount
+	err := mw.db.Select(q.Eq("WalletID", walletID), q.Eq("Name", name)).First(&existing)
+	if err == nil {
+		return nil, errors.New(ErrExist)
+	} else if err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	ctx := wallet.shutdownContext()
+
+	err = wallet.checkAccountXPubsFinal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ownXPub, err := wallet.internal.MasterPubKey(ctx, uint32(account))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	cosigners := map[string]struct{}{
+		ownXPub.String(): {},
+	}
+	for _, xpub := range xpubs {
+		extendedKey, err := hdkeychain.NewKeyFromString(xpub, wallet.chainParams)
+		if err != nil || extendedKey.IsPrivate() {
+			return nil, errors.New(ErrInvalid)
+		}
+		cosigners[extendedKey.String()] = struct{}{}
+	}
+
+	multisigAccount := &MultisigAccount{
+		WalletID:     walletID,
+		Name:         name,
+		RequiredSigs: requiredSigs,
+		Account:      account,
+		XPubs:        make([]string, 0, len(cosigners)),
+		CreatedAt:    time.Now().Unix(),
+	}
+	for xpub := range cosigners {
+		multisigAccount.XPubs = append(multisigAccount.XPubs, xpub)
+	}
+	sort.Strings(multisigAccount.XPubs)
+
+	if len(multisigAccount.XPubs) < 2 || len(multisigAccount.XPubs) > MaxMultisigCosigners ||
+		requiredSigs < 1 || int(requiredSigs) > len(multisigAccount.XPubs) {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	err = multisigAccount.importAddresses(wallet, privPass)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mw.db.Save(multisigAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return multisigAccount, nil
+}
+
+// MultisigAccount returns the multisig account with `multisigAccountID`.
+func (mw *MultiWallet) MultisigAccount(multisigAccountID int) (*MultisigAccount, error) {
+	var multisigAccount MultisigAccount
+	err := mw.db.One("ID", multisigAccountID, &multisigAccount)
+	if err == storm.ErrNotFound {
+		return nil, errors.New(ErrNotExist)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &multisigAccount, nil
+}
+
+// MultisigAccounts returns the json-encoded multisig accounts of the wallet
+// with `walletID`.
+func (mw *MultiWallet) MultisigAccounts(walletID int) (string, error) {
+	multisigAccounts, err := mw.MultisigAccountsRaw(walletID)
+	if err != nil {
+		return "", err
+	}
+
+	result, _ := json.Marshal(multisigAccounts)
+	return string(result), nil
+}
+
+// MultisigAccountsRaw returns the multisig accounts of the wallet with `walletID`.
+func (mw *MultiWallet) MultisigAccountsRaw(walletID int) ([]*MultisigAccount, error) {
+	multisigAccounts := make([]*MultisigAccount, 0)
+	err := mw.db.Select(q.Eq("WalletID", walletID)).OrderBy("ID").Find(&multisigAccounts)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	return multisigAccounts, nil
+}
+
+// CurrentMultisigAddress returns the last address returned by
+// NextMultisigAddress, or the first address of the account if none was.
+func (mw *MultiWallet) CurrentMultisigAddress(multisigAccountID int) (string, error) {
+	multisigAccount, err := mw.MultisigAccount(multisigAccountID)
+	if err != nil {
+		return "", err
+	}
+
+	wallet := mw.WalletWithID(multisigAccount.WalletID)
+	if wallet == nil {
+		return "", errors.New(ErrNotExist)
+	}
+
+	address, _, err := multisigAccount.address(multisigExternalBranch, multisigAccount.currentIndex(), wallet.chainParams)
+	if err != nil {
+		return "", err
+	}
+
+	return address.Address(), nil
+}
+
+// NextMultisigAddress returns a new address of the multisig account and
+// imports more addresses into the wallet if the new address is within
+// multisigAddressGap of the last imported address.
+func (mw *MultiWallet) NextMultisigAddress(multisigAccountID int, privPass []byte) (string, error) {
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+	}()
+
+	multisigAccount, err := mw.MultisigAccount(multisigAccountID)
+	if err != nil {
+		return "", err
+	}
+
+	wallet := mw.WalletWithID(multisigAccount.WalletID)
+	if wallet == nil {
+		return "", errors.New(ErrNotExist)
+	}
+
+	err = multisigAccount.importAddresses(wallet, privPass)
+	if err != nil {
+		return "", err
+	}
+
+	address, _, err := multisigAccount.address(multisigExternalBranch, multisigAccount.NextAddressIndex, wallet.chainParams)
+	if err != nil {
+		return "", err
+	}
+
+	multisigAccount.NextAddressIndex++
+	err = mw.db.Save(multisigAccount)
+	if err != nil {
+		return "", err
+	}
+
+	return address.Address(), nil
+}
+
+// importAddresses imports the redeem scripts of the receiving and change
+// addresses of the account up to multisigAddressGap ahead of the next
+// addresses into `wallet` so that they are watched during sync.
+func (multisigAccount *MultisigAccount) importAddresses(wallet *Wallet, privPass []byte) error {
+	receivingCount := multisigAccount.NextAddressIndex + multisigAddressGap
+	changeCount := multisigAccount.NextChangeIndex + multisigAddressGap
+	if receivingCount <= multisigAccount.ImportedAddresses && changeCount <= multisigAccount.ImportedChangeAddresses {
+		return nil
+	}
+
+	ctx := wallet.shutdownContext()
+	if !wallet.IsWatchingOnlyWallet() {
+		lock := make(chan time.Time, 1)
+		defer func() {
+			lock <- time.Time{} // send matters, not the value
+		}()
+
+		err := wallet.unlock(ctx, privPass, lock)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+
+	importBranch := func(branch, imported, count uint32) error {
+		for index := imported; index < count; index++ {
+			_, redeemScript, err := multisigAccount.address(branch, index, wallet.chainParams)
+			if err != nil {
+				return err
+			}
+
+			err = wallet.internal.ImportScript(ctx, redeemScript)
+			if err != nil && !errors.Is(err, errors.Exist) {
+				log.Errorf("[%d] Error importing multisig redeem script: %v", wallet.ID, err)
+				return translateError(err)
+			}
+		}
+		return nil
+	}
+
+	err := importBranch(multisigExternalBranch, multisigAccount.ImportedAddresses, receivingCount)
+	if err != nil {
+		return err
+	}
+	if receivingCount > multisigAccount.ImportedAddresses {
+		multisigAccount.ImportedAddresses = receivingCount
+	}
+
+	err = importBranch(multisigInternalBranch, multisigAccount.ImportedChangeAddresses, changeCount)
+	if err != nil {
+		return err
+	}
+	if changeCount > multisigAccount.ImportedChangeAddresses {
+		multisigAccount.ImportedChangeAddresses = changeCount
+	}
+
+	return nil
+}
+
+// MultisigAccountBalance returns the total, spendable and unconfirmed
+// balances of the imported addresses of the multisig account.
+func (mw *MultiWallet) MultisigAccountBalance(multisigAccountID int) (*Balance, error) {
+	multisigAccount, err := mw.MultisigAccount(multisigAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := mw.WalletWithID(multisigAccount.WalletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	return multisigAccount.balance(wallet)
+}
+
+// NewUnsignedMultisigTx returns a TxAuthor that spends the outputs of the
+// multisig account with `multisigAccountID`. The unsigned tx returned by
+// TxAuthor.UnsignedMultisigTx is passed to the cosigners to add their
+// signatures with SignMultisigTx. Change is sent to the next change address
+// of the account, the last imported one is reused if NextMultisigAddress or
+// SignMultisigTx were not called to import more since they were all used.
+func (mw *MultiWallet) NewUnsignedMultisigTx(multisigAccountID int) (*TxAuthor, error) {
+	multisigAccount, err := mw.MultisigAccount(multisigAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := mw.WalletWithID(multisigAccount.WalletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	changeIndex := multisigAccount.NextChangeIndex
+	if changeIndex < multisigAccount.ImportedChangeAddresses {
+		multisigAccount.NextChangeIndex++
+		err = mw.db.Save(multisigAccount)
+		if err != nil {
+			return nil, err
+		}
+	} else if changeIndex > 0 {
+		changeIndex--
+	}
+
+	changeAddress, _, err := multisigAccount.address(multisigInternalBranch, changeIndex, wallet.chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxAuthor{
+		sourceWallet:        wallet,
+		sourceAccountNumber: uint32(multisigAccount.Account),
+		multisigAccount:     multisigAccount,
+		destinations:        make([]TransactionDestination, 0),
+		changeAddress:       changeAddress.Address(),
+	}, nil
+}
+
+// UnsignedMultisigTx returns the unsigned tx spending outputs of the multisig
+// account that this TxAuthor was created for. Change is sent back to a change
+// address of the multisig account.
+func (tx *TxAuthor) UnsignedMultisigTx() (*MultisigTx, error) {
+	if tx.multisigAccount == nil {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	unsignedTx, err := tx.constructTransaction()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if unsignedTx.ChangeIndex >= 0 {
+		unsignedTx.RandomizeChangePosition()
+	}
+
+	return tx.multisigAccount.multisigTx(unsignedTx.Tx)
+}
+
+// constructMultisigTransaction selects outputs of the multisig account to
+// pay the tx destinations.
+func (tx *TxAuthor) constructMultisigTransaction() (*txauthor.AuthoredTx, error) {
+	multisigAccount := tx.multisigAccount
+	chainParams := tx.sourceWallet.chainParams
+
+	unspentOutputs, err := multisigAccount.unspentOutputs(tx.sourceWallet)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredConfirmations := tx.sourceWallet.RequiredConfirmations()
+	spendable := unspentOutputs[:0]
+	for _, output := range unspentOutputs {
+		if output.confirmations >= requiredConfirmations {
+			spendable = append(spendable, output)
+		}
+	}
+
+	// every input is redeemed with the required number of signatures and the
+	// redeem script, which is the same size for all addresses of the account.
+	_, redeemScript, err := multisigAccount.address(multisigExternalBranch, 0, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	redeemScriptPush, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+	if err != nil {
+		return nil, err
+	}
+	sigScriptSize := int(multisigAccount.RequiredSigs)*(1+73) + len(redeemScriptPush)
+
+	var changeSource txauthor.ChangeSource
+	var outputs = make([]*wire.TxOut, 0)
+	for _, destination := range tx.destinations {
+		if !destination.SendMax && (destination.AtomAmount <= 0 || destination.AtomAmount > MaxAmountAtom) {
+			return nil, errors.E(errors.Invalid, "invalid amount")
+		}
+
+		if destination.SendMax && changeSource != nil {
+			return nil, errors.E(errors.Invalid, "cannot send max amount to multiple recipients")
+		}
+
+		if destination.SendMax {
+			changeSource, err = txhelper.MakeTxChangeSource(destination.Address, chainParams)
+		} else {
+			var output *wire.TxOut
+			output, err = txhelper.MakeTxOutput(destination.Address, destination.AtomAmount, chainParams)
+			outputs = append(outputs, output)
+		}
+		if err != nil {
+			log.Errorf("constructMultisigTransaction: error preparing tx output: %v", err)
+			return nil, errors.New(ErrInvalidAddress)
+		}
+	}
+
+	sendMax := changeSource != nil
+	if !sendMax {
+		changeSource, err = txhelper.MakeTxChangeSource(tx.changeAddress, chainParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inputSource := func(target dcrutil.Amount) (*txauthor.InputDetail, error) {
+		detail := &txauthor.InputDetail{}
+		for _, output := range spendable {
+			if !sendMax && detail.Amount >= target {
+				break
+			}
+			detail.Amount += dcrutil.Amount(output.amount)
+			detail.Inputs = append(detail.Inputs, wire.NewTxIn(&output.outPoint, output.amount, nil))
+			detail.Scripts = append(detail.Scripts, output.pkScript)
+			detail.RedeemScriptSizes = append(detail.RedeemScriptSizes, sigScriptSize)
+		}
+		return detail, nil
+	}
+
+	authoredTx, err := txauthor.NewUnsignedTransaction(outputs, txrules.DefaultRelayFeePerKb, inputSource, changeSource)
+	if err != nil {
+		return nil, err
+	}
+
+	// txauthor estimates the change output as a P2PKH output, account for the
+	// difference in the size of the P2SH change output.
+	if authoredTx.ChangeIndex >= 0 {
+		authoredTx.EstimatedSignedSerializeSize += changeSource.ScriptSize() - txsizes.P2PKHPkScriptSize
+	}
+
+	return authoredTx, nil
+}
+
+// SignMultisigTx adds the signatures of the wallet of the multisig account to
+// the inputs of `txHex` that spend outputs of the account. Signatures already
+// on the inputs are kept, so the tx can be passed from one cosigner to the
+// next until it has enough signatures to be broadcast.
+func (mw *MultiWallet) SignMultisigTx(multisigAccountID int, txHex string, privPass []byte) (*MultisigTx, error) {
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+	}()
+
+	multisigAccount, wallet, msgTx, err := mw.multisigAccountTx(multisigAccountID, txHex)
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet.IsWatchingOnlyWallet() {
+		return nil, errors.New(ErrWalletIsWatchOnly)
+	}
+
+	ctx := wallet.shutdownContext()
+	lock := make(chan time.Time, 1)
+	defer func() {
+		lock <- time.Time{} // send matters, not the value
+	}()
+	err = wallet.unlock(ctx, privPass, lock)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	accountKey, err := wallet.internal.MasterPrivKey(ctx, uint32(multisigAccount.Account))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if !multisigAccount.hasXPub(accountKey) {
+		return nil, errors.New(ErrInvalid)
+	}
+	// accountKey is owned by the wallet and zeroed when it is locked
+	branchKeys := make(map[uint32]*hdkeychain.ExtendedKey, 2)
+	for _, branch := range []uint32{multisigExternalBranch, multisigInternalBranch} {
+		branchKey, err := accountKey.Child(branch)
+		if err != nil {
+			return nil, err
+		}
+		defer branchKey.Zero()
+		branchKeys[branch] = branchKey
+	}
+
+	addressIndexes, err := multisigAccount.addressIndexes(wallet.chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, txIn := range msgTx.TxIn {
+		pkScript, redeemScript, addressIndex, err := multisigAccount.prevOutScripts(ctx, wallet, txIn, addressIndexes)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := branchKeys[addressIndex.branch].Child(addressIndex.index)
+		if err != nil {
+			return nil, err
+		}
+		ecPrivKey, err := key.ECPrivKey()
+		key.Zero()
+		if err != nil {
+			return nil, err
+		}
+		privKey, pubKey := chainec.Secp256k1.PrivKeyFromBytes(ecPrivKey.Serialize())
+
+		getKey := txscript.KeyClosure(func(address dcrutil.Address) (chainec.PrivateKey, bool, error) {
+			pubKeyAddress, ok := address.(*dcrutil.AddressSecpPubKey)
+			if !ok || !bytes.Equal(pubKeyAddress.ScriptAddress(), pubKey.SerializeCompressed()) {
+				return nil, false, errors.New(ErrNotExist)
+			}
+			return privKey, true, nil
+		})
+
+		txIn.SignatureScript, err = txscript.SignTxOutput(wallet.chainParams, msgTx, i, pkScript,
+			txscript.SigHashAll, getKey, multisigScriptClosure(redeemScript), txIn.SignatureScript,
+			dcrec.STEcdsaSecp256k1)
+		if err != nil {
+			log.Errorf("[%d] Error signing multisig input %d: %v", wallet.ID, i, err)
+			return nil, err
+		}
+	}
+
+	// import change addresses to replace the ones used by NewUnsignedMultisigTx
+	err = multisigAccount.importAddresses(wallet, privPass)
+	if err != nil {
+		return nil, err
+	}
+	err = mw.db.Save(multisigAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return multisigAccount.multisigTx(msgTx)
+}
+
+// CombineMultisigTxs combines the signatures of copies of the same tx signed
+// separately by cosigners of the multisig account, passed as a json-encoded
+// list of tx hexes, into a single tx.
+func (mw *MultiWallet) CombineMultisigTxs(multisigAccountID int, txHexes string) (*MultisigTx, error) {
+	var txHexList []string
+	if err := json.Unmarshal([]byte(txHexes), &txHexList); err != nil {
+		log.Error(err)
+		return nil, errors.New(ErrInvalid)
+	}
+
+	return mw.CombineMultisigTxsRaw(multisigAccountID, txHexList)
+}
+
+// CombineMultisigTxsRaw combines the signatures of copies of the same tx
+// signed separately by cosigners of the multisig account into a single tx.
+func (mw *MultiWallet) CombineMultisigTxsRaw(multisigAccountID int, txHexes []string) (*MultisigTx, error) {
+	if len(txHexes) == 0 {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	multisigAccount, wallet, msgTx, err := mw.multisigAccountTx(multisigAccountID, txHexes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	signedTxs := make([]*wire.MsgTx, 0, len(txHexes))
+	for _, txHex := range txHexes {
+		signedTx, err := txhelpers.MsgTxFromHex(txHex)
+		if err != nil || signedTx.TxHash() != msgTx.TxHash() {
+			return nil, errors.New(ErrInvalid)
+		}
+		signedTxs = append(signedTxs, signedTx)
+	}
+
+	addressIndexes, err := multisigAccount.addressIndexes(wallet.chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := wallet.shutdownContext()
+	noKeys := txscript.KeyClosure(func(dcrutil.Address) (chainec.PrivateKey, bool, error) {
+		return nil, false, errors.New(ErrNotExist)
+	})
+
+	for i, txIn := range msgTx.TxIn {
+		pkScript, redeemScript, _, err := multisigAccount.prevOutScripts(ctx, wallet, txIn, addressIndexes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Gather the signatures of all copies in a single script, txscript
+		// keeps the ones that are valid for the keys of the redeem script.
+		builder := txscript.NewScriptBuilder()
+		for _, signedTx := range signedTxs {
+			pushes, err := txscript.PushedData(signedTx.TxIn[i].SignatureScript)
+			if err != nil || len(pushes) == 0 {
+				continue
+			}
+			for _, signature := range pushes[:len(pushes)-1] {
+				if len(signature) > 0 {
+					builder.AddData(signature)
+				}
+			}
+		}
+		signatures, err := builder.AddData(redeemScript).Script()
+		if err != nil {
+			return nil, err
+		}
+
+		txIn.SignatureScript, err = txscript.SignTxOutput(wallet.chainParams, msgTx, i, pkScript,
+			txscript.SigHashAll, noKeys, multisigScriptClosure(redeemScript), signatures,
+			dcrec.STEcdsaSecp256k1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return multisigAccount.multisigTx(msgTx)
+}
+
+// BroadcastMultisigTx publishes a tx spending outputs of the multisig account
+// once all its inputs have the required number of signatures and returns the
+// tx hash.
+func (mw *MultiWallet) BroadcastMultisigTx(multisigAccountID int, txHex string) (string, error) {
+	multisigAccount, wallet, msgTx, err := mw.multisigAccountTx(multisigAccountID, txHex)
+	if err != nil {
+		return "", err
+	}
+
+	addressIndexes, err := multisigAccount.addressIndexes(wallet.chainParams)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := wallet.shutdownContext()
+	for i, txIn := range msgTx.TxIn {
+		pkScript, _, _, err := multisigAccount.prevOutScripts(ctx, wallet, txIn, addressIndexes)
+		if err != nil {
+			return "", err
+		}
+
+		vm, err := txscript.NewEngine(pkScript, msgTx, i, scriptVerifyFlags, 0, nil)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			log.Errorf("[%d] Multisig input %d is not fully signed: %v", wallet.ID, i, err)
+			return "", errors.New(ErrMultisigThresholdNotMet)
+		}
+	}
+
+	n, err := wallet.internal.NetworkBackend()
+	if err != nil {
+		log.Error(err)
+		return "", errors.New(ErrNotConnected)
+	}
+
+	serializedTx, err := msgTx.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	txHash, err := wallet.internal.PublishTransaction(ctx, msgTx, serializedTx, n)
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	return txHash.String(), nil
+}
+
+// multisigAccountTx decodes `txHex` and returns it with the multisig account
+// with `multisigAccountID` and its wallet.
+func (mw *MultiWallet) multisigAccountTx(multisigAccountID int, txHex string) (*MultisigAccount, *Wallet, *wire.MsgTx, error) {
+	multisigAccount, err := mw.MultisigAccount(multisigAccountID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	wallet := mw.WalletWithID(multisigAccount.WalletID)
+	if wallet == nil {
+		return nil, nil, nil, errors.New(ErrNotExist)
+	}
+
+	msgTx, err := txhelpers.MsgTxFromHex(txHex)
+	if err != nil || len(msgTx.TxIn) == 0 {
+		return nil, nil, nil, errors.New(ErrInvalid)
+	}
+
+	return multisigAccount, wallet, msgTx, nil
+}
+
+// balance returns the balances of the imported addresses of the account.
+func (multisigAccount *MultisigAccount) balance(wallet *Wallet) (*Balance, error) {
+	unspentOutputs, err := multisigAccount.unspentOutputs(wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredConfirmations := wallet.RequiredConfirmations()
+	balance := &Balance{}
+	for _, output := range unspentOutputs {
+		balance.Total += output.amount
+		if output.confirmations == 0 {
+			balance.UnConfirmed += output.amount
+		}
+		if output.confirmations >= requiredConfirmations {
+			balance.Spendable += output.amount
+		}
+	}
+
+	return balance, nil
+}
+
+// hasXPub returns true if the xpub of `accountKey` is a key of the account.
+func (multisigAccount *MultisigAccount) hasXPub(accountKey *hdkeychain.ExtendedKey) bool {
+	xpub, err := accountKey.Neuter()
+	if err != nil {
+		return false
+	}
+
+	for _, cosignerXPub := range multisigAccount.XPubs {
+		if cosignerXPub == xpub.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// currentIndex returns the index of the last receiving address returned by
+// NextMultisigAddress, or 0 if none was.
+func (multisigAccount *MultisigAccount) currentIndex() uint32 {
+	if multisigAccount.NextAddressIndex == 0 {
+		return 0
+	}
+	return multisigAccount.NextAddressIndex - 1
+}
+
+// address returns the P2SH address at `index` of the account's `branch` and
+// its redeem script. The public keys of the cosigners at `index` are sorted
+// so that the redeem script does not depend on the order in which cosigners
+// added the xpubs.
+func (multisigAccount *MultisigAccount) address(branch, index uint32, chainParams *chaincfg.Params) (*dcrutil.AddressScriptHash, []byte, error) {
+	pubKeys := make([][]byte, 0, len(multisigAccount.XPubs))
+	for _, xpub := range multisigAccount.XPubs {
+		extendedKey, err := hdkeychain.NewKeyFromString(xpub, chainParams)
+		if err != nil {
+			return nil, nil, err
+		}
+		branchKey, err := extendedKey.Child(branch)
+		if err != nil {
+			return nil, nil, err
+		}
+		childKey, err := branchKey.Child(index)
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKey, err := childKey.ECPubKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKeys = append(pubKeys, pubKey.SerializeCompressed())
+	}
+
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+	})
+
+	pubKeyAddresses := make([]*dcrutil.AddressSecpPubKey, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		pubKeyAddress, err := dcrutil.NewAddressSecpPubKey(pubKey, chainParams)
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKeyAddresses[i] = pubKeyAddress
+	}
+
+	redeemScript, err := txscript.MultiSigScript(pubKeyAddresses, int(multisigAccount.RequiredSigs))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	address, err := dcrutil.NewAddressScriptHash(redeemScript, chainParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return address, redeemScript, nil
+}
+
+// multisigAddressIndex is the branch and index an address of a multisig
+// account is derived at.
+type multisigAddressIndex struct {
+	branch uint32
+	index  uint32
+}
+
+// addressIndexes maps the imported receiving and change addresses of the
+// account to their branch and index.
+func (multisigAccount *MultisigAccount) addressIndexes(chainParams *chaincfg.Params) (map[string]multisigAddressIndex, error) {
+	addressIndexes := make(map[string]multisigAddressIndex,
+		multisigAccount.ImportedAddresses+multisigAccount.ImportedChangeAddresses)
+
+	branches := map[uint32]uint32{
+		multisigExternalBranch: multisigAccount.ImportedAddresses,
+		multisigInternalBranch: multisigAccount.ImportedChangeAddresses,
+	}
+	for branch, imported := range branches {
+		for index := uint32(0); index < imported; index++ {
+			address, _, err := multisigAccount.address(branch, index, chainParams)
+			if err != nil {
+				return nil, err
+			}
+			addressIndexes[address.Address()] = multisigAddressIndex{branch, index}
+		}
+	}
+	return addressIndexes, nil
+}
+
+// prevOutScripts returns the pkScript of the output spent by `txIn` and the
+// redeem script and index of the account address it pays to. An error is
+// returned if the output does not pay to an imported address of the account.
+func (multisigAccount *MultisigAccount) prevOutScripts(ctx context.Context, wallet *Wallet, txIn *wire.TxIn,
+	addressIndexes map[string]multisigAddressIndex) (pkScript, redeemScript []byte, addressIndex multisigAddressIndex, err error) {
+
+	prevOut, err := wallet.internal.FetchOutput(ctx, &txIn.PreviousOutPoint)
+	if err != nil {
+		log.Errorf("[%d] Error fetching multisig input %v: %v", wallet.ID, &txIn.PreviousOutPoint, err)
+		return nil, nil, addressIndex, errors.New(ErrNotExist)
+	}
+
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(prevOut.Version, prevOut.PkScript, wallet.chainParams)
+	if err != nil || len(addresses) != 1 {
+		return nil, nil, addressIndex, errors.New(ErrInvalid)
+	}
+
+	addressIndex, ok := addressIndexes[addresses[0].Address()]
+	if !ok {
+		return nil, nil, addressIndex, errors.New(ErrInvalid)
+	}
+
+	_, redeemScript, err = multisigAccount.address(addressIndex.branch, addressIndex.index, wallet.chainParams)
+	if err != nil {
+		return nil, nil, addressIndex, err
+	}
+
+	return prevOut.PkScript, redeemScript, addressIndex, nil
+}
+
+type multisigOutput struct {
+	outPoint      wire.OutPoint
+	pkScript      []byte
+	amount        int64
+	confirmations int32
+}
+
+// unspentOutputs returns the outputs paying to the imported addresses of the
+// account that the wallet has not seen spent, oldest first.
+func (multisigAccount *MultisigAccount) unspentOutputs(wallet *Wallet) ([]*multisigOutput, error) {
+	addressIndexes, err := multisigAccount.addressIndexes(wallet.chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string]struct{}, len(addressIndexes))
+	for address := range addressIndexes {
+		addresses[address] = struct{}{}
+	}
+
+	unspent, err := wallet.internal.ListUnspent(wallet.shutdownContext(), 0, math.MaxInt32, addresses)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	outputs := make([]*multisigOutput, 0, len(unspent))
+	for _, output := range unspent {
+		txHash, err := chainhash.NewHashFromStr(output.TxID)
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := hex.DecodeString(output.ScriptPubKey)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := dcrutil.NewAmount(output.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, &multisigOutput{
+			outPoint:      *wire.NewOutPoint(txHash, output.Vout, output.Tree),
+			pkScript:      pkScript,
+			amount:        int64(amount),
+			confirmations: int32(output.Confirmations),
+		})
+	}
+
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return outputs[i].confirmations > outputs[j].confirmations
+	})
+
+	return outputs, nil
+}
+
+// multisigTx serializes `msgTx` and counts the signatures of its inputs.
+func (multisigAccount *MultisigAccount) multisigTx(msgTx *wire.MsgTx) (*MultisigTx, error) {
+	serializedTx, err := msgTx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := multisigAccount.RequiredSigs
+	for _, txIn := range msgTx.TxIn {
+		var inputSignatures int32
+		pushes, err := txscript.PushedData(txIn.SignatureScript)
+		if err == nil && len(pushes) > 0 {
+			for _, data := range pushes[:len(pushes)-1] {
+				if len(data) > 0 {
+					inputSignatures++
+				}
+			}
+		}
+		if inputSignatures < signatures {
+			signatures = inputSignatures
+		}
+	}
+
+	return &MultisigTx{
+		Hex:                hex.EncodeToString(serializedTx),
+		Hash:               msgTx.TxHash().String(),
+		Signatures:         signatures,
+		RequiredSignatures: multisigAccount.RequiredSigs,
+		Complete:           signatures >= multisigAccount.RequiredSigs,
+	}, nil
+}
+
+// multisigScriptClosure returns a txscript.ScriptDB that only knows `redeemScript`.
+func multisigScriptClosure(redeemScript []byte) txscript.ScriptClosure {
+	return func(address dcrutil.Address) ([]byte, error) {
+		if !bytes.Equal(address.ScriptAddress(), dcrutil.Hash160(redeemScript)) {
+			return nil, errors.New(ErrNotExist)
+		}
+		return redeemScript, nil
+	}
+}
@@ -26,14 +26,32 @@ type MultiWallet struct {
 	dbDriver string
 	rootDir  string
 	db       *storm.DB
+	dbCodec  *dbCodec
 
 	chainParams *chaincfg.Params
 	wallets     map[int]*Wallet
 	syncData    *syncData
+	politeia    *politeia
+	webhooks    *webhookDispatcher
+
+	// hiddenWallets are the archived and deleted wallets, see
+	// walletarchive.go.
+	hiddenWallets map[int]*Wallet
+
+	// passphraseAttemptsMu serializes the attempts at the startup and private
+	// passphrases, see verifyPassphraseAttempt.
+	passphraseAttemptsMu sync.Mutex
+
+	// seedBackupQuizzes are the current seed backup quizzes of the wallets,
+	// they are only kept in memory, see seedbackup.go.
+	seedBackupQuizzesMu sync.Mutex
+	seedBackupQuizzes   map[int]*SeedBackupQuiz
 
 	notificationListenersMu         sync.RWMutex
 	txAndBlockNotificationListeners map[string]TxAndBlockNotificationListener
+	txAndBlockEventListeners        map[string]TxAndBlockEventListener
 	blocksRescanProgressListener    BlocksRescanProgressListener
+	walletLockListeners             map[string]WalletLockListener
 
 	shuttingDown chan bool
 	cancelFuncs  []context.CancelFunc
@@ -58,7 +76,15 @@ func NewMultiWallet(rootDir, dbDriver, netType string) (*MultiWallet, error) {
 		return nil, errors.Errorf("failed to init logRotator: %v", err.Error())
 	}
 
-	walletsDb, err := storm.Open(filepath.Join(rootDir, walletsDbName))
+	walletsDbPath := filepath.Join(rootDir, walletsDbName)
+	err = compactDatabaseIfPending(walletsDbPath)
+	if err != nil && err != bolt.ErrTimeout {
+		log.Errorf("Error compacting wallets database: %v", err)
+		return nil, err
+	}
+
+	walletsDbCodec := &dbCodec{}
+	walletsDb, err := storm.Open(walletsDbPath, storm.Codec(walletsDbCodec))
 	if err != nil {
 		log.Errorf("Error opening wallets database: %s", err.Error())
 		if err == bolt.ErrTimeout {
@@ -75,10 +101,59 @@ func NewMultiWallet(rootDir, dbDriver, netType string) (*MultiWallet, error) {
 		return nil, err
 	}
 
+	// init database for saving/reading ticket price history
+	err = walletsDb.Init(&TicketPriceHistory{})
+	if err != nil {
+		log.Errorf("Error initializing ticket price history database: %s", err.Error())
+		return nil, err
+	}
+
+	// init database for saving/reading proposal and proposal vote objects
+	err = walletsDb.Init(&Proposal{})
+	if err == nil {
+		err = walletsDb.Init(&ProposalVote{})
+	}
+	if err != nil {
+		log.Errorf("Error initializing proposals database: %s", err.Error())
+		return nil, err
+	}
+
+	// init database for tracking tx confirmation notifications
+	err = walletsDb.Init(&TxConfirmation{})
+	if err != nil {
+		log.Errorf("Error initializing tx confirmations database: %s", err.Error())
+		return nil, err
+	}
+
+	// init database for saving/reading webhooks and undelivered webhook events
+	err = walletsDb.Init(&Webhook{})
+	if err == nil {
+		err = walletsDb.Init(&WebhookDelivery{})
+	}
+	if err != nil {
+		log.Errorf("Error initializing webhooks database: %s", err.Error())
+		return nil, err
+	}
+
+	// init database for saving/reading multisig accounts
+	err = walletsDb.Init(&MultisigAccount{})
+	if err != nil {
+		log.Errorf("Error initializing multisig accounts database: %s", err.Error())
+		return nil, err
+	}
+
+	// private values were indexed by earlier versions
+	err = dropPrivateIndexes(walletsDb)
+	if err != nil {
+		log.Errorf("Error dropping private indexes from the wallets database: %s", err.Error())
+		return nil, err
+	}
+
 	mw := &MultiWallet{
 		dbDriver:    dbDriver,
 		rootDir:     rootDir,
 		db:          walletsDb,
+		dbCodec:     walletsDbCodec,
 		chainParams: chainParams,
 		wallets:     make(map[int]*Wallet),
 		syncData: &syncData{
@@ -86,33 +161,69 @@ func NewMultiWallet(rootDir, dbDriver, netType string) (*MultiWallet, error) {
 			syncProgressListeners: make(map[string]SyncProgressListener),
 		},
 		txAndBlockNotificationListeners: make(map[string]TxAndBlockNotificationListener),
+		txAndBlockEventListeners:        make(map[string]TxAndBlockEventListener),
+		walletLockListeners:             make(map[string]WalletLockListener),
+		politeia:                        newPoliteia(),
+		hiddenWallets:                   make(map[int]*Wallet),
+		seedBackupQuizzes:               make(map[int]*SeedBackupQuiz),
+	}
+	mw.webhooks = newWebhookDispatcher(mw)
+
+	mw.listenForShutdown()
+
+	// the records of an encrypted database are loaded once it is unlocked
+	if mw.IsDatabaseEncrypted() {
+		mw.dbCodec.lock()
+	} else if err = mw.loadRecords(); err != nil {
+		return nil, err
 	}
 
+	logLevel := mw.ReadStringConfigValueForKey(LogLevelConfigKey)
+	SetLogLevels(logLevel)
+
+	log.Infof("Loaded %d wallets", mw.LoadedWalletsCount())
+
+	return mw, nil
+}
+
+// loadRecords loads the wallets saved in the database and resumes delivering
+// the undelivered webhook events.
+func (mw *MultiWallet) loadRecords() error {
 	// read saved wallets info from db and initialize wallets
 	query := mw.db.Select(q.True()).OrderBy("ID")
 	var wallets []*Wallet
-	err = query.Find(&wallets)
+	err := query.Find(&wallets)
 	if err != nil && err != storm.ErrNotFound {
-		return nil, err
+		return err
 	}
 
 	// prepare the wallets loaded from db for use
 	for _, wallet := range wallets {
-		err = wallet.prepare(rootDir, chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID))
+		if wallet.IsArchived() || wallet.IsDeleted() {
+			if err = mw.loadHiddenWallet(wallet); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		mw.wallets[wallet.ID] = wallet
 	}
+	mw.saveWalletsCount()
 
-	mw.listenForShutdown()
-
-	logLevel := mw.ReadStringConfigValueForKey(LogLevelConfigKey)
-	SetLogLevels(logLevel)
-
-	log.Infof("Loaded %d wallets", mw.LoadedWalletsCount())
-
-	return mw, nil
+	// resume delivering undelivered events if there are registered webhooks
+	webhooksCount, err := mw.db.Count(&Webhook{})
+	if err != nil {
+		return err
+	}
+	if webhooksCount > 0 {
+		return mw.webhooks.start()
+	}
+	return nil
 }
 
 func (mw *MultiWallet) Shutdown() {
@@ -128,12 +239,19 @@ func (mw *MultiWallet) Shutdown() {
 		wallet.Shutdown()
 	}
 
+	mw.webhooks.stop()
+
 	if mw.db != nil {
 		if err := mw.db.Close(); err != nil {
 			log.Errorf("db closed with error: %v", err)
 		} else {
 			log.Info("db closed successfully")
 		}
+
+		err := compactDatabaseIfPending(filepath.Join(mw.rootDir, walletsDbName))
+		if err != nil {
+			log.Errorf("Error compacting wallets database: %v", err)
+		}
 	}
 
 	if logRotator != nil {
@@ -148,7 +266,7 @@ func (mw *MultiWallet) SetStartupPassphrase(passphrase []byte, passphraseType in
 
 func (mw *MultiWallet) VerifyStartupPassphrase(startupPassphrase []byte) error {
 	var startupPassphraseHash []byte
-	err := mw.db.Get(walletsMetadataBucketName, walletstartupPassphraseField, &startupPassphraseHash)
+	err := mw.plaintextDb().Get(walletsMetadataBucketName, walletstartupPassphraseField, &startupPassphraseHash)
 	if err != nil && err != storm.ErrNotFound {
 		return err
 	}
@@ -162,12 +280,13 @@ func (mw *MultiWallet) VerifyStartupPassphrase(startupPassphrase []byte) error {
 	}
 
 	// startup passphrase was set, verify
-	err = bcrypt.CompareHashAndPassword(startupPassphraseHash, startupPassphrase)
-	if err != nil {
-		return errors.E(ErrInvalidPassphrase)
-	}
-
-	return nil
+	return mw.verifyPassphraseAttempt(startupPassphraseAttemptsField, func() error {
+		err := bcrypt.CompareHashAndPassword(startupPassphraseHash, startupPassphrase)
+		if err != nil {
+			return errors.E(ErrInvalidPassphrase)
+		}
+		return nil
+	}, mw.wipeWallets)
 }
 
 func (mw *MultiWallet) ChangeStartupPassphrase(oldPassphrase, newPassphrase []byte, passphraseType int32) error {
@@ -185,7 +304,10 @@ func (mw *MultiWallet) ChangeStartupPassphrase(oldPassphrase, newPassphrase []by
 		return err
 	}
 
-	err = mw.db.Set(walletsMetadataBucketName, walletstartupPassphraseField, startupPassphraseHash)
+	// re-encrypt the database with the new passphrase
+	err = mw.changeDatabaseEncryption(oldPassphrase, newPassphrase, func(db storm.Node) error {
+		return db.Set(walletsMetadataBucketName, walletstartupPassphraseField, startupPassphraseHash)
+	})
 	if err != nil {
 		return err
 	}
@@ -202,7 +324,10 @@ func (mw *MultiWallet) RemoveStartupPassphrase(oldPassphrase []byte) error {
 		return err
 	}
 
-	err = mw.db.Delete(walletsMetadataBucketName, walletstartupPassphraseField)
+	// save the database in plaintext
+	err = mw.changeDatabaseEncryption(oldPassphrase, nil, func(db storm.Node) error {
+		return db.Delete(walletsMetadataBucketName, walletstartupPassphraseField)
+	})
 	if err != nil {
 		return err
 	}
@@ -231,7 +356,18 @@ func (mw *MultiWallet) OpenWallets(startupPassphrase []byte) error {
 		return err
 	}
 
+	err = mw.unlockDatabase(startupPassphrase)
+	if err != nil {
+		return err
+	}
+
 	for _, wallet := range mw.wallets {
+		// wallets restored with UnarchiveWallet or RestoreDeletedWallet
+		// are already opened
+		if wallet.WalletOpened() {
+			continue
+		}
+
 		err = wallet.openWallet()
 		if err != nil {
 			return err
@@ -263,7 +399,8 @@ func (mw *MultiWallet) CreateWatchOnlyWallet(walletName, extendedPublicKey strin
 	}
 
 	return mw.saveNewWallet(wallet, func() error {
-		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID))
+		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
 		if err != nil {
 			return err
 		}
@@ -272,6 +409,77 @@ func (mw *MultiWallet) CreateWatchOnlyWallet(walletName, extendedPublicKey strin
 	})
 }
 
+// CreateMultiAccountWatchOnlyWallet creates a watch-only wallet that watches
+// the accounts in the json-encoded list of WatchOnlyAccount `accounts`. See
+// CreateMultiAccountWatchOnlyWalletRaw.
+func (mw *MultiWallet) CreateMultiAccountWatchOnlyWallet(walletName, accounts string) (*Wallet, error) {
+	var watchOnlyAccounts []*WatchOnlyAccount
+	if err := json.Unmarshal([]byte(accounts), &watchOnlyAccounts); err != nil {
+		log.Error(err)
+		return nil, errors.New(ErrInvalid)
+	}
+
+	return mw.CreateMultiAccountWatchOnlyWalletRaw(walletName, watchOnlyAccounts)
+}
+
+// CreateMultiAccountWatchOnlyWalletRaw creates a watch-only wallet that
+// watches the account xpubs of `accounts`. The first account replaces the
+// default account of the wallet, the others are imported with their own names.
+func (mw *MultiWallet) CreateMultiAccountWatchOnlyWalletRaw(walletName string, accounts []*WatchOnlyAccount) (*Wallet, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	accountNames := make(map[string]struct{}, len(accounts))
+	for _, account := range accounts {
+		if account == nil {
+			return nil, errors.New(ErrInvalid)
+		}
+		if _, ok := accountNames[account.Name]; ok || account.Name == "" {
+			return nil, errors.New(ErrInvalid)
+		}
+		accountNames[account.Name] = struct{}{}
+
+		err := mw.ValidateExtPubKey(account.ExtendedPublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wallet := &Wallet{
+		Name:                  walletName,
+		IsRestored:            true,
+		HasDiscoveredAccounts: true,
+	}
+
+	return mw.saveNewWallet(wallet, func() error {
+		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
+		if err != nil {
+			return err
+		}
+
+		err = wallet.createWatchingOnlyWallet(accounts[0].ExtendedPublicKey)
+		if err != nil {
+			return err
+		}
+
+		err = wallet.RenameAccount(0, accounts[0].Name)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts[1:] {
+			_, err = wallet.ImportWatchOnlyAccount(account.Name, account.ExtendedPublicKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 func (mw *MultiWallet) CreateNewWallet(walletName, privatePassphrase string, privatePassphraseType int32) (*Wallet, error) {
 	seed, err := GenerateSeed()
 	if err != nil {
@@ -291,16 +499,31 @@ func (mw *MultiWallet) CreateNewWallet(walletName, privatePassphrase string, pri
 	}
 
 	return mw.saveNewWallet(wallet, func() error {
-		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID))
+		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
 		if err != nil {
 			return err
 		}
 
-		return wallet.createWallet(privatePassphrase, seed)
+		return wallet.createWallet(privatePassphrase, seed, "")
 	})
 }
 
-func (mw *MultiWallet) RestoreWallet(walletName, seedMnemonic, privatePassphrase string, privatePassphraseType int32) (*Wallet, error) {
+// RestoreWallet restores a wallet from `seedMnemonic`, which may be in any
+// of the formats of ParseSeedInput. The unix timestamp `birthday` or the block
+// height `birthdayHeight` of the wallet birthday, the time the seed was
+// created at, can be passed to skip looking for transactions in earlier blocks
+// during the first sync. Pass 0 for an unknown birthday.
+func (mw *MultiWallet) RestoreWallet(walletName, seedMnemonic, privatePassphrase string, privatePassphraseType int32,
+	birthday int64, birthdayHeight int32) (*Wallet, error) {
+	return mw.RestoreWalletWithSeedPassphrase(walletName, seedMnemonic, "", privatePassphrase, privatePassphraseType,
+		birthday, birthdayHeight)
+}
+
+// RestoreWalletWithSeedPassphrase restores a wallet like RestoreWallet from a
+// BIP0039 mnemonic protected with `seedPassphrase`.
+func (mw *MultiWallet) RestoreWalletWithSeedPassphrase(walletName, seedMnemonic, seedPassphrase, privatePassphrase string,
+	privatePassphraseType int32, birthday int64, birthdayHeight int32) (*Wallet, error) {
 
 	wallet := &Wallet{
 		Name:                  walletName,
@@ -308,18 +531,25 @@ func (mw *MultiWallet) RestoreWallet(walletName, seedMnemonic, privatePassphrase
 		IsRestored:            true,
 		HasDiscoveredAccounts: false,
 	}
+	if err := wallet.setBirthday(birthday, birthdayHeight); err != nil {
+		return nil, err
+	}
 
 	return mw.saveNewWallet(wallet, func() error {
-		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID))
+		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
 		if err != nil {
 			return err
 		}
 
-		return wallet.createWallet(privatePassphrase, seedMnemonic)
+		return wallet.createWallet(privatePassphrase, seedMnemonic, seedPassphrase)
 	})
 }
 
-func (mw *MultiWallet) LinkExistingWallet(walletName, walletDataDir, originalPubPass string, privatePassphraseType int32) (*Wallet, error) {
+// LinkExistingWallet adopts the dcrwallet wallet in `walletDataDir`. The wallet
+// birthday can be passed like for RestoreWallet.
+func (mw *MultiWallet) LinkExistingWallet(walletName, walletDataDir, originalPubPass string, privatePassphraseType int32,
+	birthday int64, birthdayHeight int32) (*Wallet, error) {
 	// check if `walletDataDir` contains wallet.db
 	if !WalletExistsAt(walletDataDir) {
 		return nil, errors.New(ErrNotExist)
@@ -338,6 +568,9 @@ func (mw *MultiWallet) LinkExistingWallet(walletName, walletDataDir, originalPub
 		IsRestored:            true,
 		HasDiscoveredAccounts: false, // assume that account discovery hasn't been done
 	}
+	if err := wallet.setBirthday(birthday, birthdayHeight); err != nil {
+		return nil, err
+	}
 
 	return mw.saveNewWallet(wallet, func() error {
 		// move wallet.db and tx.db files to newly created dir for the wallet
@@ -355,7 +588,8 @@ func (mw *MultiWallet) LinkExistingWallet(walletName, walletDataDir, originalPub
 
 		// prepare the wallet for use and open it
 		err := (func() error {
-			err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID))
+			err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+				mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
 			if err != nil {
 				return err
 			}
@@ -410,6 +644,9 @@ func (mw *MultiWallet) saveNewWallet(wallet *Wallet, setupWallet func() error) (
 	}
 	// Perform database save operations in batch transaction
 	// for automatic rollback if error occurs at any point.
+	// new wallets are listed after the existing wallets
+	wallet.DisplayOrder = mw.nextWalletDisplayOrder()
+
 	err = mw.batchDbTransaction(func(db storm.Node) error {
 		// saving struct to update ID property with an auto-generated value
 		err := db.Save(wallet)
@@ -452,6 +689,7 @@ func (mw *MultiWallet) saveNewWallet(wallet *Wallet, setupWallet func() error) (
 	}
 
 	mw.wallets[wallet.ID] = wallet
+	mw.saveWalletsCount()
 
 	return wallet, nil
 }
@@ -476,6 +714,9 @@ func (mw *MultiWallet) RenameWallet(walletID int, newName string) error {
 	return mw.db.Save(wallet) // update WalletName field
 }
 
+// DeleteWallet deletes the wallet, its files are kept for the
+// DeletedWalletsRecoveryDaysConfigKey days during which it can be restored
+// with RestoreDeletedWallet.
 func (mw *MultiWallet) DeleteWallet(walletID int, privPass []byte) error {
 
 	if mw.IsConnectedToDecredNetwork() {
@@ -487,17 +728,54 @@ func (mw *MultiWallet) DeleteWallet(walletID int, privPass []byte) error {
 		return errors.New(ErrNotExist)
 	}
 
+	// keep the files of the wallet for the recovery period
+	if mw.deletedWalletsRecoveryPeriod() > 0 {
+		err := wallet.checkPrivatePassphrase(privPass)
+		if err != nil {
+			return translateError(err)
+		}
+
+		return mw.hideWallet(wallet, func() {
+			wallet.DeletedAt = time.Now()
+		})
+	}
+
 	err := wallet.deleteWallet(privPass)
 	if err != nil {
 		return translateError(err)
 	}
 
-	err = mw.db.DeleteStruct(wallet)
+	return mw.deleteWalletRecords(wallet)
+}
+
+// deleteWalletRecords deletes a wallet and the records that belong to it from
+// the wallets database.
+func (mw *MultiWallet) deleteWalletRecords(wallet *Wallet) error {
+	walletID := wallet.ID
+	err := mw.db.DeleteStruct(wallet)
 	if err != nil {
 		return translateError(err)
 	}
 
+	err = mw.db.Select(q.Eq("WalletID", walletID)).Delete(&TxConfirmation{})
+	if err != nil && err != storm.ErrNotFound {
+		log.Errorf("[%d] Error deleting tracked txs: %v", walletID, err)
+	}
+
+	err = mw.db.Select(q.Eq("WalletID", walletID)).Delete(&MultisigAccount{})
+	if err != nil && err != storm.ErrNotFound {
+		log.Errorf("[%d] Error deleting multisig accounts: %v", walletID, err)
+	}
+
+	mw.deleteSeedBackupQuiz(walletID)
+
+	err = mw.plaintextDb().Delete(walletsMetadataBucketName, walletPassphraseAttemptsField(walletID))
+	if err != nil && err != storm.ErrNotFound {
+		log.Errorf("[%d] Error deleting failed passphrase attempts: %v", walletID, err)
+	}
+
 	delete(mw.wallets, walletID)
+	mw.saveWalletsCount()
 
 	return nil
 }
@@ -516,7 +794,7 @@ func (mw *MultiWallet) VerifySeedForWallet(walletID int, seedMnemonic string, pr
 		return false, errors.New(ErrNotExist)
 	}
 
-	decryptedSeed, err := decryptWalletSeed(privpass, wallet.EncryptedSeed)
+	decryptedSeed, err := wallet.DecryptSeed(privpass)
 	if err != nil {
 		return false, err
 	}
@@ -540,10 +818,34 @@ func (mw *MultiWallet) NumWalletsNeedingSeedBackup() int32 {
 	return backupsNeeded
 }
 
+// LoadedWalletsCount returns the number of wallets that are not archived or
+// deleted. The wallets of an encrypted database are only loaded by
+// OpenWallets, until then the number of wallets when the database was last
+// unlocked is returned.
 func (mw *MultiWallet) LoadedWalletsCount() int32 {
+	if mw.IsDatabaseLocked() {
+		var count int32
+		err := mw.plaintextDb().Get(walletsMetadataBucketName, walletsCountField, &count)
+		if err != nil && err != storm.ErrNotFound {
+			log.Errorf("Error reading the wallets count: %v", err)
+		}
+		return count
+	}
 	return int32(len(mw.wallets))
 }
 
+// saveWalletsCount saves the number of loaded wallets for LoadedWalletsCount.
+func (mw *MultiWallet) saveWalletsCount() {
+	if mw.IsDatabaseLocked() {
+		return
+	}
+
+	err := mw.plaintextDb().Set(walletsMetadataBucketName, walletsCountField, int32(len(mw.wallets)))
+	if err != nil {
+		log.Errorf("Error saving the wallets count: %v", err)
+	}
+}
+
 func (mw *MultiWallet) OpenedWalletIDsRaw() []int {
 	walletIDs := make([]int, 0)
 	for _, wallet := range mw.wallets {
@@ -580,13 +882,25 @@ func (mw *MultiWallet) WalletNameExists(walletName string) (bool, error) {
 		return false, errors.E(ErrReservedWalletName)
 	}
 
-	err := mw.db.One("Name", walletName, &Wallet{})
-	if err == nil {
-		return true, nil
-	} else if err != storm.ErrNotFound {
+	return mw.walletNameUsed(walletName)
+}
+
+// walletNameUsed returns true if a wallet that isn't deleted has the name,
+// the names of deleted wallets can be reused during their recovery period.
+func (mw *MultiWallet) walletNameUsed(walletName string) (bool, error) {
+	var wallets []*Wallet
+	err := mw.db.Find("Name", walletName, &wallets)
+	if err == storm.ErrNotFound {
+		return false, nil
+	} else if err != nil {
 		return false, err
 	}
 
+	for _, wallet := range wallets {
+		if !wallet.IsDeleted() {
+			return true, nil
+		}
+	}
 	return false, nil
 }
 
@@ -612,7 +926,7 @@ func (mw *MultiWallet) ChangePrivatePassphraseForWallet(walletID int, oldPrivate
 
 	encryptedSeed := wallet.EncryptedSeed
 	if encryptedSeed != nil {
-		decryptedSeed, err := decryptWalletSeed(oldPrivatePassphrase, encryptedSeed)
+		decryptedSeed, err := wallet.DecryptSeed(oldPrivatePassphrase)
 		if err != nil {
 			return err
 		}
@@ -52,14 +52,14 @@ func (mw *MultiWallet) walletConfigReadFn(walletID int) configReadFn {
 }
 
 func (mw *MultiWallet) SaveUserConfigValue(key string, value interface{}) {
-	err := mw.db.Set(userConfigBucketName, key, value)
+	err := mw.configDb(key).Set(userConfigBucketName, key, value)
 	if err != nil {
 		log.Errorf("error setting config value for key: %s, error: %v", key, err)
 	}
 }
 
 func (mw *MultiWallet) ReadUserConfigValue(key string, valueOut interface{}) error {
-	err := mw.db.Get(userConfigBucketName, key, valueOut)
+	err := mw.configDb(key).Get(userConfigBucketName, key, valueOut)
 	if err != nil && err != storm.ErrNotFound {
 		log.Errorf("error reading config value for key: %s, error: %v", key, err)
 	}
@@ -67,7 +67,7 @@ func (mw *MultiWallet) ReadUserConfigValue(key string, valueOut interface{}) err
 }
 
 func (mw *MultiWallet) DeleteUserConfigValueForKey(key string) {
-	err := mw.db.Delete(userConfigBucketName, key)
+	err := mw.configDb(key).Delete(userConfigBucketName, key)
 	if err != nil {
 		log.Errorf("error deleting config value for key: %s, error: %v", key, err)
 	}
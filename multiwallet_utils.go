@@ -20,6 +20,11 @@ const (
 
 	walletsMetadataBucketName    = "metadata"
 	walletstartupPassphraseField = "startup-passphrase"
+
+	// walletsCountField is the number of wallets returned by
+	// LoadedWalletsCount, which is saved in plaintext to be read while the
+	// wallets of an encrypted database are not loaded.
+	walletsCountField = "wallets-count"
 )
 
 func (mw *MultiWallet) batchDbTransaction(dbOp func(node storm.Node) error) (err error) {
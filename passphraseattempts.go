@@ -0,0 +1,265 @@
+package dcrlibwallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/decred/dcrwallet/errors/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The failed attempts at the startup passphrase and at the private passphrase
+// of each wallet are counted to slow down guessing short passphrases such as
+// PINs. After freePassphraseAttempts failed attempts in a row, each failed
+// attempt locks the passphrase out for twice as long as the previous one. If
+// PassphraseAttemptsBeforeWipeConfigKey is set, the wallets protected by the
+// passphrase are deleted once that many attempts in a row failed.
+//
+// The counters are saved in the plaintext metadata bucket of the wallets
+// database, the startup passphrase attempts are counted before the database
+// is unlocked.
+
+const (
+	// PassphraseAttemptsBeforeWipeConfigKey is the number of failed attempts
+	// in a row at a passphrase after which the wallets it protects are
+	// deleted, all the wallets for the startup passphrase. 0 or unset
+	// disables deleting wallets.
+	PassphraseAttemptsBeforeWipeConfigKey = "passphrase_attempts_before_wipe"
+
+	startupPassphraseAttemptsField = "startup-passphrase-attempts"
+
+	freePassphraseAttempts = 3
+	minPassphraseLockout   = 30 * time.Second
+	maxPassphraseLockout   = 24 * time.Hour
+)
+
+type passphraseAttemptFn = func(verify func() error) error
+
+// passphraseAttempts is the record of the failed attempts in a row at a
+// passphrase.
+type passphraseAttempts struct {
+	FailedAttempts int32
+	LockedUntil    time.Time
+}
+
+func walletPassphraseAttemptsField(walletID int) string {
+	return fmt.Sprintf("wallet-%d-passphrase-attempts", walletID)
+}
+
+// StartupPassphraseAttempts returns the failed attempts at the startup
+// passphrase and how long it is locked out for.
+func (mw *MultiWallet) StartupPassphraseAttempts() (*PassphraseAttempts, error) {
+	return mw.passphraseAttemptsInfo(startupPassphraseAttemptsField)
+}
+
+// WalletPassphraseAttempts returns the failed attempts at the private
+// passphrase of a wallet and how long it is locked out for.
+func (mw *MultiWallet) WalletPassphraseAttempts(walletID int) (*PassphraseAttempts, error) {
+	if mw.WalletWithID(walletID) == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+	return mw.passphraseAttemptsInfo(walletPassphraseAttemptsField(walletID))
+}
+
+func (mw *MultiWallet) passphraseAttemptsInfo(field string) (*PassphraseAttempts, error) {
+	mw.passphraseAttemptsMu.Lock()
+	attempts, err := mw.readPassphraseAttempts(field)
+	mw.passphraseAttemptsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PassphraseAttempts{
+		FailedAttempts:    attempts.FailedAttempts,
+		RemainingAttempts: -1,
+	}
+
+	if maxAttempts := mw.ReadInt32ConfigValueForKey(PassphraseAttemptsBeforeWipeConfigKey, 0); maxAttempts > 0 {
+		info.RemainingAttempts = maxAttempts - attempts.FailedAttempts
+		if info.RemainingAttempts < 0 {
+			info.RemainingAttempts = 0
+		}
+	}
+
+	if lockout := time.Until(attempts.LockedUntil); lockout > 0 {
+		info.LockedUntil = attempts.LockedUntil.Unix()
+		// round up, the passphrase is still locked out in the last second
+		info.LockoutSeconds = int64((lockout + time.Second - 1) / time.Second)
+	}
+
+	return info, nil
+}
+
+func (mw *MultiWallet) readPassphraseAttempts(field string) (*passphraseAttempts, error) {
+	attempts := &passphraseAttempts{}
+	err := mw.plaintextDb().Get(walletsMetadataBucketName, field, attempts)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// walletPassphraseAttemptFn returns the function wallets verify their private
+// passphrase with, see verifyPassphraseAttempt.
+func (mw *MultiWallet) walletPassphraseAttemptFn(walletID int) passphraseAttemptFn {
+	return func(verify func() error) error {
+		return mw.verifyPassphraseAttempt(walletPassphraseAttemptsField(walletID), verify, func() error {
+			return mw.wipeWallet(walletID)
+		})
+	}
+}
+
+// verifyPassphraseAttempt calls verify unless the passphrase of `field` is
+// locked out and counts the attempt as failed if verify returns an invalid
+// passphrase error. wipe is called once the failed attempts reach the
+// PassphraseAttemptsBeforeWipeConfigKey limit.
+func (mw *MultiWallet) verifyPassphraseAttempt(field string, verify func() error, wipe func() error) error {
+	// attempts are verified one at a time so that no attempt is made before
+	// the previous failed attempt is counted
+	mw.passphraseAttemptsMu.Lock()
+	defer mw.passphraseAttemptsMu.Unlock()
+
+	attempts, err := mw.readPassphraseAttempts(field)
+	if err != nil {
+		return err
+	}
+
+	if time.Now().Before(attempts.LockedUntil) {
+		return errors.New(ErrPassphraseLockedOut)
+	}
+
+	verifyErr := verify()
+	if verifyErr == nil {
+		if attempts.FailedAttempts > 0 {
+			err = mw.plaintextDb().Delete(walletsMetadataBucketName, field)
+			if err != nil {
+				log.Errorf("error resetting failed passphrase attempts: %v", err)
+			}
+		}
+		return nil
+	}
+
+	if verifyErr.Error() != ErrInvalidPassphrase {
+		return verifyErr
+	}
+
+	attempts.FailedAttempts++
+
+	maxAttempts := mw.ReadInt32ConfigValueForKey(PassphraseAttemptsBeforeWipeConfigKey, 0)
+	if maxAttempts > 0 && attempts.FailedAttempts >= maxAttempts {
+		log.Warnf("%d failed passphrase attempts, deleting wallets", attempts.FailedAttempts)
+		if err = wipe(); err != nil {
+			log.Errorf("error deleting wallets after failed passphrase attempts: %v", err)
+			return err
+		}
+		return errors.New(ErrPassphraseAttemptsExceeded)
+	}
+
+	if attempts.FailedAttempts > freePassphraseAttempts {
+		attempts.LockedUntil = time.Now().Add(passphraseLockout(attempts.FailedAttempts))
+	}
+
+	err = mw.plaintextDb().Set(walletsMetadataBucketName, field, attempts)
+	if err != nil {
+		log.Errorf("error saving failed passphrase attempts: %v", err)
+	}
+
+	return verifyErr
+}
+
+// passphraseLockout returns how long a passphrase is locked out for after
+// `failedAttempts` failed attempts in a row.
+func passphraseLockout(failedAttempts int32) time.Duration {
+	lockout := minPassphraseLockout
+	for i := failedAttempts - freePassphraseAttempts; i > 1 && lockout < maxPassphraseLockout; i-- {
+		lockout *= 2
+	}
+	if lockout > maxPassphraseLockout {
+		lockout = maxPassphraseLockout
+	}
+	return lockout
+}
+
+// wipeWallet deletes a wallet without its private passphrase.
+func (mw *MultiWallet) wipeWallet(walletID int) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	if mw.IsConnectedToDecredNetwork() {
+		mw.CancelSync()
+	}
+
+	wallet.Shutdown()
+	err := os.RemoveAll(wallet.dataDir)
+	if err != nil {
+		return err
+	}
+
+	return mw.deleteWalletRecords(wallet)
+}
+
+// wipeWallets deletes all the wallets and the wallets database content,
+// including the startup passphrase and the user config.
+func (mw *MultiWallet) wipeWallets() error {
+	if mw.IsConnectedToDecredNetwork() {
+		mw.CancelSync()
+	}
+	mw.webhooks.stop()
+
+	for _, wallet := range mw.wallets {
+		wallet.Shutdown()
+	}
+	mw.wallets = make(map[int]*Wallet)
+	mw.hiddenWallets = make(map[int]*Wallet)
+
+	// the wallets of an encrypted database are not loaded before it is
+	// unlocked, remove the data directories of all wallets, which are named
+	// after the wallet IDs
+	entries, err := ioutil.ReadDir(mw.rootDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil || !entry.IsDir() {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(mw.rootDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the content of an encrypted database can't be deleted record by
+	// record before it is unlocked, drop the buckets instead
+	err = mw.db.Bolt.Update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if err = tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	mw.dbCodec.setKey(nil)
+
+	// drop the freed pages that still hold the deleted values
+	return mw.plaintextDb().Set(walletsMetadataBucketName, walletsDbCompactPendingField, true)
+}
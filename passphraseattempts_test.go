@@ -0,0 +1,170 @@
+package dcrlibwallet
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Passphrase attempts", func() {
+	const passphrase = "1234"
+
+	var (
+		mw     *MultiWallet
+		wallet *Wallet
+	)
+
+	// endLockout ends the lockout of the passphrase of `field` as if the
+	// lockout time had passed.
+	endLockout := func(field string) {
+		attempts, err := mw.readPassphraseAttempts(field)
+		Expect(err).To(BeNil())
+		attempts.LockedUntil = time.Now().Add(-time.Second)
+		Expect(mw.plaintextDb().Set(walletsMetadataBucketName, field, attempts)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		var err error
+		mw = newTestMultiWallet("testnet3")
+
+		wallet, err = mw.CreateNewWallet("wallet", passphrase, PassphraseTypePin)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("locks out and wipes after failed startup passphrase attempts", func() {
+		Expect(mw.SetStartupPassphrase([]byte(passphrase), PassphraseTypePin)).To(BeNil())
+
+		for i := 0; i < freePassphraseAttempts; i++ {
+			Expect(mw.VerifyStartupPassphrase([]byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		}
+		attempts, err := mw.StartupPassphraseAttempts()
+		Expect(err).To(BeNil())
+		Expect(attempts.FailedAttempts).To(Equal(int32(freePassphraseAttempts)))
+		Expect(attempts.RemainingAttempts).To(Equal(int32(-1)))
+		Expect(attempts.LockoutSeconds).To(BeZero())
+
+		Expect(mw.OpenWallets([]byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		attempts, err = mw.StartupPassphraseAttempts()
+		Expect(err).To(BeNil())
+		Expect(attempts.LockoutSeconds).To(BeNumerically("~", minPassphraseLockout.Seconds(), 1))
+		Expect(attempts.LockedUntil).To(BeNumerically(">", time.Now().Unix()))
+		Expect(mw.VerifyStartupPassphrase([]byte(passphrase))).To(MatchError(ErrPassphraseLockedOut))
+
+		endLockout(startupPassphraseAttemptsField)
+		Expect(mw.VerifyStartupPassphrase([]byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		attempts, err = mw.StartupPassphraseAttempts()
+		Expect(err).To(BeNil())
+		Expect(attempts.LockoutSeconds).To(BeNumerically("~", 2*minPassphraseLockout.Seconds(), 1))
+
+		endLockout(startupPassphraseAttemptsField)
+		Expect(mw.VerifyStartupPassphrase([]byte(passphrase))).To(BeNil())
+		attempts, err = mw.StartupPassphraseAttempts()
+		Expect(err).To(BeNil())
+		Expect(attempts.FailedAttempts).To(BeZero())
+
+		By("Deleting the wallets once the attempts before wipe failed")
+		mw.SetInt32ConfigValueForKey(PassphraseAttemptsBeforeWipeConfigKey, 2)
+		Expect(mw.VerifyStartupPassphrase([]byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		attempts, err = mw.StartupPassphraseAttempts()
+		Expect(err).To(BeNil())
+		Expect(attempts.RemainingAttempts).To(Equal(int32(1)))
+
+		Expect(mw.VerifyStartupPassphrase([]byte("0000"))).To(MatchError(ErrPassphraseAttemptsExceeded))
+		Expect(mw.LoadedWalletsCount()).To(BeZero())
+		Expect(mw.IsStartupSecuritySet()).To(BeFalse())
+		Expect(mw.IsDatabaseEncrypted()).To(BeFalse())
+		_, err = os.Stat(wallet.dataDir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(mw.OpenWallets(nil)).To(BeNil())
+
+		_, err = mw.CreateNewWallet("new wallet", passphrase, PassphraseTypePin)
+		Expect(err).To(BeNil())
+		mw = reopenTestMultiWallet(mw)
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+	})
+
+	It("wipes the wallets of a locked database", func() {
+		Expect(mw.SetStartupPassphrase([]byte(passphrase), PassphraseTypePin)).To(BeNil())
+		mw.SetInt32ConfigValueForKey(PassphraseAttemptsBeforeWipeConfigKey, 1)
+
+		mw = reopenTestMultiWallet(mw)
+		Expect(mw.IsDatabaseLocked()).To(BeTrue())
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+
+		Expect(mw.OpenWallets([]byte("0000"))).To(MatchError(ErrPassphraseAttemptsExceeded))
+		Expect(mw.LoadedWalletsCount()).To(BeZero())
+		Expect(mw.IsDatabaseEncrypted()).To(BeFalse())
+		_, err := os.Stat(wallet.dataDir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("locks out and wipes after failed private passphrase attempts", func() {
+		address, err := wallet.CurrentAddress(0)
+		Expect(err).To(BeNil())
+
+		for i := 0; i <= freePassphraseAttempts; i++ {
+			Expect(mw.UnlockWallet(wallet.ID, []byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		}
+		attempts, err := mw.WalletPassphraseAttempts(wallet.ID)
+		Expect(err).To(BeNil())
+		Expect(attempts.FailedAttempts).To(Equal(int32(freePassphraseAttempts + 1)))
+		Expect(attempts.LockoutSeconds).To(BeNumerically(">", 0))
+		_, err = wallet.SignMessage([]byte(passphrase), address, "message")
+		Expect(err).To(MatchError(ErrPassphraseLockedOut))
+
+		field := walletPassphraseAttemptsField(wallet.ID)
+		endLockout(field)
+		Expect(mw.UnlockWallet(wallet.ID, []byte(passphrase))).To(BeNil())
+		wallet.LockWallet()
+		attempts, err = mw.WalletPassphraseAttempts(wallet.ID)
+		Expect(err).To(BeNil())
+		Expect(attempts.FailedAttempts).To(BeZero())
+
+		By("Deleting the wallet once the attempts before wipe failed")
+		mw.SetInt32ConfigValueForKey(PassphraseAttemptsBeforeWipeConfigKey, 1)
+		_, err = wallet.SignMessage([]byte("0000"), address, "message")
+		Expect(err).To(MatchError(ErrPassphraseAttemptsExceeded))
+		Expect(mw.WalletWithID(wallet.ID)).To(BeNil())
+		_, err = mw.WalletPassphraseAttempts(wallet.ID)
+		Expect(err).To(MatchError(ErrNotExist))
+		_, err = os.Stat(wallet.dataDir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("counts the attempts at every use of the private passphrase", func() {
+		attempts := func() int32 {
+			info, err := mw.WalletPassphraseAttempts(wallet.ID)
+			Expect(err).To(BeNil())
+			return info.FailedAttempts
+		}
+
+		_, err := wallet.AccountXPub(0, []byte("0000"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		Expect(attempts()).To(Equal(int32(1)))
+
+		_, err = wallet.DecryptSeed([]byte("0000"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		Expect(attempts()).To(Equal(int32(2)))
+
+		Expect(mw.DeleteWallet(wallet.ID, []byte("0000"))).To(MatchError(ErrInvalidPassphrase))
+		Expect(attempts()).To(Equal(int32(3)))
+
+		_, err = mw.VerifySeedForWallet(wallet.ID, "", []byte("0000"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		Expect(attempts()).To(Equal(int32(4)))
+
+		_, err = wallet.DecryptSeed([]byte(passphrase))
+		Expect(err).To(MatchError(ErrPassphraseLockedOut))
+
+		endLockout(walletPassphraseAttemptsField(wallet.ID))
+		_, err = wallet.DecryptSeed([]byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(attempts()).To(BeZero())
+	})
+})
@@ -0,0 +1,631 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+const (
+	PoliteiaHostConfigKey = "politeia_host"
+
+	PoliteiaMainnetHost = "https://proposals.decred.org"
+	PoliteiaTestnetHost = "https://test-proposals.decred.org"
+
+	politeiaApiPath          = "/api/v1"
+	politeiaCsrfTokenHeader  = "X-Csrf-Token"
+	politeiaVettedBatchLimit = 20
+
+	// Politeia vote statuses as defined by the politeiawww v1 api.
+	ProposalVoteStatusInvalid       int32 = 0
+	ProposalVoteStatusNotAuthorized int32 = 1
+	ProposalVoteStatusAuthorized    int32 = 2
+	ProposalVoteStatusStarted       int32 = 3
+	ProposalVoteStatusFinished      int32 = 4
+	ProposalVoteStatusDoesntExist   int32 = 5
+
+	// Politeia proposal statuses as defined by the politeiawww v1 api.
+	proposalStatusPublic    int32 = 4
+	proposalStatusAbandoned int32 = 6
+
+	ProposalCategoryAll       int32 = 0
+	ProposalCategoryPre       int32 = 1
+	ProposalCategoryActive    int32 = 2
+	ProposalCategoryApproved  int32 = 3
+	ProposalCategoryRejected  int32 = 4
+	ProposalCategoryAbandoned int32 = 5
+
+	ProposalVoteStateSuccess = "success"
+	ProposalVoteStateFailed  = "failed"
+)
+
+// politeia is a client for a Politeia-compatible http api.
+// The csrf token and cookies obtained from the server
+// are reused for subsequent POST requests.
+type politeia struct {
+	mu        sync.Mutex
+	client    *http.Client
+	csrfToken string
+}
+
+func newPoliteia() *politeia {
+	jar, _ := cookiejar.New(nil)
+	return &politeia{
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// PoliteiaHost returns the Politeia server url that is used to fetch
+// proposals and submit votes, defaulting to the official server
+// for the current network.
+func (mw *MultiWallet) PoliteiaHost() string {
+	host := mw.ReadStringConfigValueForKey(PoliteiaHostConfigKey)
+	if host != "" {
+		return host
+	}
+
+	if mw.chainParams.Name == chaincfg.MainNetParams().Name {
+		return PoliteiaMainnetHost
+	}
+	return PoliteiaTestnetHost
+}
+
+// SetPoliteiaHost sets the Politeia server url to use. Previously cached
+// proposals are cleared since they may not exist on the new server.
+func (mw *MultiWallet) SetPoliteiaHost(host string) error {
+	host = strings.TrimSuffix(host, "/")
+	if host == mw.PoliteiaHost() {
+		return nil
+	}
+
+	err := mw.db.Drop(&Proposal{})
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	mw.politeia.mu.Lock()
+	mw.politeia.csrfToken = ""
+	mw.politeia.mu.Unlock()
+
+	mw.SetStringConfigValueForKey(PoliteiaHostConfigKey, host)
+	return mw.db.Init(&Proposal{})
+}
+
+// SyncPoliteiaProposals fetches all vetted proposals and their vote summaries
+// from the Politeia server and saves them to the local database, replacing
+// previously cached copies.
+func (mw *MultiWallet) SyncPoliteiaProposals() error {
+	host := mw.PoliteiaHost()
+
+	var after string
+	for {
+		var vettedResponse struct {
+			Proposals []politeiaProposalRecord `json:"proposals"`
+		}
+
+		path := "/proposals/vetted"
+		if after != "" {
+			path += "?after=" + after
+		}
+		err := mw.politeia.get(host, path, &vettedResponse)
+		if err != nil {
+			return err
+		}
+
+		if len(vettedResponse.Proposals) == 0 {
+			break
+		}
+
+		tokens := make([]string, len(vettedResponse.Proposals))
+		for i, record := range vettedResponse.Proposals {
+			tokens[i] = record.CensorshipRecord.Token
+		}
+
+		summaries, err := mw.politeia.batchVoteSummary(host, tokens)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range vettedResponse.Proposals {
+			proposal := record.proposal()
+			if summary, ok := summaries[proposal.Token]; ok {
+				summary.updateProposal(proposal)
+			}
+			proposal.Category = proposalCategory(proposal)
+
+			err = mw.saveOrUpdateProposal(proposal)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(vettedResponse.Proposals) < politeiaVettedBatchLimit {
+			break
+		}
+		after = tokens[len(tokens)-1]
+	}
+
+	return nil
+}
+
+func (mw *MultiWallet) saveOrUpdateProposal(proposal *Proposal) error {
+	var existing Proposal
+	err := mw.db.One("Token", proposal.Token, &existing)
+	if err == nil {
+		proposal.ID = existing.ID
+	} else if err != storm.ErrNotFound {
+		return err
+	}
+
+	// Save replaces every field of an existing proposal, unlike Update
+	// which skips zero values such as vote results that were reset.
+	return mw.db.Save(proposal)
+}
+
+// GetProposals returns json-encoded proposals in the specified category
+// from the local database. SyncPoliteiaProposals should be called
+// to update the local database.
+func (mw *MultiWallet) GetProposals(category, offset, limit int32, newestFirst bool) (string, error) {
+	proposals, err := mw.GetProposalsRaw(category, offset, limit, newestFirst)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(proposals)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (mw *MultiWallet) GetProposalsRaw(category, offset, limit int32, newestFirst bool) ([]Proposal, error) {
+	var query storm.Query
+	if category == ProposalCategoryAll {
+		query = mw.db.Select(q.True())
+	} else {
+		query = mw.db.Select(q.Eq("Category", category))
+	}
+
+	if offset > 0 {
+		query = query.Skip(int(offset))
+	}
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if newestFirst {
+		query = query.OrderBy("PublishedAt").Reverse()
+	} else {
+		query = query.OrderBy("PublishedAt")
+	}
+
+	proposals := make([]Proposal, 0)
+	err := query.Find(&proposals)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	return proposals, nil
+}
+
+// GetProposal returns the json-encoded locally saved proposal with the specified token.
+func (mw *MultiWallet) GetProposal(token string) (string, error) {
+	proposal, err := mw.GetProposalRaw(token)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(proposal)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (mw *MultiWallet) GetProposalRaw(token string) (*Proposal, error) {
+	var proposal Proposal
+	err := mw.db.One("Token", token, &proposal)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return nil, errors.New(ErrNotExist)
+		}
+		return nil, err
+	}
+
+	return &proposal, nil
+}
+
+// CastProposalVotes votes on the proposal with the specified token using all
+// of the wallet's tickets that are eligible to vote on the proposal and have not
+// already voted. Each vote message is signed with the ticket's commitment address.
+// The outcome of each vote is saved locally and the json-encoded records returned.
+func (mw *MultiWallet) CastProposalVotes(walletID int, token, voteOptionID string, privPass []byte) (string, error) {
+	votes, err := mw.CastProposalVotesRaw(walletID, token, voteOptionID, privPass)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(votes)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (mw *MultiWallet) CastProposalVotesRaw(walletID int, token, voteOptionID string, privPass []byte) ([]*ProposalVote, error) {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	proposal, err := mw.GetProposalRaw(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal.VoteStatus != ProposalVoteStatusStarted {
+		return nil, errors.E(ErrFailedPrecondition, "proposal voting is not active")
+	}
+
+	var voteBits uint64
+	var validOption bool
+	for _, option := range proposal.VoteOptions {
+		if option.ID == voteOptionID {
+			voteBits = option.Bits
+			validOption = true
+			break
+		}
+	}
+	if !validOption {
+		return nil, errors.E(ErrInvalid, "invalid vote option")
+	}
+
+	host := mw.PoliteiaHost()
+	eligibleTickets, err := mw.politeia.eligibleTickets(host, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketHashes := make([]*chainhash.Hash, 0, len(eligibleTickets))
+	for _, ticket := range eligibleTickets {
+		hash, err := chainhash.NewHashFromStr(ticket)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip tickets that previously voted successfully from this device
+		var previousVote ProposalVote
+		err = mw.db.Select(q.Eq("Token", token), q.Eq("Ticket", ticket), q.Eq("State", ProposalVoteStateSuccess)).First(&previousVote)
+		if err == nil {
+			continue
+		} else if err != storm.ErrNotFound {
+			return nil, err
+		}
+
+		ticketHashes = append(ticketHashes, hash)
+	}
+
+	ctx := wallet.shutdownContext()
+	tickets, addresses, err := wallet.internal.CommittedTickets(ctx, ticketHashes)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if len(tickets) == 0 {
+		return nil, errors.E(ErrNotExist, "no eligible tickets found")
+	}
+
+	lock := make(chan time.Time, 1)
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+		lock <- time.Time{} // send matters, not the value
+	}()
+
+	err = wallet.unlock(ctx, privPass, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	voteBit := strconv.FormatUint(voteBits, 16)
+	castVotes := make([]politeiaCastVote, len(tickets))
+	for i, ticket := range tickets {
+		msg := token + ticket.String() + voteBit
+		signature, err := wallet.internal.SignMessage(ctx, msg, addresses[i])
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		castVotes[i] = politeiaCastVote{
+			Token:     token,
+			Ticket:    ticket.String(),
+			VoteBit:   voteBit,
+			Signature: hex.EncodeToString(signature),
+		}
+	}
+
+	receipts, err := mw.politeia.castVotes(host, castVotes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(receipts) != len(castVotes) {
+		return nil, errors.Errorf("politeia returned %d receipts for %d votes", len(receipts), len(castVotes))
+	}
+
+	votes := make([]*ProposalVote, len(castVotes))
+	for i, castVote := range castVotes {
+		vote := &ProposalVote{
+			WalletID:  walletID,
+			Token:     token,
+			Ticket:    castVote.Ticket,
+			OptionID:  voteOptionID,
+			Timestamp: time.Now().Unix(),
+			State:     ProposalVoteStateSuccess,
+		}
+		if receipts[i].Error != "" {
+			vote.State = ProposalVoteStateFailed
+			vote.Error = receipts[i].Error
+		}
+
+		err = mw.db.Save(vote)
+		if err != nil {
+			log.Errorf("[%d] error saving proposal vote for ticket %s: %v", walletID, vote.Ticket, err)
+		}
+
+		votes[i] = vote
+	}
+
+	return votes, nil
+}
+
+// ProposalVotes returns the json-encoded votes previously cast
+// by the specified wallet on the proposal with the specified token.
+func (mw *MultiWallet) ProposalVotes(walletID int, token string) (string, error) {
+	votes, err := mw.ProposalVotesRaw(walletID, token)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(votes)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (mw *MultiWallet) ProposalVotesRaw(walletID int, token string) ([]ProposalVote, error) {
+	votes := make([]ProposalVote, 0)
+	err := mw.db.Select(q.Eq("WalletID", walletID), q.Eq("Token", token)).OrderBy("Timestamp").Find(&votes)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return votes, nil
+}
+
+func proposalCategory(proposal *Proposal) int32 {
+	switch {
+	case proposal.Status == proposalStatusAbandoned:
+		return ProposalCategoryAbandoned
+	case proposal.VoteStatus == ProposalVoteStatusStarted:
+		return ProposalCategoryActive
+	case proposal.VoteStatus == ProposalVoteStatusFinished && proposal.Approved:
+		return ProposalCategoryApproved
+	case proposal.VoteStatus == ProposalVoteStatusFinished:
+		return ProposalCategoryRejected
+	default:
+		return ProposalCategoryPre
+	}
+}
+
+/** begin politeia api helpers */
+
+type politeiaProposalRecord struct {
+	Name             string `json:"name"`
+	State            int32  `json:"state"`
+	Status           int32  `json:"status"`
+	Timestamp        int64  `json:"timestamp"`
+	UserID           string `json:"userid"`
+	Username         string `json:"username"`
+	NumComments      int32  `json:"numcomments"`
+	Version          string `json:"version"`
+	PublishedAt      int64  `json:"publishedat"`
+	CensorshipRecord struct {
+		Token string `json:"token"`
+	} `json:"censorshiprecord"`
+}
+
+func (record *politeiaProposalRecord) proposal() *Proposal {
+	return &Proposal{
+		Token:       record.CensorshipRecord.Token,
+		Name:        record.Name,
+		State:       record.State,
+		Status:      record.Status,
+		Timestamp:   record.Timestamp,
+		UserID:      record.UserID,
+		Username:    record.Username,
+		NumComments: record.NumComments,
+		Version:     record.Version,
+		PublishedAt: record.PublishedAt,
+	}
+}
+
+type politeiaVoteSummary struct {
+	Status           int32  `json:"status"`
+	Approved         bool   `json:"approved"`
+	EligibleTickets  int32  `json:"eligibletickets"`
+	Duration         int32  `json:"duration"`
+	EndHeight        uint64 `json:"endheight"`
+	QuorumPercentage int32  `json:"quorumpercentage"`
+	PassPercentage   int32  `json:"passpercentage"`
+	Results          []struct {
+		Option struct {
+			ID          string `json:"id"`
+			Description string `json:"description"`
+			Bits        uint64 `json:"bits"`
+		} `json:"option"`
+		VotesReceived int64 `json:"votesreceived"`
+	} `json:"results"`
+}
+
+func (summary *politeiaVoteSummary) updateProposal(proposal *Proposal) {
+	proposal.VoteStatus = summary.Status
+	proposal.Approved = summary.Approved
+	proposal.EligibleTickets = summary.EligibleTickets
+	proposal.EndHeight = int32(summary.EndHeight)
+	proposal.QuorumPercentage = summary.QuorumPercentage
+	proposal.PassPercentage = summary.PassPercentage
+
+	proposal.TotalVotes = 0
+	proposal.VoteOptions = make([]*ProposalVoteOption, len(summary.Results))
+	for i, result := range summary.Results {
+		proposal.TotalVotes += result.VotesReceived
+		proposal.VoteOptions[i] = &ProposalVoteOption{
+			ID:            result.Option.ID,
+			Description:   result.Option.Description,
+			Bits:          result.Option.Bits,
+			VotesReceived: result.VotesReceived,
+		}
+	}
+}
+
+type politeiaCastVote struct {
+	Token     string `json:"token"`
+	Ticket    string `json:"ticket"`
+	VoteBit   string `json:"votebit"`
+	Signature string `json:"signature"`
+}
+
+type politeiaCastVoteReceipt struct {
+	ClientSignature string `json:"clientsignature"`
+	Signature       string `json:"signature"`
+	Error           string `json:"error"`
+}
+
+func (p *politeia) batchVoteSummary(host string, tokens []string) (map[string]*politeiaVoteSummary, error) {
+	var response struct {
+		Summaries map[string]*politeiaVoteSummary `json:"summaries"`
+	}
+	request := map[string][]string{"tokens": tokens}
+	err := p.post(host, "/proposals/batchvotesummary", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Summaries, nil
+}
+
+func (p *politeia) eligibleTickets(host, token string) ([]string, error) {
+	var response struct {
+		StartVoteReply struct {
+			EligibleTickets []string `json:"eligibletickets"`
+		} `json:"startvotereply"`
+	}
+	err := p.get(host, "/proposals/"+token+"/votes", &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.StartVoteReply.EligibleTickets, nil
+}
+
+func (p *politeia) castVotes(host string, votes []politeiaCastVote) ([]politeiaCastVoteReceipt, error) {
+	var response struct {
+		Receipts []politeiaCastVoteReceipt `json:"receipts"`
+	}
+	request := map[string][]politeiaCastVote{"votes": votes}
+	err := p.post(host, "/proposals/castvotes", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Receipts, nil
+}
+
+func (p *politeia) get(host, path string, responseOut interface{}) error {
+	req, err := http.NewRequest("GET", host+politeiaApiPath+path, nil)
+	if err != nil {
+		return err
+	}
+	return p.do(req, responseOut)
+}
+
+func (p *politeia) post(host, path string, body, responseOut interface{}) error {
+	csrfToken, err := p.getCSRFToken(host)
+	if err != nil {
+		return err
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", host+politeiaApiPath+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(politeiaCsrfTokenHeader, csrfToken)
+	return p.do(req, responseOut)
+}
+
+// getCSRFToken returns the csrf token previously issued by the server
+// or requests a new token if none was issued.
+func (p *politeia) getCSRFToken(host string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.csrfToken != "" {
+		return p.csrfToken, nil
+	}
+
+	resp, err := p.client.Get(host + politeiaApiPath + "/")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	p.csrfToken = resp.Header.Get(politeiaCsrfTokenHeader)
+	return p.csrfToken, nil
+}
+
+func (p *politeia) do(req *http.Request, responseOut interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusForbidden {
+			// the csrf token may have expired, request a new one on next try
+			p.mu.Lock()
+			p.csrfToken = ""
+			p.mu.Unlock()
+		}
+		return fmt.Errorf("politeia request failed: %s %s returned %s", req.Method, req.URL.Path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(responseOut)
+}
+
+/** end politeia api helpers */
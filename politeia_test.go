@@ -0,0 +1,105 @@
+package dcrlibwallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const testCsrfToken = "test-csrf-token"
+
+// newPoliteiaStandIn returns a local http server that serves
+// a minimal subset of the politeiawww v1 api for tests.
+func newPoliteiaStandIn() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(politeiaCsrfTokenHeader, testCsrfToken)
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("/api/v1/proposals/vetted", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"proposals":[
+			{"name":"Active proposal","status":4,"publishedat":100,"censorshiprecord":{"token":"aaaa"}},
+			{"name":"Abandoned proposal","status":6,"publishedat":200,"censorshiprecord":{"token":"bbbb"}}
+		]}`))
+	})
+	mux.HandleFunc("/api/v1/proposals/batchvotesummary", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get(politeiaCsrfTokenHeader) != testCsrfToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"summaries":{"aaaa":{"status":3,"endheight":500,"passpercentage":60,"results":[
+			{"option":{"id":"no","bits":1},"votesreceived":10},
+			{"option":{"id":"yes","bits":2},"votesreceived":30}
+		]}}}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+var _ = Describe("Politeia", func() {
+	var (
+		mw     *MultiWallet
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+
+		server = newPoliteiaStandIn()
+		Expect(mw.SetPoliteiaHost(server.URL)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		removeTestMultiWallet(mw)
+	})
+
+	It("caches proposals and vote summaries from the politeia server", func() {
+		Expect(mw.SyncPoliteiaProposals()).To(Succeed())
+
+		proposals, err := mw.GetProposalsRaw(ProposalCategoryAll, 0, 0, true)
+		Expect(err).To(BeNil())
+		Expect(proposals).To(HaveLen(2))
+		Expect(proposals[0].Token).To(Equal("bbbb"))
+
+		active, err := mw.GetProposalsRaw(ProposalCategoryActive, 0, 0, false)
+		Expect(err).To(BeNil())
+		Expect(active).To(HaveLen(1))
+		Expect(active[0].TotalVotes).To(BeEquivalentTo(40))
+		Expect(active[0].VoteOptions).To(HaveLen(2))
+		Expect(active[0].VoteOptions[1].ID).To(Equal("yes"))
+
+		abandoned, err := mw.GetProposalsRaw(ProposalCategoryAbandoned, 0, 0, false)
+		Expect(err).To(BeNil())
+		Expect(abandoned).To(HaveLen(1))
+
+		By("Updating cached proposals on a subsequent sync")
+		Expect(mw.SyncPoliteiaProposals()).To(Succeed())
+		count, err := mw.db.Count(&Proposal{})
+		Expect(err).To(BeNil())
+		Expect(count).To(Equal(2))
+
+		proposalJSON, err := mw.GetProposal("aaaa")
+		Expect(err).To(BeNil())
+		var proposal Proposal
+		Expect(json.Unmarshal([]byte(proposalJSON), &proposal)).To(Succeed())
+		Expect(proposal.VoteStatus).To(Equal(ProposalVoteStatusStarted))
+
+		By("Clearing vote results that are no longer reported")
+		Expect(mw.saveOrUpdateProposal(&Proposal{Token: "aaaa", Name: "Active proposal"})).To(Succeed())
+		cached, err := mw.GetProposalRaw("aaaa")
+		Expect(err).To(BeNil())
+		Expect(cached.ID).To(Equal(proposal.ID))
+		Expect(cached.TotalVotes).To(BeZero())
+		Expect(cached.VoteOptions).To(BeEmpty())
+	})
+
+	It("returns an error for proposals that are not cached", func() {
+		Expect(mw.SyncPoliteiaProposals()).To(Succeed())
+
+		_, err := mw.GetProposalRaw("cccc")
+		Expect(err).To(MatchError(ErrNotExist))
+	})
+})
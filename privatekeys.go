@@ -0,0 +1,343 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/decred/dcrd/blockchain/stake/v2"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2/chainec"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/gcs/blockcf"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrwallet/errors/v2"
+	w "github.com/decred/dcrwallet/wallet/v3"
+	"github.com/decred/dcrwallet/wallet/v3/txauthor"
+	"github.com/decred/dcrwallet/wallet/v3/txrules"
+	"github.com/decred/dcrwallet/wallet/v3/txsizes"
+	"github.com/planetdecred/dcrlibwallet/txhelper"
+)
+
+// ImportPrivateKey imports the WIF encoded private key `wif` into the imported
+// account of the wallet and returns the address of the key. Payments to the
+// address are watched from then on. If `rescanFromHeight` is not negative, the
+// blocks from that height are rescanned for earlier transactions of the key,
+// which blocks until the rescan is complete.
+func (wallet *Wallet) ImportPrivateKey(wif string, privPass []byte, rescanFromHeight int32) (string, error) {
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+	}()
+
+	if wallet.IsWatchingOnlyWallet() {
+		return "", errors.New(ErrWalletIsWatchOnly)
+	}
+
+	decodedWIF, err := dcrutil.DecodeWIF(wif, wallet.chainParams.PrivateKeyID)
+	if err != nil {
+		return "", errors.New(ErrInvalid)
+	}
+
+	lock := make(chan time.Time, 1)
+	defer func() {
+		lock <- time.Time{}
+	}()
+
+	ctx := wallet.shutdownContext()
+	err = wallet.unlock(ctx, privPass, lock)
+	if err != nil {
+		return "", err
+	}
+
+	address, err := wallet.internal.ImportPrivateKey(ctx, decodedWIF)
+	if err != nil {
+		if errors.Is(err, errors.Exist) {
+			return "", errors.New(ErrExist)
+		}
+		log.Errorf("[%d] Error importing private key: %v", wallet.ID, err)
+		return "", translateError(err)
+	}
+
+	if rescanFromHeight < 0 {
+		return address, nil
+	}
+
+	n, err := wallet.internal.NetworkBackend()
+	if err != nil {
+		return address, errors.New(ErrNotConnected)
+	}
+
+	err = wallet.internal.RescanFromHeight(ctx, n, rescanFromHeight)
+	if err != nil {
+		log.Errorf("[%d] Error rescanning for imported key: %v", wallet.ID, err)
+		return address, translateError(err)
+	}
+
+	return address, wallet.reindexTransactions()
+}
+
+// SweepTx spends all the outputs paying to the address of a private key, e.g.
+// from a paper wallet, to an address of a wallet. The key is only used to sign
+// the tx and is not imported into the wallet.
+type SweepTx struct {
+	destinationWallet  *Wallet
+	destinationAccount int32
+
+	wif      *dcrutil.WIF
+	address  dcrutil.Address
+	pkScript []byte
+
+	// outputs are the unspent outputs paying to address found by Scan.
+	outputs []*sweepOutput
+}
+
+type sweepOutput struct {
+	outPoint wire.OutPoint
+	amount   int64
+}
+
+// NewSweepTx returns a SweepTx sweeping the outputs of the WIF encoded private
+// key `wif` to `destinationAccount` of `destinationWallet`.
+func (mw *MultiWallet) NewSweepTx(destinationWallet *Wallet, destinationAccount int32, wif string) (*SweepTx, error) {
+	decodedWIF, err := dcrutil.DecodeWIF(wif, mw.chainParams.PrivateKeyID)
+	if err != nil {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	address, err := dcrutil.NewAddressPubKeyHash(dcrutil.Hash160(decodedWIF.SerializePubKey()),
+		mw.chainParams, decodedWIF.DSA())
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SweepTx{
+		destinationWallet:  destinationWallet,
+		destinationAccount: destinationAccount,
+		wif:                decodedWIF,
+		address:            address,
+		pkScript:           pkScript,
+	}, nil
+}
+
+// Address returns the address of the swept private key.
+func (tx *SweepTx) Address() string {
+	return tx.address.Address()
+}
+
+// Scan looks for the unspent outputs paying to the address of the private key
+// in the main chain blocks from `fromHeight` to the tip of the destination
+// wallet. The compact filters of the blocks synced by the wallet are matched
+// against the address and the outputs it spends, and only the blocks matching
+// them are fetched from the network.
+func (tx *SweepTx) Scan(fromHeight int32) error {
+	wallet := tx.destinationWallet
+	n, err := wallet.internal.NetworkBackend()
+	if err != nil {
+		return errors.New(ErrNotConnected)
+	}
+
+	ctx := wallet.shutdownContext()
+	_, tipHeight := wallet.internal.MainChainTip(ctx)
+	if fromHeight < 0 || fromHeight > tipHeight {
+		return errors.New(ErrInvalid)
+	}
+
+	var outputs []*sweepOutput
+	for height := fromHeight; height <= tipHeight; height++ {
+		blockInfo, err := wallet.internal.BlockInfo(ctx, w.NewBlockIdentifierFromHeight(height))
+		if err != nil {
+			return translateError(err)
+		}
+		blockHash := blockInfo.Hash
+
+		filter, err := wallet.internal.CFilter(ctx, &blockHash)
+		if err != nil {
+			return translateError(err)
+		}
+
+		// outputs paying to the address or spending found outputs match
+		entries := blockcf.Entries{tx.pkScript}
+		for _, output := range outputs {
+			entries.AddOutPoint(&output.outPoint)
+		}
+		if !filter.MatchAny(blockcf.Key(&blockHash), entries) {
+			continue
+		}
+
+		blocks, err := n.Blocks(ctx, []*chainhash.Hash{&blockHash})
+		if err != nil {
+			return translateError(err)
+		}
+		outputs = tx.scanBlock(blocks[0], outputs)
+	}
+
+	tx.outputs = outputs
+	return nil
+}
+
+// scanBlock returns `outputs` without the outputs spent in `block` and with
+// the outputs of `block` paying to the address of the key.
+func (tx *SweepTx) scanBlock(block *wire.MsgBlock, outputs []*sweepOutput) []*sweepOutput {
+	spent := make(map[wire.OutPoint]bool)
+	for _, blockTx := range block.Transactions {
+		for _, txIn := range blockTx.TxIn {
+			spent[txIn.PreviousOutPoint] = true
+		}
+	}
+	for _, blockTx := range block.STransactions {
+		if stake.IsSStx(blockTx) {
+			for _, txIn := range blockTx.TxIn {
+				spent[txIn.PreviousOutPoint] = true
+			}
+		}
+	}
+
+	unspent := outputs[:0]
+	for _, output := range outputs {
+		if !spent[output.outPoint] {
+			unspent = append(unspent, output)
+		}
+	}
+
+	for _, blockTx := range block.Transactions {
+		txHash := blockTx.TxHash()
+		for i, txOut := range blockTx.TxOut {
+			if txOut.Version != wire.DefaultPkScriptVersion || !bytes.Equal(txOut.PkScript, tx.pkScript) {
+				continue
+			}
+			outPoint := wire.NewOutPoint(&txHash, uint32(i), wire.TxTreeRegular)
+			if spent[*outPoint] {
+				continue
+			}
+			unspent = append(unspent, &sweepOutput{
+				outPoint: *outPoint,
+				amount:   txOut.Value,
+			})
+		}
+	}
+
+	return unspent
+}
+
+// TotalAmount returns the sum of the outputs found by Scan.
+func (tx *SweepTx) TotalAmount() *Amount {
+	var totalAmountAtom int64
+	for _, output := range tx.outputs {
+		totalAmountAtom += output.amount
+	}
+
+	return &Amount{
+		AtomValue: totalAmountAtom,
+		DcrValue:  dcrutil.Amount(totalAmountAtom).ToCoin(),
+	}
+}
+
+// EstimateFeeAndSize returns the fee and size of the tx spending the outputs
+// found by Scan.
+func (tx *SweepTx) EstimateFeeAndSize() (*TxFeeAndSize, error) {
+	unsignedTx, err := tx.constructTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	feeToSendTx := txrules.FeeForSerializeSize(txrules.DefaultRelayFeePerKb, unsignedTx.EstimatedSignedSerializeSize)
+	return &TxFeeAndSize{
+		EstimatedSignedSize: unsignedTx.EstimatedSignedSerializeSize,
+		Fee: &Amount{
+			AtomValue: int64(feeToSendTx),
+			DcrValue:  feeToSendTx.ToCoin(),
+		},
+	}, nil
+}
+
+// Broadcast signs the tx spending the outputs found by Scan with the private
+// key and publishes it. The hash of the tx is returned.
+func (tx *SweepTx) Broadcast() ([]byte, error) {
+	wallet := tx.destinationWallet
+	n, err := wallet.internal.NetworkBackend()
+	if err != nil {
+		return nil, errors.New(ErrNotConnected)
+	}
+
+	unsignedTx, err := tx.constructTransaction()
+	if err != nil {
+		return nil, err
+	}
+	msgTx := unsignedTx.Tx
+
+	getKey := txscript.KeyClosure(func(address dcrutil.Address) (chainec.PrivateKey, bool, error) {
+		if address.Address() != tx.address.Address() {
+			return nil, false, errors.New(ErrNotExist)
+		}
+		return tx.wif.PrivKey, true, nil
+	})
+	for i := range msgTx.TxIn {
+		msgTx.TxIn[i].SignatureScript, err = txscript.SignTxOutput(wallet.chainParams, msgTx, i,
+			tx.pkScript, txscript.SigHashAll, getKey, nil, nil, tx.wif.DSA())
+		if err != nil {
+			log.Errorf("[%d] Error signing sweep input %d: %v", wallet.ID, i, err)
+			return nil, err
+		}
+	}
+
+	var serializedTx bytes.Buffer
+	serializedTx.Grow(msgTx.SerializeSize())
+	err = msgTx.Serialize(&serializedTx)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	ctx := wallet.shutdownContext()
+	txHash, err := wallet.internal.PublishTransaction(ctx, msgTx, serializedTx.Bytes(), n)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return txHash[:], nil
+}
+
+// constructTransaction returns a tx spending all the outputs found by Scan to
+// the current address of the destination account, less the fee.
+func (tx *SweepTx) constructTransaction() (*txauthor.AuthoredTx, error) {
+	if len(tx.outputs) == 0 {
+		return nil, errors.New(ErrInsufficientBalance)
+	}
+
+	destinationAddress, err := tx.destinationWallet.CurrentAddress(tx.destinationAccount)
+	if err != nil {
+		return nil, err
+	}
+	changeSource, err := txhelper.MakeTxChangeSource(destinationAddress, tx.destinationWallet.chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	inputSource := func(dcrutil.Amount) (*txauthor.InputDetail, error) {
+		detail := &txauthor.InputDetail{}
+		for _, output := range tx.outputs {
+			detail.Amount += dcrutil.Amount(output.amount)
+			detail.Inputs = append(detail.Inputs, wire.NewTxIn(&output.outPoint, output.amount, nil))
+			detail.Scripts = append(detail.Scripts, tx.pkScript)
+			detail.RedeemScriptSizes = append(detail.RedeemScriptSizes, txsizes.RedeemP2PKHSigScriptSize)
+		}
+		return detail, nil
+	}
+
+	// all the swept amount less the fee goes to the change output, there is
+	// no change output if the amount does not cover the fee
+	unsignedTx, err := txauthor.NewUnsignedTransaction(nil, txrules.DefaultRelayFeePerKb, inputSource, changeSource)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if unsignedTx.ChangeIndex < 0 {
+		return nil, errors.New(ErrInsufficientBalance)
+	}
+
+	return unsignedTx, nil
+}
@@ -9,13 +9,30 @@ import (
 	w "github.com/decred/dcrwallet/wallet/v3"
 )
 
+// RescanBlocks rescans the blocks from the birthday block of the wallet, or
+// from the genesis block if the wallet birthday is unknown.
 func (mw *MultiWallet) RescanBlocks(walletID int) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.E(ErrNotExist)
+	}
+
+	return mw.RescanBlocksFromHeight(walletID, wallet.BirthdayHeight)
+}
+
+// RescanBlocksFromHeight rescans the blocks from `startHeight` for the
+// transactions of the wallet.
+func (mw *MultiWallet) RescanBlocksFromHeight(walletID int, startHeight int32) error {
 
 	wallet := mw.WalletWithID(walletID)
 	if wallet == nil {
 		return errors.E(ErrNotExist)
 	}
 
+	if startHeight < 0 || startHeight > wallet.GetBestBlock() {
+		return errors.E(ErrInvalid)
+	}
+
 	netBackend, err := wallet.internal.NetworkBackend()
 	if err != nil {
 		return errors.E(ErrNotConnected)
@@ -45,7 +62,7 @@ func (mw *MultiWallet) RescanBlocks(walletID int) error {
 		}
 
 		progress := make(chan w.RescanProgress, 1)
-		go wallet.internal.RescanProgressFromHeight(ctx, netBackend, 0, progress)
+		go wallet.internal.RescanProgressFromHeight(ctx, netBackend, startHeight, progress)
 
 		rescanStartTime := time.Now().Unix()
 
@@ -65,7 +82,7 @@ func (mw *MultiWallet) RescanBlocks(walletID int) error {
 			}
 
 			elapsedRescanTime := time.Now().Unix() - rescanStartTime
-			rescanRate := float64(p.ScannedThrough) / float64(rescanProgressReport.TotalHeadersToScan)
+			rescanRate := float64(p.ScannedThrough-startHeight) / float64(rescanProgressReport.TotalHeadersToScan-startHeight)
 
 			rescanProgressReport.RescanProgress = int32(math.Round(rescanRate * 100))
 			estimatedTotalRescanTime := int64(math.Round(float64(elapsedRescanTime) / rescanRate))
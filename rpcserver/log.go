@@ -0,0 +1,18 @@
+package rpcserver
+
+import (
+	"github.com/decred/slog"
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+var log = slog.Disabled
+
+// Use the dcrlibwallet RPCS subsystem logger by default.
+func init() {
+	UseLogger(dcrlibwallet.SubsystemLogger("RPCS"))
+}
+
+// UseLogger uses a specified Logger to output package logging info.
+func UseLogger(logger slog.Logger) {
+	log = logger
+}
@@ -0,0 +1,537 @@
+package rpcserver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+// handlers maps each JSON-RPC method to its handler. The authenticate and
+// subscribe methods are handled by the connection.
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		// wallets
+		"listWallets":   listWallets,
+		"openWallets":   openWallets,
+		"createWallet":  createWallet,
+		"restoreWallet": restoreWallet,
+		"renameWallet":  renameWallet,
+		"deleteWallet":  deleteWallet,
+		"unlockWallet":  unlockWallet,
+		"lockWallet":    lockWallet,
+
+		// accounts and addresses
+		"getAccounts":       getAccounts,
+		"getAccountBalance": getAccountBalance,
+		"nextAccount":       nextAccount,
+		"renameAccount":     renameAccount,
+		"currentAddress":    currentAddress,
+		"nextAddress":       nextAddress,
+		"validateAddress":   validateAddress,
+
+		// transactions
+		"getTransactions": getTransactions,
+		"getTransaction":  getTransaction,
+		"estimateFee":     estimateFee,
+		"sendTransaction": sendTransaction,
+
+		// sync
+		"startSync":  startSync,
+		"cancelSync": cancelSync,
+		"syncStatus": syncStatus,
+
+		// tickets
+		"ticketPrice":     ticketPrice,
+		"stakeInfo":       stakeInfo,
+		"getTickets":      getTickets,
+		"purchaseTickets": purchaseTickets,
+	}
+}
+
+// WalletInfo describes a wallet without exposing its encrypted seed.
+type WalletInfo struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	CreatedAt       int64  `json:"created_at"`
+	IsRestored      bool   `json:"is_restored"`
+	IsWatchingOnly  bool   `json:"is_watching_only"`
+	IsLocked        bool   `json:"is_locked"`
+	IsSynced        bool   `json:"is_synced"`
+	NeedsSeedBackup bool   `json:"needs_seed_backup"`
+	BestBlock       int32  `json:"best_block"`
+}
+
+func walletInfo(wallet *dcrlibwallet.Wallet) *WalletInfo {
+	info := &WalletInfo{
+		ID:              wallet.ID,
+		Name:            wallet.Name,
+		CreatedAt:       wallet.CreatedAt.Unix(),
+		IsRestored:      wallet.IsRestored,
+		NeedsSeedBackup: len(wallet.EncryptedSeed) > 0,
+	}
+
+	if wallet.WalletOpened() {
+		info.IsWatchingOnly = wallet.IsWatchingOnlyWallet()
+		info.IsLocked = wallet.IsLocked()
+		info.IsSynced = wallet.IsSynced()
+		info.BestBlock = wallet.GetBestBlock()
+	}
+	return info
+}
+
+type walletParams struct {
+	WalletID int `json:"wallet_id"`
+}
+
+func (s *Server) walletWithID(walletID int) (*dcrlibwallet.Wallet, error) {
+	wallet := s.mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, errors.New(dcrlibwallet.ErrNotExist)
+	}
+	return wallet, nil
+}
+
+func listWallets(s *Server, _ json.RawMessage) (interface{}, error) {
+	wallets := make([]*WalletInfo, 0)
+	for _, wallet := range s.mw.AllWallets() {
+		wallets = append(wallets, walletInfo(wallet))
+	}
+	return wallets, nil
+}
+
+func openWallets(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		StartupPassphrase string `json:"startup_passphrase"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return true, s.mw.OpenWallets([]byte(params.StartupPassphrase))
+}
+
+type newWalletParams struct {
+	Name           string `json:"name"`
+	Passphrase     string `json:"passphrase"`
+	PassphraseType int32  `json:"passphrase_type"`
+	Seed           string `json:"seed"`
+	SeedPassphrase string `json:"seed_passphrase"`
+	Birthday       int64  `json:"birthday"`
+	BirthdayHeight int32  `json:"birthday_height"`
+}
+
+func createWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params newWalletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.mw.CreateNewWallet(params.Name, params.Passphrase, params.PassphraseType)
+	if err != nil {
+		return nil, err
+	}
+	return walletInfo(wallet), nil
+}
+
+func restoreWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params newWalletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.mw.RestoreWalletWithSeedPassphrase(params.Name, params.Seed, params.SeedPassphrase,
+		params.Passphrase, params.PassphraseType, params.Birthday, params.BirthdayHeight)
+	if err != nil {
+		return nil, err
+	}
+	return walletInfo(wallet), nil
+}
+
+func renameWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID int    `json:"wallet_id"`
+		Name     string `json:"name"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return true, s.mw.RenameWallet(params.WalletID, params.Name)
+}
+
+type passphraseParams struct {
+	WalletID   int    `json:"wallet_id"`
+	Passphrase string `json:"passphrase"`
+}
+
+func deleteWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params passphraseParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return true, s.mw.DeleteWallet(params.WalletID, []byte(params.Passphrase))
+}
+
+func unlockWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params passphraseParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return true, s.mw.UnlockWallet(params.WalletID, []byte(params.Passphrase))
+}
+
+func lockWallet(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params walletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.LockWallet()
+	return true, nil
+}
+
+type accountParams struct {
+	WalletID int   `json:"wallet_id"`
+	Account  int32 `json:"account"`
+}
+
+func getAccounts(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params walletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.GetAccountsRaw()
+}
+
+func getAccountBalance(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params accountParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.GetAccountBalance(params.Account)
+}
+
+func nextAccount(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID   int    `json:"wallet_id"`
+		Name       string `json:"name"`
+		Passphrase string `json:"passphrase"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.NextAccount(params.Name, []byte(params.Passphrase))
+}
+
+func renameAccount(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID int    `json:"wallet_id"`
+		Account  int32  `json:"account"`
+		Name     string `json:"name"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return true, wallet.RenameAccount(params.Account, params.Name)
+}
+
+func currentAddress(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params accountParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.CurrentAddress(params.Account)
+}
+
+func nextAddress(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params accountParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.NextAddress(params.Account)
+}
+
+func validateAddress(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		Address string `json:"address"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return s.mw.IsAddressValid(params.Address), nil
+}
+
+func getTransactions(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID    int   `json:"wallet_id"`
+		Offset      int32 `json:"offset"`
+		Limit       int32 `json:"limit"`
+		Filter      int32 `json:"filter"`
+		NewestFirst bool  `json:"newest_first"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.GetTransactionsRaw(params.Offset, params.Limit, params.Filter, params.NewestFirst)
+}
+
+func getTransaction(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID int    `json:"wallet_id"`
+		Hash     string `json:"hash"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := chainhash.NewHashFromStr(params.Hash)
+	if err != nil {
+		return nil, newError(ErrCodeInvalidParams, "invalid transaction hash: %v", err)
+	}
+	return wallet.GetTransactionRaw(hash[:])
+}
+
+type sendParams struct {
+	WalletID     int    `json:"wallet_id"`
+	Account      int32  `json:"account"`
+	Passphrase   string `json:"passphrase"`
+	Destinations []struct {
+		Address string `json:"address"`
+		Amount  int64  `json:"amount"`
+		SendMax bool   `json:"send_max"`
+	} `json:"destinations"`
+}
+
+func (s *Server) txAuthor(raw json.RawMessage) (*dcrlibwallet.TxAuthor, *sendParams, error) {
+	var params sendParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, nil, err
+	}
+	if len(params.Destinations) == 0 {
+		return nil, nil, newError(ErrCodeInvalidParams, "no destinations")
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txAuthor := s.mw.NewUnsignedTx(wallet, params.Account)
+	for _, destination := range params.Destinations {
+		if !s.mw.IsAddressValid(destination.Address) {
+			return nil, nil, newError(ErrCodeInvalidParams, "invalid address %s", destination.Address)
+		}
+		txAuthor.AddSendDestination(destination.Address, destination.Amount, destination.SendMax)
+	}
+	return txAuthor, &params, nil
+}
+
+func estimateFee(s *Server, raw json.RawMessage) (interface{}, error) {
+	txAuthor, _, err := s.txAuthor(raw)
+	if err != nil {
+		return nil, err
+	}
+	return txAuthor.EstimateFeeAndSize()
+}
+
+func sendTransaction(s *Server, raw json.RawMessage) (interface{}, error) {
+	txAuthor, params, err := s.txAuthor(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash, err := txAuthor.Broadcast([]byte(params.Passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := chainhash.NewHash(txHash)
+	if err != nil {
+		return hex.EncodeToString(txHash), nil
+	}
+	return hash.String(), nil
+}
+
+func startSync(s *Server, _ json.RawMessage) (interface{}, error) {
+	return true, s.mw.SpvSync()
+}
+
+func cancelSync(s *Server, _ json.RawMessage) (interface{}, error) {
+	s.mw.CancelSync()
+	return true, nil
+}
+
+// SyncStatus is the result of the syncStatus method.
+type SyncStatus struct {
+	Syncing        bool                              `json:"syncing"`
+	Synced         bool                              `json:"synced"`
+	ConnectedPeers int32                             `json:"connected_peers"`
+	SyncStage      int32                             `json:"sync_stage"`
+	Progress       *dcrlibwallet.GeneralSyncProgress `json:"progress,omitempty"`
+	BestBlock      *dcrlibwallet.BlockInfo           `json:"best_block,omitempty"`
+}
+
+func syncStatus(s *Server, _ json.RawMessage) (interface{}, error) {
+	return &SyncStatus{
+		Syncing:        s.mw.IsSyncing(),
+		Synced:         s.mw.IsSynced(),
+		ConnectedPeers: s.mw.ConnectedPeers(),
+		SyncStage:      s.mw.CurrentSyncStage(),
+		Progress:       s.mw.GeneralSyncProgress(),
+		BestBlock:      s.mw.GetBestBlock(),
+	}, nil
+}
+
+func ticketPrice(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params walletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.TicketPrice(s.ctx)
+}
+
+func stakeInfo(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params walletParams
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.StakeInfo()
+}
+
+// Ticket is a ticket returned by the getTickets method.
+type Ticket struct {
+	Hash        string `json:"hash"`
+	Status      string `json:"status"`
+	BlockHeight int32  `json:"block_height"`
+	SpenderHash string `json:"spender_hash,omitempty"`
+}
+
+func getTickets(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID    int   `json:"wallet_id"`
+		StartHeight int32 `json:"start_height"`
+		EndHeight   int32 `json:"end_height"`
+		TargetCount int32 `json:"target_count"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketInfos, err := wallet.GetTicketsForBlockHeightRange(params.StartHeight, params.EndHeight, params.TargetCount)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := make([]*Ticket, len(ticketInfos))
+	for i, ticketInfo := range ticketInfos {
+		tickets[i] = &Ticket{
+			Hash:        ticketInfo.Ticket.Hash.String(),
+			Status:      ticketInfo.Status,
+			BlockHeight: ticketInfo.BlockHeight,
+		}
+		if ticketInfo.Spender != nil {
+			tickets[i].SpenderHash = ticketInfo.Spender.Hash.String()
+		}
+	}
+	return tickets, nil
+}
+
+func purchaseTickets(s *Server, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		WalletID   int    `json:"wallet_id"`
+		Account    uint32 `json:"account"`
+		NumTickets uint32 `json:"num_tickets"`
+		Passphrase string `json:"passphrase"`
+		Expiry     uint32 `json:"expiry"`
+		VSPHost    string `json:"vsp_host"`
+		VSPToken   string `json:"vsp_api_token"`
+	}
+	if err := parseParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletWithID(params.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &dcrlibwallet.PurchaseTicketsRequest{
+		Account:               params.Account,
+		RequiredConfirmations: uint32(wallet.RequiredConfirmations()),
+		NumTickets:            params.NumTickets,
+		Passphrase:            []byte(params.Passphrase),
+		Expiry:                params.Expiry,
+		VSPAPIToken:           params.VSPToken,
+	}
+	return wallet.PurchaseTickets(s.ctx, request, params.VSPHost)
+}
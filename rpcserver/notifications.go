@@ -0,0 +1,70 @@
+package rpcserver
+
+import (
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+// Notification methods sent to subscribed connections.
+const (
+	NotificationSyncStarted   = "syncStarted"
+	NotificationSyncProgress  = "syncProgress"
+	NotificationSyncCompleted = "syncCompleted"
+	NotificationSyncCanceled  = "syncCanceled"
+	NotificationSyncError     = "syncError"
+	NotificationPeers         = "peersChanged"
+	NotificationTxEvent       = "txEvent"
+	NotificationBlockEvent    = "blockEvent"
+)
+
+// SyncProgress is the params of a syncProgress notification.
+type SyncProgress struct {
+	Stage    string      `json:"stage"`
+	Progress interface{} `json:"progress"`
+}
+
+// subscriber forwards MultiWallet notifications to a connection.
+type subscriber struct {
+	c *conn
+}
+
+func (s *subscriber) OnSyncStarted(wasRestarted bool) {
+	s.c.notify(NotificationSyncStarted, map[string]bool{"was_restarted": wasRestarted})
+}
+
+func (s *subscriber) OnPeerConnectedOrDisconnected(numberOfConnectedPeers int32) {
+	s.c.notify(NotificationPeers, map[string]int32{"connected_peers": numberOfConnectedPeers})
+}
+
+func (s *subscriber) OnHeadersFetchProgress(headersFetchProgress *dcrlibwallet.HeadersFetchProgressReport) {
+	s.c.notify(NotificationSyncProgress, &SyncProgress{Stage: "headers_fetch", Progress: headersFetchProgress})
+}
+
+func (s *subscriber) OnAddressDiscoveryProgress(addressDiscoveryProgress *dcrlibwallet.AddressDiscoveryProgressReport) {
+	s.c.notify(NotificationSyncProgress, &SyncProgress{Stage: "address_discovery", Progress: addressDiscoveryProgress})
+}
+
+func (s *subscriber) OnHeadersRescanProgress(headersRescanProgress *dcrlibwallet.HeadersRescanProgressReport) {
+	s.c.notify(NotificationSyncProgress, &SyncProgress{Stage: "headers_rescan", Progress: headersRescanProgress})
+}
+
+func (s *subscriber) OnSyncCompleted() {
+	s.c.notify(NotificationSyncCompleted, nil)
+}
+
+func (s *subscriber) OnSyncCanceled(willRestart bool) {
+	s.c.notify(NotificationSyncCanceled, map[string]bool{"will_restart": willRestart})
+}
+
+func (s *subscriber) OnSyncEndedWithError(err error) {
+	s.c.notify(NotificationSyncError, map[string]string{"error": err.Error()})
+}
+
+func (s *subscriber) Debug(debugInfo *dcrlibwallet.DebugInfo) {}
+
+func (s *subscriber) OnTxEvent(event *dcrlibwallet.TxEvent) {
+	s.c.notify(NotificationTxEvent, event)
+}
+
+func (s *subscriber) OnBlockEvent(event *dcrlibwallet.BlockEvent) {
+	s.c.notify(NotificationBlockEvent, event)
+}
@@ -0,0 +1,486 @@
+// Package rpcserver exposes a MultiWallet over an authenticated JSON-RPC 2.0
+// interface on a local unix or loopback tcp socket, so that several processes
+// such as desktop and command line tools can share one wallet backend.
+//
+// Requests and responses are newline-delimited JSON-RPC 2.0 objects with named
+// params. The first request on a connection must call the "authenticate"
+// method with the server's auth token. Clients may call "subscribe" to receive
+// sync progress and transaction notifications on the same connection as
+// JSON-RPC notifications.
+package rpcserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+const (
+	NetworkUnix = "unix"
+	NetworkTCP  = "tcp"
+
+	// maxRequestSize is the maximum size of a single request line.
+	maxRequestSize = 1 << 20
+
+	// notificationQueueSize is the number of notifications buffered for
+	// a connection before further notifications are dropped.
+	notificationQueueSize = 256
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	ErrCodeParse           = -32700
+	ErrCodeInvalidRequest  = -32600
+	ErrCodeMethodNotFound  = -32601
+	ErrCodeInvalidParams   = -32602
+	ErrCodeWallet          = -32000
+	ErrCodeUnauthenticated = -32001
+	ErrCodeWalletNotExist  = -32002
+	ErrCodeInvalidPassword = -32003
+)
+
+// Config holds the options for a Server.
+type Config struct {
+	// Network is either NetworkUnix or NetworkTCP.
+	Network string
+	// Address is the socket file path for unix sockets
+	// or a loopback host:port for tcp sockets.
+	Address string
+	// AuthToken must be sent by clients in the authenticate request.
+	AuthToken string
+}
+
+// Server serves JSON-RPC requests for a MultiWallet.
+type Server struct {
+	mw       *dcrlibwallet.MultiWallet
+	cfg      Config
+	listener net.Listener
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	conns  map[int]*conn
+	nextID int
+	wg     sync.WaitGroup
+}
+
+// New returns a Server for `mw`. The server does not accept connections
+// until Start is called.
+func New(mw *dcrlibwallet.MultiWallet, cfg *Config) (*Server, error) {
+	if cfg.AuthToken == "" {
+		return nil, fmt.Errorf("rpcserver: an auth token is required")
+	}
+
+	switch cfg.Network {
+	case NetworkUnix:
+	case NetworkTCP:
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("rpcserver: invalid tcp address: %v", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("rpcserver: tcp address %s is not a loopback address", cfg.Address)
+		}
+	default:
+		return nil, fmt.Errorf("rpcserver: unsupported network %q", cfg.Network)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		mw:     mw,
+		cfg:    *cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		conns:  make(map[int]*conn),
+	}, nil
+}
+
+// Start listens on the configured socket and serves connections
+// in the background until Stop is called.
+func (s *Server) Start() error {
+	var listener net.Listener
+	var err error
+	if s.cfg.Network == NetworkUnix {
+		listener, err = listenUnix(s.cfg.Address)
+	} else {
+		listener, err = net.Listen(s.cfg.Network, s.cfg.Address)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	log.Infof("RPC server listening on %s %s", s.cfg.Network, s.Addr())
+
+	s.wg.Add(1)
+	go s.acceptConnections()
+	return nil
+}
+
+// listenUnix listens on a unix socket at `address` that only the current
+// user can connect to. The socket is created in a new directory only the
+// current user can open and then moved to `address`, so no other user can
+// connect before its permissions are restricted. A stale socket at `address`
+// is replaced, any other file is left alone.
+func listenUnix(address string) (net.Listener, error) {
+	if err := checkStaleSocket(address); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(address), ".rpcserver")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tempAddress := filepath.Join(dir, "rpc.sock")
+	listener, err := net.Listen(NetworkUnix, tempAddress)
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed from `address` by Stop
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	err = os.Chmod(tempAddress, 0600)
+	if err == nil {
+		err = os.Rename(tempAddress, address)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// checkStaleSocket returns an error if a file other than a socket exists at
+// `address`.
+func checkStaleSocket(address string) error {
+	info, err := os.Lstat(address)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("rpcserver: %s exists and is not a socket", address)
+	}
+	return nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	if s.cfg.Network == NetworkUnix {
+		// the listener was created at a temporary path, see listenUnix
+		return &net.UnixAddr{Name: s.cfg.Address, Net: NetworkUnix}
+	}
+	return s.listener.Addr()
+}
+
+// Stop closes the listener and all open connections
+// and waits for the connection handlers to return.
+func (s *Server) Stop() {
+	s.cancel()
+	if s.listener != nil {
+		s.listener.Close()
+		if s.cfg.Network == NetworkUnix && checkStaleSocket(s.cfg.Address) == nil {
+			os.Remove(s.cfg.Address)
+		}
+	}
+
+	s.mu.Lock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Info("RPC server stopped")
+}
+
+func (s *Server) acceptConnections() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+			default:
+				log.Errorf("RPC server accept error: %v", err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		s.nextID++
+		c := newConn(s, s.nextID, netConn)
+		s.conns[c.id] = c
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c.id)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) authenticate(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AuthToken)) == 1
+}
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func newError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// walletError converts an error returned by dcrlibwallet to a JSON-RPC error.
+func walletError(err error) *Error {
+	if rpcErr, ok := err.(*Error); ok {
+		return rpcErr
+	}
+
+	code := ErrCodeWallet
+	switch err.Error() {
+	case dcrlibwallet.ErrNotExist:
+		code = ErrCodeWalletNotExist
+	case dcrlibwallet.ErrInvalidPassphrase:
+		code = ErrCodeInvalidPassword
+	}
+	return &Error{Code: code, Message: err.Error()}
+}
+
+// conn is a single client connection.
+type conn struct {
+	server  *Server
+	id      int
+	netConn net.Conn
+
+	writeMu       sync.Mutex
+	encoder       *json.Encoder
+	authenticated bool
+
+	notifications chan *notification
+	subscribed    bool
+	closeOnce     sync.Once
+	closed        chan struct{}
+}
+
+func newConn(server *Server, id int, netConn net.Conn) *conn {
+	return &conn{
+		server:        server,
+		id:            id,
+		netConn:       netConn,
+		encoder:       json.NewEncoder(netConn),
+		notifications: make(chan *notification, notificationQueueSize),
+		closed:        make(chan struct{}),
+	}
+}
+
+func (c *conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.netConn.Close()
+	})
+}
+
+func (c *conn) listenerID() string {
+	return fmt.Sprintf("rpcserver-%d", c.id)
+}
+
+func (c *conn) serve() {
+	defer func() {
+		if c.subscribed {
+			c.server.mw.RemoveSyncProgressListener(c.listenerID())
+			c.server.mw.RemoveTxAndBlockEventListener(c.listenerID())
+		}
+		c.Close()
+	}()
+
+	go c.writeNotifications()
+
+	scanner := bufio.NewScanner(c.netConn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			c.write(&response{JSONRPC: "2.0", Error: newError(ErrCodeParse, "parse error: %v", err)})
+			continue
+		}
+
+		result, rpcErr := c.handleRequest(&req)
+		if req.ID == nil {
+			// no response is sent for notifications from the client
+			continue
+		}
+
+		resp := &response{JSONRPC: "2.0", ID: req.ID}
+		if rpcErr != nil {
+			resp.Error = rpcErr
+		} else {
+			resp.Result = result
+		}
+		c.write(resp)
+	}
+
+	if err := scanner.Err(); err != nil {
+		select {
+		case <-c.closed:
+		default:
+			log.Debugf("RPC connection %d read error: %v", c.id, err)
+		}
+	}
+}
+
+func (c *conn) handleRequest(req *request) (interface{}, *Error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, newError(ErrCodeInvalidRequest, "invalid request")
+	}
+
+	if req.Method == "authenticate" {
+		var params struct {
+			Token string `json:"token"`
+		}
+		if err := parseParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if !c.server.authenticate(params.Token) {
+			log.Warnf("RPC connection %d failed to authenticate", c.id)
+			return nil, newError(ErrCodeUnauthenticated, "invalid auth token")
+		}
+		c.authenticated = true
+		return true, nil
+	}
+
+	if !c.authenticated {
+		return nil, newError(ErrCodeUnauthenticated, "authentication required")
+	}
+
+	if req.Method == "subscribe" {
+		return c.subscribe()
+	}
+
+	handler, ok := handlers[req.Method]
+	if !ok {
+		return nil, newError(ErrCodeMethodNotFound, "method %s not found", req.Method)
+	}
+
+	result, err := handler(c.server, req.Params)
+	if err != nil {
+		return nil, walletError(err)
+	}
+	return result, nil
+}
+
+// subscribe registers the connection for sync progress
+// and transaction and block notifications.
+func (c *conn) subscribe() (interface{}, *Error) {
+	if c.subscribed {
+		return true, nil
+	}
+
+	listener := &subscriber{c}
+	err := c.server.mw.AddTxAndBlockEventListener(listener, c.listenerID())
+	if err != nil {
+		return nil, walletError(err)
+	}
+
+	err = c.server.mw.AddSyncProgressListener(listener, c.listenerID())
+	if err != nil {
+		c.server.mw.RemoveTxAndBlockEventListener(c.listenerID())
+		return nil, walletError(err)
+	}
+
+	c.subscribed = true
+	return true, nil
+}
+
+func (c *conn) write(v interface{}) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.encoder.Encode(v); err != nil {
+		log.Debugf("RPC connection %d write error: %v", c.id, err)
+		c.Close()
+	}
+}
+
+func (c *conn) notify(method string, params interface{}) {
+	select {
+	case c.notifications <- &notification{JSONRPC: "2.0", Method: method, Params: params}:
+	case <-c.closed:
+	default:
+		log.Warnf("RPC connection %d is not reading notifications, dropping %s", c.id, method)
+	}
+}
+
+func (c *conn) writeNotifications() {
+	for {
+		select {
+		case n := <-c.notifications:
+			c.write(n)
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func parseParams(raw json.RawMessage, params interface{}) *Error {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return newError(ErrCodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
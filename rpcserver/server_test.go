@@ -0,0 +1,149 @@
+package rpcserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/planetdecred/dcrlibwallet"
+)
+
+type testResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+var _ = Describe("Server", func() {
+	var (
+		mw      *dcrlibwallet.MultiWallet
+		server  *Server
+		rootDir string
+		client  net.Conn
+		reader  *bufio.Reader
+		nextID  int
+	)
+
+	call := func(method string, params interface{}) *testResponse {
+		nextID++
+		err := json.NewEncoder(client).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      nextID,
+			"method":  method,
+			"params":  params,
+		})
+		Expect(err).To(BeNil())
+
+		line, err := reader.ReadBytes('\n')
+		Expect(err).To(BeNil())
+
+		var resp testResponse
+		Expect(json.Unmarshal(line, &resp)).To(Succeed())
+		Expect(resp.ID).To(Equal(nextID))
+		return &resp
+	}
+
+	BeforeEach(func() {
+		var err error
+		rootDir, err = ioutil.TempDir("", "dcrlibwallet-rpcserver")
+		Expect(err).To(BeNil())
+
+		mw, err = dcrlibwallet.NewMultiWallet(rootDir, "", "testnet3")
+		Expect(err).To(BeNil())
+
+		server, err = New(mw, &Config{
+			Network:   NetworkUnix,
+			Address:   filepath.Join(rootDir, "rpc.sock"),
+			AuthToken: "token",
+		})
+		Expect(err).To(BeNil())
+		Expect(server.Start()).To(Succeed())
+
+		client, err = net.Dial(NetworkUnix, filepath.Join(rootDir, "rpc.sock"))
+		Expect(err).To(BeNil())
+		reader = bufio.NewReader(client)
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Stop()
+		mw.Shutdown()
+		os.RemoveAll(rootDir)
+	})
+
+	It("only accepts loopback tcp addresses", func() {
+		_, err := New(mw, &Config{Network: NetworkTCP, Address: "0.0.0.0:9110", AuthToken: "token"})
+		Expect(err).NotTo(BeNil())
+
+		_, err = New(mw, &Config{Network: NetworkTCP, Address: "127.0.0.1:9110", AuthToken: "token"})
+		Expect(err).To(BeNil())
+	})
+
+	It("only lets the current user connect to the unix socket", func() {
+		address := filepath.Join(rootDir, "rpc.sock")
+		info, err := os.Lstat(address)
+		Expect(err).To(BeNil())
+		Expect(info.Mode() & os.ModeSocket).NotTo(BeZero())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		Expect(server.Addr().String()).To(Equal(address))
+
+		// files other than sockets are not replaced
+		otherAddress := filepath.Join(rootDir, "not-a-socket")
+		Expect(ioutil.WriteFile(otherAddress, []byte("data"), 0600)).To(Succeed())
+		other, err := New(mw, &Config{Network: NetworkUnix, Address: otherAddress, AuthToken: "token"})
+		Expect(err).To(BeNil())
+		Expect(other.Start()).NotTo(Succeed())
+		Expect(ioutil.ReadFile(otherAddress)).To(Equal([]byte("data")))
+
+		server.Stop()
+		_, err = os.Lstat(address)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("requires authentication before other methods", func() {
+		resp := call("listWallets", nil)
+		Expect(resp.Error).NotTo(BeNil())
+		Expect(resp.Error.Code).To(Equal(ErrCodeUnauthenticated))
+
+		resp = call("authenticate", map[string]string{"token": "wrong"})
+		Expect(resp.Error.Code).To(Equal(ErrCodeUnauthenticated))
+
+		resp = call("authenticate", map[string]string{"token": "token"})
+		Expect(resp.Error).To(BeNil())
+
+		resp = call("listWallets", nil)
+		Expect(resp.Error).To(BeNil())
+		Expect(string(resp.Result)).To(Equal("[]"))
+	})
+
+	It("returns json-rpc errors for unknown methods and wallets", func() {
+		call("authenticate", map[string]string{"token": "token"})
+
+		resp := call("unknownMethod", nil)
+		Expect(resp.Error.Code).To(Equal(ErrCodeMethodNotFound))
+
+		resp = call("getAccounts", map[string]int{"wallet_id": 5})
+		Expect(resp.Error.Code).To(Equal(ErrCodeWalletNotExist))
+
+		resp = call("validateAddress", map[string]string{"address": "invalid"})
+		Expect(resp.Error).To(BeNil())
+		Expect(string(resp.Result)).To(Equal("false"))
+	})
+
+	It("does not leave listeners behind when subscribing fails", func() {
+		// take the sync progress listener id of the first connection
+		Expect(mw.AddSyncProgressListener(&subscriber{}, "rpcserver-1")).To(Succeed())
+
+		call("authenticate", map[string]string{"token": "token"})
+		resp := call("subscribe", nil)
+		Expect(resp.Error).NotTo(BeNil())
+
+		Expect(mw.AddTxAndBlockEventListener(&subscriber{}, "rpcserver-1")).To(Succeed())
+		mw.RemoveTxAndBlockEventListener("rpcserver-1")
+	})
+})
@@ -0,0 +1,275 @@
+package dcrlibwallet
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+const (
+	// MaxSeedBackupQuizAttempts is the number of failed attempts in a row at
+	// the seed backup quizzes of a wallet after which the quiz is discarded
+	// and no new quiz can be generated for SeedBackupQuizLockout.
+	MaxSeedBackupQuizAttempts = 3
+
+	// SeedBackupQuizLockout is how long no seed backup quiz can be generated
+	// for a wallet after MaxSeedBackupQuizAttempts failed attempts.
+	SeedBackupQuizLockout = 10 * time.Minute
+
+	// The failed attempts are counted across quizzes, so that generating a
+	// new quiz doesn't reset them, and are saved with the wallet config so
+	// that restarts don't either.
+	seedBackupQuizFailedAttemptsConfigKey = "seed_backup_quiz_failed_attempts"
+	seedBackupQuizLockedUntilConfigKey    = "seed_backup_quiz_locked_until"
+
+	// seedBackupQuizChoices is the number of words offered for each question
+	// of a seed backup quiz, including the seed word.
+	seedBackupQuizChoices = 4
+)
+
+// NewSeedBackupQuiz generates a quiz asking for the seed words of the wallet at
+// `numQuestions` random positions, which replaces any previous quiz of the
+// wallet. The decoy choices of each question are words of the PGP word list
+// that can appear at its position, so the right word can't be told from the
+// word list alternation. The quiz is only kept in memory, so the choices that
+// reveal the seed words are never written to disk, and it is lost when the
+// MultiWallet is shut down. The answers are checked against the decrypted seed
+// by AnswerSeedBackupQuiz. No quiz can be generated for SeedBackupQuizLockout
+// after MaxSeedBackupQuizAttempts failed attempts.
+func (mw *MultiWallet) NewSeedBackupQuiz(walletID int, numQuestions int32, privpass []byte) (*SeedBackupQuiz, error) {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	lockedUntil := wallet.ReadLongConfigValueForKey(seedBackupQuizLockedUntilConfigKey, 0)
+	if time.Now().Unix() < lockedUntil {
+		return nil, errors.New(ErrSeedBackupQuizLockedOut)
+	}
+
+	seedWords, err := wallet.decryptSeedWords(privpass)
+	if err != nil {
+		return nil, err
+	}
+
+	if numQuestions < 1 || int(numQuestions) > len(seedWords) {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	positions, err := randomPositions(len(seedWords), int(numQuestions))
+	if err != nil {
+		return nil, err
+	}
+
+	wordList := PGPWordList()
+	quiz := &SeedBackupQuiz{
+		WalletID:  walletID,
+		Questions: make([]*SeedBackupQuestion, len(positions)),
+		Attempts:  wallet.ReadInt32ConfigValueForKey(seedBackupQuizFailedAttemptsConfigKey, 0),
+		CreatedAt: time.Now().Unix(),
+	}
+	for i, position := range positions {
+		choices, err := seedWordChoices(wordList, position, seedWords[position])
+		if err != nil {
+			return nil, err
+		}
+		quiz.Questions[i] = &SeedBackupQuestion{
+			Position: int32(position),
+			Choices:  choices,
+		}
+	}
+
+	mw.seedBackupQuizzesMu.Lock()
+	mw.seedBackupQuizzes[walletID] = quiz
+	mw.seedBackupQuizzesMu.Unlock()
+
+	return quiz.copy(), nil
+}
+
+// SeedBackupQuiz returns the current seed backup quiz of the wallet.
+func (mw *MultiWallet) SeedBackupQuiz(walletID int) (*SeedBackupQuiz, error) {
+	mw.seedBackupQuizzesMu.Lock()
+	defer mw.seedBackupQuizzesMu.Unlock()
+
+	quiz, ok := mw.seedBackupQuizzes[walletID]
+	if !ok {
+		return nil, errors.New(ErrNotExist)
+	}
+	return quiz.copy(), nil
+}
+
+// copy returns a copy of the quiz that callers can't change the kept quiz
+// through.
+func (quiz *SeedBackupQuiz) copy() *SeedBackupQuiz {
+	quizCopy := *quiz
+	quizCopy.Questions = make([]*SeedBackupQuestion, len(quiz.Questions))
+	for i, question := range quiz.Questions {
+		quizCopy.Questions[i] = &SeedBackupQuestion{
+			Position: question.Position,
+			Choices:  append([]string(nil), question.Choices...),
+		}
+	}
+	return &quizCopy
+}
+
+// AnswerSeedBackupQuiz checks the json-encoded list of `answers` to the
+// questions of the seed backup quiz of the wallet. See AnswerSeedBackupQuizRaw.
+func (mw *MultiWallet) AnswerSeedBackupQuiz(walletID int, answers string, privpass []byte) (*SeedBackupQuizResult, error) {
+	var answerList []string
+	if err := json.Unmarshal([]byte(answers), &answerList); err != nil {
+		log.Error(err)
+		return nil, errors.New(ErrInvalid)
+	}
+
+	return mw.AnswerSeedBackupQuizRaw(walletID, answerList, privpass)
+}
+
+// AnswerSeedBackupQuizRaw checks the `answers` to the questions of the seed
+// backup quiz of the wallet, given in the order of the questions, against the
+// seed. The seed is considered backed up and wallet.EncryptedSeed is cleared
+// once all answers are right. The quiz is discarded after
+// MaxSeedBackupQuizAttempts failed attempts in a row, at this and previous
+// quizzes of the wallet.
+func (mw *MultiWallet) AnswerSeedBackupQuizRaw(walletID int, answers []string, privpass []byte) (*SeedBackupQuizResult, error) {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return nil, errors.New(ErrNotExist)
+	}
+
+	mw.seedBackupQuizzesMu.Lock()
+	defer mw.seedBackupQuizzesMu.Unlock()
+
+	quiz, ok := mw.seedBackupQuizzes[walletID]
+	if !ok {
+		return nil, errors.New(ErrNotExist)
+	}
+	if len(answers) != len(quiz.Questions) {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	seedWords, err := wallet.decryptSeedWords(privpass)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SeedBackupQuizResult{
+		WrongPositions: make([]int32, 0),
+	}
+	for i, question := range quiz.Questions {
+		answer := strings.TrimSpace(answers[i])
+		if int(question.Position) >= len(seedWords) || !strings.EqualFold(answer, seedWords[question.Position]) {
+			result.WrongPositions = append(result.WrongPositions, question.Position)
+		}
+	}
+
+	if len(result.WrongPositions) == 0 {
+		result.Passed = true
+		wallet.EncryptedSeed = nil
+		err = mw.db.Save(wallet)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		delete(mw.seedBackupQuizzes, walletID)
+		wallet.SetInt32ConfigValueForKey(seedBackupQuizFailedAttemptsConfigKey, 0)
+		return result, nil
+	}
+
+	quiz.Attempts = wallet.ReadInt32ConfigValueForKey(seedBackupQuizFailedAttemptsConfigKey, 0) + 1
+	result.RemainingAttempts = MaxSeedBackupQuizAttempts - quiz.Attempts
+	if result.RemainingAttempts > 0 {
+		wallet.SetInt32ConfigValueForKey(seedBackupQuizFailedAttemptsConfigKey, quiz.Attempts)
+		return result, nil
+	}
+
+	log.Infof("[%d] Seed backup quiz failed %d times, discarding it", walletID, quiz.Attempts)
+	result.RemainingAttempts = 0
+	result.LockedUntil = time.Now().Add(SeedBackupQuizLockout).Unix()
+	wallet.SetLongConfigValueForKey(seedBackupQuizLockedUntilConfigKey, result.LockedUntil)
+	wallet.SetInt32ConfigValueForKey(seedBackupQuizFailedAttemptsConfigKey, 0)
+	delete(mw.seedBackupQuizzes, walletID)
+
+	return result, nil
+}
+
+func (mw *MultiWallet) deleteSeedBackupQuiz(walletID int) {
+	mw.seedBackupQuizzesMu.Lock()
+	delete(mw.seedBackupQuizzes, walletID)
+	mw.seedBackupQuizzesMu.Unlock()
+}
+
+// decryptSeedWords returns the words of the seed of a wallet whose seed has
+// not been verified yet.
+func (wallet *Wallet) decryptSeedWords(privpass []byte) ([]string, error) {
+	seed, err := wallet.DecryptSeed(privpass)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(strings.ToLower(seed)), nil
+}
+
+// seedWordChoices returns `seedWord` and seedBackupQuizChoices-1 other words
+// of `wordList` that can appear at `position` in a seed, in random order.
+// The words at even positions are at even indexes of the PGP word list and the
+// words at odd positions at odd indexes.
+func seedWordChoices(wordList []string, position int, seedWord string) ([]string, error) {
+	choices := []string{seedWord}
+	for len(choices) < seedBackupQuizChoices {
+		index, err := randomInt(len(wordList) / 2)
+		if err != nil {
+			return nil, err
+		}
+		word := strings.ToLower(wordList[index*2+position%2])
+
+		isChoice := false
+		for _, choice := range choices {
+			isChoice = isChoice || choice == word
+		}
+		if !isChoice {
+			choices = append(choices, word)
+		}
+	}
+
+	// Fisher-Yates shuffle
+	for i := len(choices) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		choices[i], choices[j] = choices[j], choices[i]
+	}
+
+	return choices, nil
+}
+
+// randomPositions returns `count` distinct random positions below `n`, sorted.
+func randomPositions(n, count int) ([]int, error) {
+	selected := make(map[int]bool, count)
+	positions := make([]int, 0, count)
+	for len(positions) < count {
+		position, err := randomInt(n)
+		if err != nil {
+			return nil, err
+		}
+		if !selected[position] {
+			selected[position] = true
+			positions = append(positions, position)
+		}
+	}
+
+	sort.Ints(positions)
+	return positions, nil
+}
+
+// randomInt returns a uniformly random int in [0, n) read from crypto/rand.
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
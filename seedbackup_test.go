@@ -0,0 +1,170 @@
+package dcrlibwallet
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seed backup quiz", func() {
+	var (
+		mw        *MultiWallet
+		wallet    *Wallet
+		seedWords []string
+	)
+
+	const passphrase = "passphrase"
+
+	BeforeEach(func() {
+		var err error
+		mw = newTestMultiWallet("testnet3")
+
+		wallet, err = mw.CreateNewWallet("quiz", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+
+		seed, err := wallet.DecryptSeed([]byte(passphrase))
+		Expect(err).To(BeNil())
+		seedWords = strings.Fields(seed)
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("offers the seed word among words of the same position parity", func() {
+		_, err := mw.NewSeedBackupQuiz(wallet.ID, 0, []byte(passphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+		_, err = mw.NewSeedBackupQuiz(wallet.ID, int32(len(seedWords)+1), []byte(passphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+		_, err = mw.NewSeedBackupQuiz(wallet.ID, 3, []byte("wrong"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+
+		quiz, err := mw.NewSeedBackupQuiz(wallet.ID, 5, []byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(quiz.Questions).To(HaveLen(5))
+
+		wordIndexes := make(map[string]int)
+		for i, word := range PGPWordList() {
+			wordIndexes[strings.ToLower(word)] = i
+		}
+
+		for i, question := range quiz.Questions {
+			if i > 0 {
+				Expect(question.Position).To(BeNumerically(">", quiz.Questions[i-1].Position))
+			}
+			Expect(question.Choices).To(HaveLen(seedBackupQuizChoices))
+			Expect(question.Choices).To(ContainElement(strings.ToLower(seedWords[question.Position])))
+			for _, choice := range question.Choices {
+				index, ok := wordIndexes[choice]
+				Expect(ok).To(BeTrue())
+				Expect(index % 2).To(Equal(int(question.Position % 2)))
+			}
+		}
+
+		currentQuiz, err := mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(BeNil())
+		Expect(currentQuiz.Questions).To(Equal(quiz.Questions))
+
+		// the quiz is not saved, it is lost on restart
+		mw = reopenTestMultiWallet(mw)
+		_, err = mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(MatchError(ErrNotExist))
+	})
+
+	It("discards the quiz after too many wrong answers", func() {
+		quiz, err := mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(BeNil())
+
+		answer := func(attempt int32) *SeedBackupQuizResult {
+			answers := []string{"wrong", strings.ToUpper(seedWords[quiz.Questions[1].Position])}
+			result, err := mw.AnswerSeedBackupQuizRaw(wallet.ID, answers, []byte(passphrase))
+			Expect(err).To(BeNil())
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.WrongPositions).To(Equal([]int32{quiz.Questions[0].Position}))
+			Expect(result.RemainingAttempts).To(Equal(MaxSeedBackupQuizAttempts - attempt))
+			return result
+		}
+
+		answer(1)
+
+		// a new quiz doesn't reset the failed attempts
+		quiz, err = mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(quiz.Attempts).To(Equal(int32(1)))
+		for attempt := int32(2); attempt < MaxSeedBackupQuizAttempts; attempt++ {
+			answer(attempt)
+		}
+		result := answer(MaxSeedBackupQuizAttempts)
+		Expect(result.LockedUntil).To(BeNumerically("~", time.Now().Add(SeedBackupQuizLockout).Unix(), 1))
+
+		_, err = mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(MatchError(ErrNotExist))
+		_, err = mw.AnswerSeedBackupQuizRaw(wallet.ID, []string{"wrong", "wrong"}, []byte(passphrase))
+		Expect(err).To(MatchError(ErrNotExist))
+		Expect(wallet.EncryptedSeed).NotTo(BeNil())
+
+		_, err = mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(MatchError(ErrSeedBackupQuizLockedOut))
+
+		// the lockout survives restarts
+		mw = reopenTestMultiWallet(mw)
+		_, err = mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(MatchError(ErrSeedBackupQuizLockedOut))
+
+		mw.WalletWithID(wallet.ID).SetLongConfigValueForKey(seedBackupQuizLockedUntilConfigKey, time.Now().Unix()-1)
+		quiz, err = mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(quiz.Attempts).To(BeZero())
+	})
+
+	It("returns copies of the quiz", func() {
+		quiz, err := mw.NewSeedBackupQuiz(wallet.ID, 2, []byte(passphrase))
+		Expect(err).To(BeNil())
+		position := quiz.Questions[0].Position
+		choice := quiz.Questions[0].Choices[0]
+
+		quiz.Questions[0].Position++
+		quiz.Questions[0].Choices[0] = "changed"
+		quiz.Attempts = MaxSeedBackupQuizAttempts
+
+		currentQuiz, err := mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(BeNil())
+		Expect(currentQuiz.Questions[0].Position).To(Equal(position))
+		Expect(currentQuiz.Questions[0].Choices[0]).To(Equal(choice))
+		Expect(currentQuiz.Attempts).To(BeZero())
+
+		currentQuiz.Questions[1].Choices = nil
+		currentQuiz, err = mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(BeNil())
+		Expect(currentQuiz.Questions[1].Choices).To(HaveLen(seedBackupQuizChoices))
+	})
+
+	It("clears the seed once all answers are right", func() {
+		quiz, err := mw.NewSeedBackupQuiz(wallet.ID, 3, []byte(passphrase))
+		Expect(err).To(BeNil())
+
+		_, err = mw.AnswerSeedBackupQuiz(wallet.ID, `["too", "few"]`, []byte(passphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+		_, err = mw.AnswerSeedBackupQuiz(wallet.ID, "not json", []byte(passphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		answers := make([]string, len(quiz.Questions))
+		for i, question := range quiz.Questions {
+			answers[i] = " " + seedWords[question.Position] + " "
+		}
+		answersJSON, err := json.Marshal(answers)
+		Expect(err).To(BeNil())
+		result, err := mw.AnswerSeedBackupQuiz(wallet.ID, string(answersJSON), []byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(result.Passed).To(BeTrue())
+		Expect(result.WrongPositions).To(BeEmpty())
+
+		Expect(wallet.EncryptedSeed).To(BeNil())
+		Expect(mw.NumWalletsNeedingSeedBackup()).To(Equal(int32(0)))
+		_, err = mw.SeedBackupQuiz(wallet.ID)
+		Expect(err).To(MatchError(ErrNotExist))
+	})
+})
@@ -0,0 +1,283 @@
+package dcrlibwallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/decred/dcrd/hdkeychain/v2"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/decred/dcrwallet/pgpwordlist"
+	"github.com/decred/dcrwallet/walletseed"
+	"github.com/planetdecred/dcrlibwallet/seedshares"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Formats of the seeds wallets can be restored from.
+const (
+	SeedFormatPGP    = "pgp"
+	SeedFormatHex    = "hex"
+	SeedFormatBIP39  = "bip39"
+	SeedFormatShares = "shares"
+)
+
+// maxWordSuggestions is the number of words suggested in place of an invalid
+// seed word.
+const maxWordSuggestions = 3
+
+// ParseSeedInput detects the format of a seed entered by the user and checks
+// it. The seed may be a PGP word list mnemonic, a hex seed, a BIP0039 mnemonic
+// of the english word list, or of the list set with SetBIP39WordList, or the
+// seed shares of a group on separate lines. Words that are not valid at their
+// position are returned with the closest valid words. A seed returned as valid can be
+// passed to RestoreWallet, or to RestoreWalletWithSeedPassphrase along with
+// the passphrase of a BIP0039 mnemonic.
+func ParseSeedInput(seedInput string) (*SeedInput, error) {
+	format, lines, words := seedInputFormat(seedInput)
+	if len(words) == 0 {
+		return nil, errors.New(ErrEmptySeed)
+	}
+
+	parsed := &SeedInput{
+		Format:      format,
+		WordCount:   int32(len(words)),
+		Corrections: make([]*SeedWordCorrection, 0),
+	}
+
+	var err error
+	switch format {
+	case SeedFormatShares:
+		var seed []byte
+		seed, err = combineSeedShares(lines)
+		zeroBytes(seed)
+
+	case SeedFormatHex:
+		var seed []byte
+		seed, err = hex.DecodeString(words[0])
+		if err == nil && (len(seed) < hdkeychain.MinSeedBytes || len(seed) > hdkeychain.MaxSeedBytes) {
+			err = errors.New(ErrInvalid)
+		}
+		zeroBytes(seed)
+
+	case SeedFormatBIP39:
+		wordList, wordIndexes := bip39Words()
+		for i, word := range words {
+			if _, ok := wordIndexes[word]; !ok {
+				parsed.Corrections = append(parsed.Corrections, &SeedWordCorrection{
+					Index:       int32(i),
+					Word:        word,
+					Suggestions: closestWords(word, wordList),
+				})
+			}
+		}
+		if len(parsed.Corrections) == 0 {
+			var checksumMatches bool
+			if !isBIP39MnemonicLength(len(words)) {
+				err = errors.New(ErrInvalid)
+			} else if _, checksumMatches = bip39Entropy(words, wordIndexes); !checksumMatches {
+				parsed.ChecksumMismatch = true
+			}
+		}
+
+	default:
+		parsed.Corrections = invalidPGPWords(words)
+		if len(parsed.Corrections) == 0 {
+			parsed.ChecksumMismatch = !pgpChecksumMatches(words)
+			var seed []byte
+			seed, err = walletseed.DecodeUserInput(strings.Join(words, " "))
+			zeroBytes(seed)
+		}
+	}
+
+	parsed.Valid = err == nil && !parsed.ChecksumMismatch && len(parsed.Corrections) == 0
+	return parsed, nil
+}
+
+// decodeSeedInput decodes a seed in any of the formats of ParseSeedInput.
+// `seedPassphrase` is the optional passphrase of a BIP0039 mnemonic, the other
+// formats have no passphrase.
+func decodeSeedInput(seedInput, seedPassphrase string) ([]byte, error) {
+	format, lines, words := seedInputFormat(seedInput)
+	if format != SeedFormatBIP39 && seedPassphrase != "" {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	switch format {
+	case SeedFormatShares:
+		return combineSeedShares(lines)
+
+	case SeedFormatBIP39:
+		_, wordIndexes := bip39Words()
+		for _, word := range words {
+			if _, ok := wordIndexes[word]; !ok {
+				return nil, errors.New(ErrInvalid)
+			}
+		}
+		if !isBIP39MnemonicLength(len(words)) {
+			return nil, errors.New(ErrInvalid)
+		}
+		entropy, checksumMatches := bip39Entropy(words, wordIndexes)
+		zeroBytes(entropy)
+		if !checksumMatches {
+			return nil, errors.New(ErrInvalid)
+		}
+		return bip39Seed(words, seedPassphrase), nil
+
+	default:
+		return walletseed.DecodeUserInput(strings.Join(words, " "))
+	}
+}
+
+// seedInputFormat returns the format of `seedInput` along with its non-empty
+// lines and its words, normalized to lower case. Lines that all decode as
+// shares, or only fail the checksum of a share, are seed shares. Otherwise the
+// words of all lines form a single seed, a single word is a hex seed and the
+// other seeds are BIP0039 mnemonics if they have more words from the BIP0039
+// word list than from the PGP word list and PGP mnemonics otherwise.
+func seedInputFormat(seedInput string) (string, []string, []string) {
+	lines := seedInputLines(seedInput)
+	var words []string
+	for _, line := range lines {
+		words = append(words, strings.Fields(line)...)
+	}
+
+	if len(lines) > 0 && areSeedShares(lines) {
+		return SeedFormatShares, lines, words
+	}
+	if len(words) == 1 {
+		return SeedFormatHex, lines, words
+	}
+
+	_, bip39Indexes := bip39Words()
+	pgpIndexes := pgpWordIndexes()
+	var pgpMatches, bip39Matches int
+	for _, word := range words {
+		if _, ok := pgpIndexes[word]; ok {
+			pgpMatches++
+		}
+		if _, ok := bip39Indexes[word]; ok {
+			bip39Matches++
+		}
+	}
+	if bip39Matches > pgpMatches {
+		return SeedFormatBIP39, lines, words
+	}
+	return SeedFormatPGP, lines, words
+}
+
+// seedInputLines returns the non-empty lines of `seedInput` with their words
+// normalized to lower case and separated by single spaces.
+func seedInputLines(seedInput string) []string {
+	var lines []string
+	for _, line := range strings.Split(seedInput, "\n") {
+		lineWords := strings.Fields(norm.NFKD.String(strings.ToLower(line)))
+		if len(lineWords) > 0 {
+			lines = append(lines, strings.Join(lineWords, " "))
+		}
+	}
+	return lines
+}
+
+// areSeedShares returns true if each of `lines` decodes as a seed share or
+// only fails the checksum of a share, which is reported when the shares are
+// combined.
+func areSeedShares(lines []string) bool {
+	for _, line := range lines {
+		share, err := seedshares.Decode(line)
+		if err == nil {
+			zeroBytes(share.Value)
+		} else if err != seedshares.ErrChecksum {
+			return false
+		}
+	}
+	return true
+}
+
+// pgpWordIndexes returns the index of each word in the PGP word list. Even
+// indexes are the words valid at even positions of a mnemonic and odd indexes
+// the words valid at odd positions.
+func pgpWordIndexes() map[string]int {
+	wordList := PGPWordList()
+	wordIndexes := make(map[string]int, len(wordList))
+	for i, word := range wordList {
+		wordIndexes[strings.ToLower(word)] = i
+	}
+	return wordIndexes
+}
+
+// pgpChecksumMatches returns true if the last word of the PGP word list
+// mnemonic `words` is the checksum of the other words, the first byte of
+// their double SHA256. The words must be valid at their positions.
+func pgpChecksumMatches(words []string) bool {
+	decoded, err := pgpwordlist.DecodeMnemonics(words)
+	if err != nil || len(decoded) < 2 {
+		return false
+	}
+	defer zeroBytes(decoded)
+
+	intermediateHash := sha256.Sum256(decoded[:len(decoded)-1])
+	return sha256.Sum256(intermediateHash[:])[0] == decoded[len(decoded)-1]
+}
+
+// pgpWordsAtPosition returns the PGP words valid at `position` in a mnemonic.
+func pgpWordsAtPosition(position int) []string {
+	wordList := PGPWordList()
+	words := make([]string, 0, len(wordList)/2)
+	for i := position % 2; i < len(wordList); i += 2 {
+		words = append(words, strings.ToLower(wordList[i]))
+	}
+	return words
+}
+
+// closestWords returns the maxWordSuggestions words of `candidates` with the
+// smallest edit distance to `word`, closest first.
+func closestWords(word string, candidates []string) []string {
+	distances := make(map[string]int, len(candidates))
+	closest := make([]string, len(candidates))
+	copy(closest, candidates)
+	for _, candidate := range closest {
+		distances[candidate] = editDistance(word, candidate)
+	}
+
+	sort.SliceStable(closest, func(i, j int) bool {
+		return distances[closest[i]] < distances[closest[j]]
+	})
+	if len(closest) > maxWordSuggestions {
+		closest = closest[:maxWordSuggestions]
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b, the number
+// of single character insertions, deletions and substitutions turning a into
+// b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			substitution := previous[j-1]
+			if ra[i-1] != rb[j-1] {
+				substitution++
+			}
+			current[j] = minInt(substitution, minInt(previous[j]+1, current[j-1]+1))
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
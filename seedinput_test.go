@@ -0,0 +1,197 @@
+package dcrlibwallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrwallet/walletseed"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seed input", func() {
+	It("detects PGP mnemonics, hex seeds and seed shares", func() {
+		_, err := ParseSeedInput(" \n ")
+		Expect(err).To(MatchError(ErrEmptySeed))
+
+		seedMnemonic, err := GenerateSeed()
+		Expect(err).To(BeNil())
+		parsed, err := ParseSeedInput(" " + strings.ToUpper(seedMnemonic) + "\n")
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatPGP))
+		Expect(parsed.WordCount).To(Equal(int32(33)))
+		Expect(parsed.Valid).To(BeTrue())
+		Expect(parsed.Corrections).To(BeEmpty())
+
+		seed, err := walletseed.DecodeUserInput(seedMnemonic)
+		Expect(err).To(BeNil())
+		parsed, err = ParseSeedInput(hex.EncodeToString(seed))
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatHex))
+		Expect(parsed.Valid).To(BeTrue())
+		parsed, err = ParseSeedInput(hex.EncodeToString(seed[:8]))
+		Expect(err).To(BeNil())
+		Expect(parsed.Valid).To(BeFalse())
+
+		sharesText, err := SplitSeed(seedMnemonic, 2, 3)
+		Expect(err).To(BeNil())
+		shares := strings.Split(sharesText, "\n")
+		parsed, err = ParseSeedInput(shares[0] + "\n" + shares[2])
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatShares))
+		Expect(parsed.Valid).To(BeTrue())
+	})
+
+	It("joins the lines of a PGP mnemonic written on several lines", func() {
+		seedMnemonic, err := GenerateSeed()
+		Expect(err).To(BeNil())
+		words := strings.Fields(seedMnemonic)
+
+		// lines of an even number of words decode as PGP words at every position
+		multiLine := strings.Join(words[:12], " ") + "\n" + strings.Join(words[12:24], " ") + "\n" +
+			strings.Join(words[24:], " ")
+		parsed, err := ParseSeedInput(multiLine)
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatPGP))
+		Expect(parsed.WordCount).To(Equal(int32(33)))
+		Expect(parsed.Valid).To(BeTrue())
+
+		seed, err := decodeSeedInput(multiLine, "")
+		Expect(err).To(BeNil())
+		expectedSeed, err := walletseed.DecodeUserInput(seedMnemonic)
+		Expect(err).To(BeNil())
+		Expect(seed).To(Equal(expectedSeed))
+	})
+
+	It("suggests corrections for invalid PGP words and detects checksum mismatches", func() {
+		seedMnemonic, err := GenerateSeed()
+		Expect(err).To(BeNil())
+		words := strings.Fields(strings.ToLower(seedMnemonic))
+		wordList := PGPWordList()
+		for i := range wordList {
+			wordList[i] = strings.ToLower(wordList[i])
+		}
+
+		// misspell a word and use a word of the other position parity
+		misspelled := append([]string(nil), words...)
+		misspelled[5] = words[5][:len(words[5])-1]
+		for i, word := range wordList {
+			if word == words[6] {
+				misspelled[6] = wordList[i^1]
+			}
+		}
+
+		parsed, err := ParseSeedInput(strings.Join(misspelled, " "))
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatPGP))
+		Expect(parsed.Valid).To(BeFalse())
+		Expect(parsed.Corrections).To(HaveLen(2))
+		Expect(parsed.Corrections[0].Index).To(Equal(int32(5)))
+		Expect(parsed.Corrections[0].Word).To(Equal(misspelled[5]))
+		Expect(parsed.Corrections[0].Suggestions).To(ContainElement(words[5]))
+		Expect(parsed.Corrections[1].Index).To(Equal(int32(6)))
+		Expect(parsed.Corrections[1].Suggestions).To(HaveLen(maxWordSuggestions))
+		for _, suggestion := range parsed.Corrections[1].Suggestions {
+			Expect(pgpWordsAtPosition(6)).To(ContainElement(suggestion))
+		}
+
+		// replace the checksum word with another word valid at its position
+		wrongChecksum := append([]string(nil), words...)
+		for i, word := range wordList {
+			if word == words[32] {
+				wrongChecksum[32] = wordList[(i+2)%len(wordList)]
+			}
+		}
+		parsed, err = ParseSeedInput(strings.Join(wrongChecksum, " "))
+		Expect(err).To(BeNil())
+		Expect(parsed.Corrections).To(BeEmpty())
+		Expect(parsed.ChecksumMismatch).To(BeTrue())
+		Expect(parsed.Valid).To(BeFalse())
+		Expect(VerifySeed(strings.Join(wrongChecksum, " "))).To(BeFalse())
+	})
+
+	Context("with BIP0039 mnemonics", func() {
+		const (
+			testMnemonic   = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+			testPassphrase = "TREZOR"
+			testSeed       = "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+		)
+
+		It("detects BIP0039 mnemonics and derives their seed", func() {
+			parsed, err := ParseSeedInput(testMnemonic)
+			Expect(err).To(BeNil())
+			Expect(parsed.Format).To(Equal(SeedFormatBIP39))
+			Expect(parsed.WordCount).To(Equal(int32(12)))
+			Expect(parsed.Valid).To(BeTrue())
+
+			parsed, err = ParseSeedInput(strings.Replace(testMnemonic, "about", "abandon", 1))
+			Expect(err).To(BeNil())
+			Expect(parsed.ChecksumMismatch).To(BeTrue())
+			Expect(parsed.Valid).To(BeFalse())
+
+			parsed, err = ParseSeedInput(strings.Replace(testMnemonic, "abandon", "abandn", 1))
+			Expect(err).To(BeNil())
+			Expect(parsed.Corrections).To(HaveLen(1))
+			Expect(parsed.Corrections[0].Index).To(Equal(int32(0)))
+			Expect(parsed.Corrections[0].Suggestions[0]).To(Equal("abandon"))
+
+			seed, err := decodeSeedInput(testMnemonic, testPassphrase)
+			Expect(err).To(BeNil())
+			Expect(hex.EncodeToString(seed)).To(Equal(testSeed))
+
+			seed, err = decodeSeedInput("legal winner thank year wave sausage worth useful legal winner thank yellow", testPassphrase)
+			Expect(err).To(BeNil())
+			Expect(hex.EncodeToString(seed)).To(Equal("2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"))
+
+			seedMnemonic, err := GenerateSeed()
+			Expect(err).To(BeNil())
+			_, err = decodeSeedInput(seedMnemonic, testPassphrase)
+			Expect(err).To(MatchError(ErrInvalid))
+		})
+
+		It("decodes mnemonics with the word list set with SetBIP39WordList", func() {
+			Expect(SetBIP39WordList("abandon ability able")).To(MatchError(ErrInvalid))
+
+			// the test mnemonic only uses the words at indexes 0 and 3
+			wordList := []string{"abandon", "ability", "able", "about"}
+			for i := len(wordList); i < bip39WordListSize; i++ {
+				wordList = append(wordList, fmt.Sprintf("word%04d", i))
+			}
+			Expect(SetBIP39WordList(strings.Join(wordList, "\n"))).To(BeNil())
+			defer func() {
+				bip39Mu.Lock()
+				bip39WordList, bip39WordIndexes = nil, nil
+				bip39Mu.Unlock()
+			}()
+
+			parsed, err := ParseSeedInput(testMnemonic)
+			Expect(err).To(BeNil())
+			Expect(parsed.Format).To(Equal(SeedFormatBIP39))
+			Expect(parsed.Valid).To(BeTrue())
+
+			parsed, err = ParseSeedInput("zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong")
+			Expect(err).To(BeNil())
+			Expect(parsed.Valid).To(BeFalse())
+		})
+
+		It("restores wallets from BIP0039 mnemonics", func() {
+			mw := newTestMultiWallet("testnet3")
+			defer removeTestMultiWallet(mw)
+
+			const passphrase = "passphrase"
+			wallet, err := mw.RestoreWalletWithSeedPassphrase("bip39", testMnemonic, testPassphrase, passphrase,
+				PassphraseTypePass, 0, 0)
+			Expect(err).To(BeNil())
+			hexWallet, err := mw.RestoreWallet("hex", testSeed, passphrase, PassphraseTypePass, 0, 0)
+			Expect(err).To(BeNil())
+
+			ctx := wallet.shutdownContext()
+			xpub, err := wallet.internal.MasterPubKey(ctx, 0)
+			Expect(err).To(BeNil())
+			hexXPub, err := hexWallet.internal.MasterPubKey(ctx, 0)
+			Expect(err).To(BeNil())
+			Expect(xpub.String()).To(Equal(hexXPub.String()))
+		})
+	})
+})
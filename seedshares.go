@@ -0,0 +1,136 @@
+package dcrlibwallet
+
+import (
+	"strings"
+
+	"github.com/decred/dcrd/hdkeychain/v2"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/decred/dcrwallet/walletseed"
+	"github.com/planetdecred/dcrlibwallet/seedshares"
+)
+
+// SplitSeed splits the seed of `seedMnemonic` into `count` seed shares, any
+// `threshold` of which recover the seed. The shares are returned one per line,
+// the lines of any `threshold` shares can be passed to RestoreWallet in place
+// of the seed mnemonic.
+func SplitSeed(seedMnemonic string, threshold, count int32) (string, error) {
+	seed, err := walletseed.DecodeUserInput(seedMnemonic)
+	if err != nil {
+		return "", errors.New(ErrInvalid)
+	}
+	defer zeroBytes(seed)
+
+	shares, err := splitSeed(seed, threshold, count)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(shares, "\n"), nil
+}
+
+// SplitWalletSeed splits the seed of a wallet whose seed has not been verified
+// yet into seed shares, see SplitSeed.
+func (mw *MultiWallet) SplitWalletSeed(walletID int, threshold, count int32, privpass []byte) (string, error) {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return "", errors.New(ErrNotExist)
+	}
+
+	seedMnemonic, err := wallet.DecryptSeed(privpass)
+	if err != nil {
+		return "", err
+	}
+
+	return SplitSeed(seedMnemonic, threshold, count)
+}
+
+// DecodeSeedShare checks the checksum of a seed share and returns its group
+// and index so that clients can tell which shares go together and how many
+// more are needed.
+func DecodeSeedShare(share string) (*SeedShareInfo, error) {
+	decoded, err := decodeSeedShare(share)
+	if err != nil {
+		return nil, err
+	}
+	zeroBytes(decoded.Value)
+
+	return &SeedShareInfo{
+		GroupID:   int32(decoded.GroupID),
+		Threshold: int32(decoded.Threshold),
+		Count:     int32(decoded.Count),
+		Index:     int32(decoded.Index),
+	}, nil
+}
+
+// CombineSeedShares recovers the seed mnemonic from the seed `shares` of a
+// group, given one per line.
+func CombineSeedShares(shares string) (string, error) {
+	seed, err := combineSeedShares(seedInputLines(shares))
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(seed)
+
+	return walletseed.EncodeMnemonic(seed), nil
+}
+
+func splitSeed(seed []byte, threshold, count int32) ([]string, error) {
+	shares, err := seedshares.Split(seed, int(threshold), int(count))
+	if err != nil {
+		if err == seedshares.ErrInvalidShare {
+			return nil, errors.New(ErrInvalid)
+		}
+		return nil, err
+	}
+
+	encodedShares := make([]string, len(shares))
+	for i, share := range shares {
+		encodedShares[i] = share.Encode()
+		zeroBytes(share.Value)
+	}
+	return encodedShares, nil
+}
+
+func combineSeedShares(encodedShares []string) ([]byte, error) {
+	shares := make([]*seedshares.Share, len(encodedShares))
+	for i, encodedShare := range encodedShares {
+		share, err := decodeSeedShare(encodedShare)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = share
+		defer zeroBytes(share.Value)
+	}
+
+	seed, err := seedshares.Combine(shares)
+	switch err {
+	case nil:
+	case seedshares.ErrThresholdNotMet:
+		return nil, errors.New(ErrSeedSharesThresholdNotMet)
+	default:
+		return nil, errors.New(ErrInvalid)
+	}
+
+	if len(seed) < hdkeychain.MinSeedBytes || len(seed) > hdkeychain.MaxSeedBytes {
+		zeroBytes(seed)
+		return nil, errors.New(ErrInvalid)
+	}
+	return seed, nil
+}
+
+func decodeSeedShare(share string) (*seedshares.Share, error) {
+	decoded, err := seedshares.Decode(share)
+	switch err {
+	case nil:
+		return decoded, nil
+	case seedshares.ErrChecksum:
+		return nil, errors.New(ErrSeedShareChecksum)
+	default:
+		return nil, errors.New(ErrInvalid)
+	}
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
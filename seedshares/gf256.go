@@ -0,0 +1,68 @@
+package seedshares
+
+// The shares are computed byte by byte in GF(2^8) with the AES reduction
+// polynomial x^8 + x^4 + x^3 + x + 1, where addition and subtraction are XOR.
+
+var (
+	expTable [255]byte
+	logTable [256]byte
+)
+
+func init() {
+	// 3 is a generator of the multiplicative group of the field
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		x ^= xtime(x)
+	}
+}
+
+// xtime multiplies b by x, i.e. by 2.
+func xtime(b byte) byte {
+	if b&0x80 != 0 {
+		return b<<1 ^ 0x1b
+	}
+	return b << 1
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+// div returns a / b, b must not be 0.
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+// evaluate returns the value at x of the polynomial with the coefficients
+// `coefficients`, lowest degree first.
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// interpolateAtZero returns the value at 0 of the polynomial of the lowest
+// degree going through the points (xs[i], ys[i]). The xs must be distinct.
+func interpolateAtZero(xs, ys []byte) byte {
+	var y byte
+	for j := range xs {
+		basis := byte(1)
+		for m := range xs {
+			if m != j {
+				basis = mul(basis, div(xs[m], xs[m]^xs[j]))
+			}
+		}
+		y ^= mul(ys[j], basis)
+	}
+	return y
+}
@@ -0,0 +1,215 @@
+// Package seedshares splits wallet seeds into M-of-N Shamir secret shares so
+// that a seed backup can be distributed between several people or places,
+// any M of which can recover the seed while fewer reveal nothing about it.
+//
+// The shares follow the structure of SLIP-0039 shares, each one carries the
+// identifier of the group of shares it was split with, the threshold, the
+// number of shares and its own index next to the share value, protected by a
+// checksum. Unlike SLIP-0039 shares, they are encoded with the PGP word list
+// that Decred seeds are encoded with and are not interchangeable with the
+// shares of SLIP-0039 wallets.
+package seedshares
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/decred/dcrwallet/pgpwordlist"
+)
+
+const (
+	// Version is the version of the share encoding.
+	Version = 1
+
+	// MaxShares is the maximum number of shares a seed can be split into.
+	MaxShares = 16
+
+	headerSize   = 6 // version, group id, threshold, count, index
+	checksumSize = 4
+)
+
+var (
+	// ErrInvalidShare describes a share that can't be decoded or has invalid
+	// parameters.
+	ErrInvalidShare = errors.New("invalid seed share")
+
+	// ErrChecksum describes a share whose checksum does not match, i.e. with
+	// a wrong or misplaced word.
+	ErrChecksum = errors.New("seed share checksum mismatch")
+
+	// ErrGroupMismatch describes shares that were not split from the same
+	// seed at the same time and can't be combined.
+	ErrGroupMismatch = errors.New("seed shares are from different groups")
+
+	// ErrDuplicateShare describes a share passed more than once to Combine.
+	ErrDuplicateShare = errors.New("duplicate seed share")
+
+	// ErrThresholdNotMet describes a set of shares smaller than the threshold
+	// of the group.
+	ErrThresholdNotMet = errors.New("not enough seed shares")
+)
+
+// Share is one of the shares a seed is split into.
+type Share struct {
+	// GroupID is a random identifier shared by all the shares of a split.
+	GroupID uint16
+
+	// Threshold is the number of shares needed to recover the seed and
+	// Count the number of shares the seed was split into.
+	Threshold uint8
+	Count     uint8
+
+	// Index is the 1-based index of the share in the group.
+	Index uint8
+
+	// Value is the share of the seed, as long as the seed.
+	Value []byte
+}
+
+// Split splits `secret` into `count` shares, any `threshold` of which can be
+// combined to recover it.
+func Split(secret []byte, threshold, count int) ([]*Share, error) {
+	if len(secret) == 0 || threshold < 1 || threshold > count || count > MaxShares {
+		return nil, ErrInvalidShare
+	}
+
+	var groupID [2]byte
+	if _, err := io.ReadFull(rand.Reader, groupID[:]); err != nil {
+		return nil, err
+	}
+
+	shares := make([]*Share, count)
+	for i := range shares {
+		shares[i] = &Share{
+			GroupID:   binary.BigEndian.Uint16(groupID[:]),
+			Threshold: uint8(threshold),
+			Count:     uint8(count),
+			Index:     uint8(i + 1),
+			Value:     make([]byte, len(secret)),
+		}
+	}
+
+	// each byte of the secret is the constant term of a random polynomial of
+	// degree threshold-1, the share values are the polynomials at the index
+	coefficients := make([]byte, threshold)
+	defer zero(coefficients)
+	for i, b := range secret {
+		coefficients[0] = b
+		if _, err := io.ReadFull(rand.Reader, coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share.Value[i] = evaluate(coefficients, share.Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine recovers the secret from at least the threshold number of shares of
+// a group.
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrThresholdNotMet
+	}
+
+	first := shares[0]
+	seen := make(map[uint8]bool, len(shares))
+	for _, share := range shares {
+		if share.GroupID != first.GroupID || share.Threshold != first.Threshold ||
+			share.Count != first.Count || len(share.Value) != len(first.Value) {
+			return nil, ErrGroupMismatch
+		}
+		if seen[share.Index] {
+			return nil, ErrDuplicateShare
+		}
+		seen[share.Index] = true
+	}
+	if len(shares) < int(first.Threshold) {
+		return nil, ErrThresholdNotMet
+	}
+
+	shares = shares[:first.Threshold]
+	xs := make([]byte, len(shares))
+	ys := make([]byte, len(shares))
+	for i, share := range shares {
+		xs[i] = share.Index
+	}
+
+	secret := make([]byte, len(first.Value))
+	for i := range secret {
+		for j, share := range shares {
+			ys[j] = share.Value[i]
+		}
+		secret[i] = interpolateAtZero(xs, ys)
+	}
+	zero(ys)
+
+	return secret, nil
+}
+
+// Encode returns the share as a PGP word list mnemonic.
+func (share *Share) Encode() string {
+	data := make([]byte, headerSize, headerSize+len(share.Value)+checksumSize)
+	data[0] = Version
+	binary.BigEndian.PutUint16(data[1:3], share.GroupID)
+	data[3] = share.Threshold
+	data[4] = share.Count
+	data[5] = share.Index
+	data = append(data, share.Value...)
+	data = append(data, checksum(data)...)
+
+	words := make([]string, len(data))
+	for i, b := range data {
+		words[i] = pgpwordlist.ByteToMnemonic(b, i)
+	}
+	zero(data)
+	return strings.Join(words, " ")
+}
+
+// Decode decodes a share encoded with Encode. ErrChecksum is returned if the
+// words and the share header are valid but the checksum does not match, so
+// that other PGP word list mnemonics are very unlikely to be taken for shares
+// with a wrong word.
+func Decode(mnemonic string) (*Share, error) {
+	data, err := pgpwordlist.DecodeMnemonics(strings.Fields(mnemonic))
+	if err != nil || len(data) <= headerSize+checksumSize {
+		return nil, ErrInvalidShare
+	}
+	defer zero(data)
+
+	payload := data[:len(data)-checksumSize]
+	threshold, count, index := payload[3], payload[4], payload[5]
+	if payload[0] != Version || threshold < 1 || threshold > count || count > MaxShares ||
+		index < 1 || index > count {
+		return nil, ErrInvalidShare
+	}
+	if string(checksum(payload)) != string(data[len(payload):]) {
+		return nil, ErrChecksum
+	}
+
+	return &Share{
+		GroupID:   binary.BigEndian.Uint16(payload[1:3]),
+		Threshold: threshold,
+		Count:     count,
+		Index:     index,
+		Value:     append([]byte(nil), payload[headerSize:]...),
+	}, nil
+}
+
+// checksum returns the first checksumSize bytes of the double SHA256 of data.
+func checksum(data []byte) []byte {
+	intermediateHash := sha256.Sum256(data)
+	hash := sha256.Sum256(intermediateHash[:])
+	return hash[:checksumSize]
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
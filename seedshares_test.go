@@ -0,0 +1,125 @@
+package dcrlibwallet
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seed shares", func() {
+	var seedMnemonic string
+
+	BeforeEach(func() {
+		var err error
+		seedMnemonic, err = GenerateSeed()
+		Expect(err).To(BeNil())
+	})
+
+	It("recovers the seed from any threshold set of shares", func() {
+		sharesText, err := SplitSeed(seedMnemonic, 3, 5)
+		Expect(err).To(BeNil())
+		shares := strings.Split(sharesText, "\n")
+		Expect(shares).To(HaveLen(5))
+
+		var groupID int32
+		for i, share := range shares {
+			info, err := DecodeSeedShare(share)
+			Expect(err).To(BeNil())
+			Expect(info.Threshold).To(Equal(int32(3)))
+			Expect(info.Count).To(Equal(int32(5)))
+			Expect(info.Index).To(Equal(int32(i + 1)))
+			if i > 0 {
+				Expect(info.GroupID).To(Equal(groupID))
+			}
+			groupID = info.GroupID
+		}
+
+		for _, indexes := range [][]int{{0, 1, 2}, {4, 0, 2}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+			var subset []string
+			for _, i := range indexes {
+				subset = append(subset, shares[i])
+			}
+			combined, err := CombineSeedShares(strings.Join(subset, "\n"))
+			Expect(err).To(BeNil())
+			Expect(combined).To(Equal(seedMnemonic))
+			Expect(VerifySeed(strings.Join(subset, "\n"))).To(BeTrue())
+		}
+
+		_, err = CombineSeedShares(shares[0] + "\n" + shares[1])
+		Expect(err).To(MatchError(ErrSeedSharesThresholdNotMet))
+		_, err = CombineSeedShares(shares[0] + "\n" + shares[1] + "\n" + shares[1])
+		Expect(err).To(MatchError(ErrInvalid))
+
+		otherSharesText, err := SplitSeed(seedMnemonic, 3, 5)
+		Expect(err).To(BeNil())
+		otherShares := strings.Split(otherSharesText, "\n")
+		_, err = CombineSeedShares(shares[0] + "\n" + shares[1] + "\n" + otherShares[2])
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+
+	It("detects invalid shares and parameters", func() {
+		for _, params := range [][2]int32{{0, 3}, {4, 3}, {2, 17}} {
+			_, err := SplitSeed(seedMnemonic, params[0], params[1])
+			Expect(err).To(MatchError(ErrInvalid))
+		}
+		_, err := SplitSeed("not a seed", 2, 3)
+		Expect(err).To(MatchError(ErrInvalid))
+
+		sharesText, err := SplitSeed(seedMnemonic, 2, 3)
+		Expect(err).To(BeNil())
+		shares := strings.Split(sharesText, "\n")
+
+		// replace a word with another word valid at its position
+		words := strings.Fields(shares[0])
+		wordList := PGPWordList()
+		for i, word := range wordList {
+			if strings.EqualFold(word, words[10]) {
+				words[10] = wordList[(i+2)%len(wordList)]
+				break
+			}
+		}
+		_, err = DecodeSeedShare(strings.Join(words, " "))
+		Expect(err).To(MatchError(ErrSeedShareChecksum))
+
+		// a share with a wrong word is still detected as a share
+		parsed, err := ParseSeedInput(strings.Join(words, " ") + "\n" + shares[1])
+		Expect(err).To(BeNil())
+		Expect(parsed.Format).To(Equal(SeedFormatShares))
+		Expect(parsed.Valid).To(BeFalse())
+
+		// drop a word
+		words = strings.Fields(shares[0])
+		_, err = DecodeSeedShare(strings.Join(words[1:], " "))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		_, err = DecodeSeedShare("not a share")
+		Expect(err).To(MatchError(ErrInvalid))
+	})
+
+	It("restores wallets from seed shares", func() {
+		mw := newTestMultiWallet("testnet3")
+		defer removeTestMultiWallet(mw)
+
+		const passphrase = "passphrase"
+		wallet, err := mw.CreateNewWallet("original", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+
+		sharesText, err := mw.SplitWalletSeed(wallet.ID, 2, 3, []byte(passphrase))
+		Expect(err).To(BeNil())
+		shares := strings.Split(sharesText, "\n")
+
+		_, err = mw.RestoreWallet("restored", shares[2], passphrase, PassphraseTypePass, 0, 0)
+		Expect(err).To(MatchError(ErrSeedSharesThresholdNotMet))
+
+		restored, err := mw.RestoreWallet("restored", shares[2]+"\n\n"+shares[0]+"\n", passphrase, PassphraseTypePass, 0, 0)
+		Expect(err).To(BeNil())
+
+		ctx := wallet.shutdownContext()
+		xpub, err := wallet.internal.MasterPubKey(ctx, 0)
+		Expect(err).To(BeNil())
+		restoredXPub, err := restored.internal.MasterPubKey(ctx, 0)
+		Expect(err).To(BeNil())
+		Expect(restoredXPub.String()).To(Equal(xpub.String()))
+	})
+})
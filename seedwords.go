@@ -0,0 +1,79 @@
+package dcrlibwallet
+
+import (
+	"strings"
+
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+// SeedWordCompletions returns the PGP words valid at the 0-based `position`
+// of a seed mnemonic that start with `prefix`, in word list order. Words at
+// even positions are from the even half of the alternating word list and words
+// at odd positions from the odd half, so the completions differ between
+// adjacent positions.
+func SeedWordCompletions(prefix string, position int32) []string {
+	if position < 0 {
+		return nil
+	}
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	completions := make([]string, 0)
+	for _, word := range pgpWordsAtPosition(int(position)) {
+		if strings.HasPrefix(word, prefix) {
+			completions = append(completions, word)
+		}
+	}
+	return completions
+}
+
+// ClosestSeedWords returns the PGP words valid at the 0-based `position` of a
+// seed mnemonic with the smallest edit distance to `word`, closest first, to
+// suggest in place of a misspelled word.
+func ClosestSeedWords(word string, position int32) []string {
+	if position < 0 {
+		return nil
+	}
+	return closestWords(strings.ToLower(strings.TrimSpace(word)), pgpWordsAtPosition(int(position)))
+}
+
+// VerifySeedWords checks the words of a PGP word list seed mnemonic one by one
+// where VerifySeed only tells whether the whole seed is valid. The words that
+// are not valid at their position are returned with the closest valid words.
+// If all the words are valid but the checksum word does not match the other
+// words, its index is returned as ChecksumIndex.
+func VerifySeedWords(seedMnemonic string) (*SeedWordsVerification, error) {
+	words := strings.Fields(strings.ToLower(seedMnemonic))
+	if len(words) == 0 {
+		return nil, errors.New(ErrEmptySeed)
+	}
+
+	verification := &SeedWordsVerification{
+		InvalidWords:  invalidPGPWords(words),
+		ChecksumIndex: -1,
+	}
+	if len(verification.InvalidWords) == 0 && !pgpChecksumMatches(words) {
+		verification.ChecksumIndex = int32(len(words) - 1)
+	}
+
+	verification.Valid = len(verification.InvalidWords) == 0 && verification.ChecksumIndex < 0 &&
+		VerifySeed(strings.Join(words, " "))
+	return verification, nil
+}
+
+// invalidPGPWords returns the words of a PGP word list mnemonic that are not
+// in the word list or not valid at their position, with the closest valid
+// words.
+func invalidPGPWords(words []string) []*SeedWordCorrection {
+	wordIndexes := pgpWordIndexes()
+	invalidWords := make([]*SeedWordCorrection, 0)
+	for i, word := range words {
+		if index, ok := wordIndexes[word]; !ok || index%2 != i%2 {
+			invalidWords = append(invalidWords, &SeedWordCorrection{
+				Index:       int32(i),
+				Word:        word,
+				Suggestions: closestWords(word, pgpWordsAtPosition(i)),
+			})
+		}
+	}
+	return invalidWords
+}
@@ -0,0 +1,68 @@
+package dcrlibwallet
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seed words", func() {
+	It("completes and corrects words valid at a position", func() {
+		evenCompletions := SeedWordCompletions(" AB", 0)
+		Expect(evenCompletions).To(ContainElement("absurd"))
+		Expect(SeedWordCompletions("ab", 1)).To(BeEmpty())
+		oddCompletions := SeedWordCompletions("ad", 1)
+		Expect(oddCompletions).To(ContainElement("adroitness"))
+		Expect(SeedWordCompletions("ad", 0)).NotTo(ContainElement("adroitness"))
+
+		for _, word := range evenCompletions {
+			Expect(word).To(HavePrefix("ab"))
+			Expect(oddCompletions).NotTo(ContainElement(word))
+			Expect(pgpWordsAtPosition(32)).To(ContainElement(word))
+		}
+
+		Expect(SeedWordCompletions("", 3)).To(HaveLen(len(PGPWordList()) / 2))
+		Expect(SeedWordCompletions("xyz", 0)).To(BeEmpty())
+		Expect(SeedWordCompletions("ab", -1)).To(BeEmpty())
+
+		Expect(ClosestSeedWords("absurdd", 0)[0]).To(Equal("absurd"))
+		Expect(ClosestSeedWords("absurd", 1)).NotTo(ContainElement("absurd"))
+	})
+
+	It("identifies invalid words and checksum failures by index", func() {
+		_, err := VerifySeedWords("")
+		Expect(err).To(MatchError(ErrEmptySeed))
+
+		seedMnemonic, err := GenerateSeed()
+		Expect(err).To(BeNil())
+		words := strings.Fields(strings.ToLower(seedMnemonic))
+
+		verification, err := VerifySeedWords(seedMnemonic)
+		Expect(err).To(BeNil())
+		Expect(verification.Valid).To(BeTrue())
+		Expect(verification.InvalidWords).To(BeEmpty())
+		Expect(verification.ChecksumIndex).To(Equal(int32(-1)))
+
+		misspelled := append([]string(nil), words...)
+		misspelled[3] = words[3] + "x"
+		verification, err = VerifySeedWords(strings.Join(misspelled, " "))
+		Expect(err).To(BeNil())
+		Expect(verification.Valid).To(BeFalse())
+		Expect(verification.InvalidWords).To(HaveLen(1))
+		Expect(verification.InvalidWords[0].Index).To(Equal(int32(3)))
+		Expect(verification.InvalidWords[0].Suggestions[0]).To(Equal(words[3]))
+		Expect(verification.ChecksumIndex).To(Equal(int32(-1)))
+
+		wrongChecksum := append([]string(nil), words...)
+		wrongChecksum[32] = pgpWordsAtPosition(32)[0]
+		if wrongChecksum[32] == words[32] {
+			wrongChecksum[32] = pgpWordsAtPosition(32)[1]
+		}
+		verification, err = VerifySeedWords(strings.Join(wrongChecksum, " "))
+		Expect(err).To(BeNil())
+		Expect(verification.Valid).To(BeFalse())
+		Expect(verification.InvalidWords).To(BeEmpty())
+		Expect(verification.ChecksumIndex).To(Equal(int32(32)))
+	})
+})
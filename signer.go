@@ -0,0 +1,137 @@
+package dcrlibwallet
+
+import (
+	"context"
+
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/hdkeychain/v2"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/decred/dcrwallet/wallet/v3/udb"
+	"github.com/planetdecred/dcrlibwallet/signer"
+)
+
+// SignerTransport exchanges the signing requests of a watch-only wallet with
+// an external signing device such as a hardware wallet. Clients implement it
+// over the channel the device is connected with, e.g. USB or Bluetooth. The
+// requests and responses are the JSON serialized signer.Request and
+// signer.Response.
+type SignerTransport interface {
+	Exchange(request []byte) ([]byte, error)
+}
+
+// SetSigner makes the watch-only wallet sign the transactions broadcast with
+// TxAuthor and the messages signed with SignMessage using `s` instead of its
+// own keys, which watch-only wallets do not have. The private passphrase
+// passed to those methods is then ignored. The keys of `s` must be the ones
+// the account extended public keys of the wallet were derived from.
+func (wallet *Wallet) SetSigner(s signer.Signer) error {
+	if !wallet.IsWatchingOnlyWallet() {
+		return errors.New(ErrInvalid)
+	}
+
+	wallet.externalSignerMu.Lock()
+	wallet.externalSigner = s
+	wallet.externalSignerMu.Unlock()
+	return nil
+}
+
+// SetSignerTransport makes the watch-only wallet sign with the device at the
+// other end of `transport`, see SetSigner.
+func (wallet *Wallet) SetSignerTransport(transport SignerTransport) error {
+	return wallet.SetSigner(signer.NewDevice(transport))
+}
+
+// RemoveSigner stops signing with the signer set with SetSigner.
+func (wallet *Wallet) RemoveSigner() {
+	wallet.externalSignerMu.Lock()
+	wallet.externalSigner = nil
+	wallet.externalSignerMu.Unlock()
+}
+
+// HasSigner returns true if the wallet signs with an external signer.
+func (wallet *Wallet) HasSigner() bool {
+	return wallet.currentSigner() != nil
+}
+
+// currentSigner returns the signer set with SetSigner, or nil if the wallet
+// signs with its own keys.
+func (wallet *Wallet) currentSigner() signer.Signer {
+	wallet.externalSignerMu.RLock()
+	defer wallet.externalSignerMu.RUnlock()
+	return wallet.externalSigner
+}
+
+// signWithExternalSigner has `s` sign the inputs of `msgTx` and checks that
+// the signatures it returned are valid.
+func (wallet *Wallet) signWithExternalSigner(ctx context.Context, s signer.Signer, msgTx *wire.MsgTx) error {
+	inputs := make([]*signer.Input, len(msgTx.TxIn))
+	for i, txIn := range msgTx.TxIn {
+		prevOut, err := wallet.internal.FetchOutput(ctx, &txIn.PreviousOutPoint)
+		if err != nil {
+			return translateError(err)
+		}
+
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(prevOut.Version, prevOut.PkScript, wallet.chainParams)
+		if err != nil {
+			return err
+		}
+		if len(addrs) != 1 {
+			return errors.E(errors.Invalid, "unsupported previous output script")
+		}
+
+		path, err := wallet.keyPath(ctx, addrs[0])
+		if err != nil {
+			return err
+		}
+
+		inputs[i] = &signer.Input{
+			Index:      i,
+			PrevScript: prevOut.PkScript,
+			Amount:     prevOut.Value,
+			Path:       path,
+		}
+	}
+
+	err := s.SignTransaction(msgTx, inputs)
+	if err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		vm, err := txscript.NewEngine(input.PrevScript, msgTx, input.Index, scriptVerifyFlags, 0, nil)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			return errors.E(errors.Invalid, errors.Errorf("input %d is not signed: %v", input.Index, err))
+		}
+	}
+
+	return nil
+}
+
+// keyPath returns the path of the key of `address` for the external signer.
+// Only the addresses of BIP0044 accounts can be signed for, the keys of
+// imported xpub accounts and addresses have no path known to the signer.
+func (wallet *Wallet) keyPath(ctx context.Context, address dcrutil.Address) (signer.KeyPath, error) {
+	addrInfo, err := wallet.internal.AddressInfo(ctx, address)
+	if err != nil {
+		return signer.KeyPath{}, translateError(err)
+	}
+
+	pubKeyAddrInfo, ok := addrInfo.(udb.ManagedPubKeyAddress)
+	if !ok || addrInfo.Imported() || addrInfo.Account() >= hdkeychain.HardenedKeyStart {
+		return signer.KeyPath{}, errors.New(ErrInvalidAddress)
+	}
+
+	path := signer.KeyPath{
+		Account: addrInfo.Account(),
+		Index:   pubKeyAddrInfo.Index(),
+	}
+	if addrInfo.Internal() {
+		path.Branch = udb.InternalBranch
+	}
+	return path, nil
+}
@@ -0,0 +1,129 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// Methods of the requests sent to a device.
+const (
+	MethodSignTransaction = "signtransaction"
+	MethodSignMessage     = "signmessage"
+)
+
+// Request is a request sent to a device, serialized as JSON.
+type Request struct {
+	Method string `json:"method"`
+
+	// Tx is the hex encoded transaction to sign and Inputs describes the
+	// inputs to sign, for MethodSignTransaction requests.
+	Tx     string   `json:"tx,omitempty"`
+	Inputs []*Input `json:"inputs,omitempty"`
+
+	// Path is the key to sign Message with, for MethodSignMessage requests.
+	Path    *KeyPath `json:"path,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// Response is the response of a device to a Request, serialized as JSON. Error
+// is set if the device did not sign, e.g. because the user rejected the
+// request on the device.
+type Response struct {
+	Tx        string `json:"tx,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Device is a Signer that forwards the signing requests to a device over a
+// Transport.
+type Device struct {
+	transport Transport
+}
+
+// NewDevice returns a Device that exchanges requests over `transport`.
+func NewDevice(transport Transport) *Device {
+	return &Device{transport: transport}
+}
+
+// SignTransaction sends the tx to the device and copies the signature scripts
+// of `inputs` from the signed tx it returns. The device may only add signature
+// scripts, a signed tx with a different prefix is rejected.
+func (d *Device) SignTransaction(tx *wire.MsgTx, inputs []*Input) error {
+	for _, input := range inputs {
+		if input.Index < 0 || input.Index >= len(tx.TxIn) {
+			return fmt.Errorf("invalid input index %d", input.Index)
+		}
+	}
+
+	var txBuf bytes.Buffer
+	txBuf.Grow(tx.SerializeSize())
+	if err := tx.Serialize(&txBuf); err != nil {
+		return err
+	}
+
+	resp, err := d.exchange(&Request{
+		Method: MethodSignTransaction,
+		Tx:     hex.EncodeToString(txBuf.Bytes()),
+		Inputs: inputs,
+	})
+	if err != nil {
+		return err
+	}
+
+	serializedTx, err := hex.DecodeString(resp.Tx)
+	if err != nil {
+		return err
+	}
+	var signedTx wire.MsgTx
+	if err = signedTx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+		return err
+	}
+
+	if signedTx.TxHash() != tx.TxHash() || len(signedTx.TxIn) != len(tx.TxIn) {
+		return errors.New("device returned a different transaction")
+	}
+
+	for _, input := range inputs {
+		tx.TxIn[input.Index].SignatureScript = signedTx.TxIn[input.Index].SignatureScript
+	}
+	return nil
+}
+
+// SignMessage asks the device to sign `message` with the key at `path`.
+func (d *Device) SignMessage(path KeyPath, message string) ([]byte, error) {
+	resp, err := d.exchange(&Request{
+		Method:  MethodSignMessage,
+		Path:    &path,
+		Message: message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+func (d *Device) exchange(req *Request) (*Response, error) {
+	serializedReq, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	serializedResp, err := d.transport.Exchange(serializedReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err = json.Unmarshal(serializedResp, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("device: %s", resp.Error)
+	}
+	return &resp, nil
+}
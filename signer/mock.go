@@ -0,0 +1,201 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/chaincfg/v2/chainec"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrec/secp256k1/v2"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/hdkeychain/v2"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrd/wire"
+)
+
+// errRejected is the error a MockDevice responds with when it is set to
+// reject requests, like a user declining a request on a real device.
+const errRejected = "request rejected"
+
+// MockDevice is a Transport to a software signing device deriving its keys
+// from a seed along the BIP0044 path m/44'/<SLIP0044 coin type>'/<account>'.
+// It answers the requests of a Device the way a hardware wallet would, which
+// makes it possible to test external signing without one.
+type MockDevice struct {
+	params      *chaincfg.Params
+	coinTypeKey *hdkeychain.ExtendedKey
+
+	mu       sync.Mutex
+	reject   bool
+	requests int
+}
+
+// NewMockDevice returns a MockDevice for `seed` on the network of `params`.
+func NewMockDevice(seed []byte, params *chaincfg.Params) (*MockDevice, error) {
+	masterKey, err := hdkeychain.NewMaster(seed, params)
+	if err != nil {
+		return nil, err
+	}
+	purposeKey, err := masterKey.Child(44 + hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return nil, err
+	}
+	coinTypeKey, err := purposeKey.Child(params.SLIP0044CoinType + hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MockDevice{
+		params:      params,
+		coinTypeKey: coinTypeKey,
+	}, nil
+}
+
+// AccountXPub returns the extended public key of `account`, from which a
+// watch-only wallet signing with the device is created.
+func (d *MockDevice) AccountXPub(account uint32) (string, error) {
+	accountKey, err := d.coinTypeKey.Child(account + hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return "", err
+	}
+	xpub, err := accountKey.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return xpub.String(), nil
+}
+
+// RejectRequests sets whether the device rejects the requests it receives.
+func (d *MockDevice) RejectRequests(reject bool) {
+	d.mu.Lock()
+	d.reject = reject
+	d.mu.Unlock()
+}
+
+// Requests returns the number of requests the device received.
+func (d *MockDevice) Requests() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requests
+}
+
+// Exchange handles a serialized Request and returns the serialized Response.
+func (d *MockDevice) Exchange(request []byte) ([]byte, error) {
+	var req Request
+	if err := json.Unmarshal(request, &req); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.requests++
+	reject := d.reject
+	d.mu.Unlock()
+
+	var resp Response
+	var err error
+	switch {
+	case reject:
+		err = errors.New(errRejected)
+	case req.Method == MethodSignTransaction:
+		resp.Tx, err = d.signTransaction(req.Tx, req.Inputs)
+	case req.Method == MethodSignMessage && req.Path != nil:
+		resp.Signature, err = d.signMessage(*req.Path, req.Message)
+	default:
+		err = errors.New("invalid request")
+	}
+	if err != nil {
+		resp = Response{Error: err.Error()}
+	}
+
+	return json.Marshal(&resp)
+}
+
+func (d *MockDevice) signTransaction(txHex string, inputs []*Input) (string, error) {
+	serializedTx, err := hex.DecodeString(txHex)
+	if err != nil {
+		return "", err
+	}
+	var tx wire.MsgTx
+	if err = tx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+		return "", err
+	}
+
+	for _, input := range inputs {
+		if input.Index < 0 || input.Index >= len(tx.TxIn) {
+			return "", errors.New("invalid input index")
+		}
+
+		privKey, address, err := d.key(input.Path)
+		if err != nil {
+			return "", err
+		}
+
+		// only sign for the address the path was derived for, like a hardware
+		// wallet showing the user what is being signed
+		getKey := txscript.KeyClosure(func(a dcrutil.Address) (chainec.PrivateKey, bool, error) {
+			if a.String() != address.String() {
+				return nil, false, errors.New("input is not paying the key at " + input.Path.String())
+			}
+			return privKey, true, nil
+		})
+
+		tx.TxIn[input.Index].SignatureScript, err = txscript.SignTxOutput(d.params, &tx, input.Index,
+			input.PrevScript, txscript.SigHashAll, getKey, nil, nil, dcrec.STEcdsaSecp256k1)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var signedTx bytes.Buffer
+	signedTx.Grow(tx.SerializeSize())
+	if err = tx.Serialize(&signedTx); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signedTx.Bytes()), nil
+}
+
+func (d *MockDevice) signMessage(path KeyPath, message string) ([]byte, error) {
+	privKey, _, err := d.key(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+	messageHash := chainhash.HashB(buf.Bytes())
+
+	return secp256k1.SignCompact(privKey, messageHash, true)
+}
+
+// key returns the private key at `path` and its P2PKH address.
+func (d *MockDevice) key(path KeyPath) (*secp256k1.PrivateKey, dcrutil.Address, error) {
+	accountKey, err := d.coinTypeKey.Child(path.Account + hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	branchKey, err := accountKey.Child(path.Branch)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := branchKey.Child(path.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	address, err := dcrutil.NewAddressPubKeyHash(dcrutil.Hash160(privKey.PubKey().SerializeCompressed()),
+		d.params, dcrec.STEcdsaSecp256k1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privKey, address, nil
+}
@@ -0,0 +1,64 @@
+// Package signer lets a watch-only wallet delegate the signing of transactions
+// and messages to an external device holding the private keys of the wallet,
+// such as a hardware wallet.
+//
+// A Signer is handed the unsigned transaction along with the previous output
+// scripts and key paths of its inputs and fills in the signature scripts.
+// Device implements Signer on top of a Transport that exchanges the requests
+// of this package with the device, e.g. over USB or Bluetooth, and MockDevice
+// is a software device that can be used in place of a real one.
+package signer
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// KeyPath identifies the key of an address by the BIP0044 account, branch
+// (0 for external and 1 for internal addresses) and index it is derived at.
+// The purpose and coin type levels are chosen by the device.
+type KeyPath struct {
+	Account uint32 `json:"account"`
+	Branch  uint32 `json:"branch"`
+	Index   uint32 `json:"index"`
+}
+
+// String returns the path in the BIP0032 notation, relative to the coin type
+// key of the device.
+func (p KeyPath) String() string {
+	return fmt.Sprintf("%d'/%d/%d", p.Account, p.Branch, p.Index)
+}
+
+// Input describes a transaction input to be signed.
+type Input struct {
+	// Index is the position of the input in the transaction.
+	Index int `json:"index"`
+
+	// PrevScript is the pkScript of the output spent by the input.
+	PrevScript []byte `json:"prev_script"`
+
+	// Amount is the value of the output spent by the input in atoms.
+	Amount int64 `json:"amount"`
+
+	// Path is the path of the key that signs the input.
+	Path KeyPath `json:"path"`
+}
+
+// Signer signs transactions and messages with keys it holds.
+type Signer interface {
+	// SignTransaction sets the signature scripts of the transaction inputs
+	// described by `inputs`, using the SigHashAll hash type.
+	SignTransaction(tx *wire.MsgTx, inputs []*Input) error
+
+	// SignMessage returns the compact signature of `message` made with the key
+	// at `path`, in the format checked by dcrwallet's VerifyMessage.
+	SignMessage(path KeyPath, message string) ([]byte, error)
+}
+
+// Transport carries the serialized requests of a Device to the signing device
+// and returns its responses. Implementations are free to use any channel and
+// framing as long as each request gets exactly one response.
+type Transport interface {
+	Exchange(request []byte) ([]byte, error)
+}
@@ -0,0 +1,580 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/chaincfg/v2/chainec"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrutil/v2"
+	"github.com/decred/dcrd/txscript/v2"
+	"github.com/decred/dcrdata/txhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/planetdecred/dcrlibwallet/internal/simnet"
+	"github.com/planetdecred/dcrlibwallet/signer"
+)
+
+// txEventRecorder records the tx events published by a MultiWallet.
+type txEventRecorder struct {
+	mu     sync.Mutex
+	events []*TxEvent
+}
+
+func (r *txEventRecorder) OnTxEvent(event *TxEvent) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *txEventRecorder) OnBlockEvent(event *BlockEvent) {}
+
+// eventTypes returns the types of the events recorded for the tx with `hash`.
+func (r *txEventRecorder) eventTypes(hash string) []int32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var types []int32
+	for _, event := range r.events {
+		if event.Transaction.Hash == hash {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+var _ = Describe("Simnet", func() {
+	const (
+		passphrase  = "simnet"
+		syncTimeout = 30 * time.Second
+	)
+
+	var (
+		peer     *simnet.Peer
+		mw       *MultiWallet
+		wallet   *Wallet
+		recorder *txEventRecorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		peer, err = simnet.NewPeer(chaincfg.SimNetParams())
+		Expect(err).To(BeNil())
+
+		mw = newTestMultiWallet("simnet")
+		mw.SetStringConfigValueForKey(SpvPersistentPeerAddressesConfigKey, peer.Addr())
+
+		wallet, err = mw.CreateNewWallet("simnet", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+
+		recorder = &txEventRecorder{}
+		Expect(mw.AddTxAndBlockEventListener(recorder, "simnet")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+		peer.Close()
+	})
+
+	mineBlocks := func(n int) {
+		_, err := peer.MineBlocks(n)
+		Expect(err).To(BeNil())
+	}
+
+	fundWallet := func(amount dcrutil.Amount) string {
+		address, err := wallet.CurrentAddress(0)
+		Expect(err).To(BeNil())
+
+		tx, err := peer.SendToAddress(address, amount)
+		Expect(err).To(BeNil())
+		return tx.TxHash().String()
+	}
+
+	sync := func() {
+		Expect(mw.SpvSync()).To(Succeed())
+		Eventually(mw.IsSynced, syncTimeout).Should(BeTrue())
+	}
+
+	waitForTip := func() {
+		_, tipHeight := peer.Chain().Tip()
+		Eventually(wallet.GetBestBlock, syncTimeout).Should(Equal(tipHeight))
+	}
+
+	// indexedBlockHeight returns the block height of the tx with `hash` in the
+	// tx index, or a height below BlockHeightInvalid if it is not indexed.
+	indexedBlockHeight := func(hash string) func() int32 {
+		return func() int32 {
+			var tx Transaction
+			if err := wallet.txDB.FindOne("Hash", hash, &tx); err != nil {
+				return BlockHeightInvalid - 1
+			}
+			return tx.BlockHeight
+		}
+	}
+
+	inMempool := func(hash string) func() bool {
+		return func() bool {
+			txHash, err := chainhash.NewHashFromStr(hash)
+			Expect(err).To(BeNil())
+			_, ok := peer.MempoolTx(txHash)
+			return ok
+		}
+	}
+
+	It("syncs headers and rescans for transactions mined before sync", func() {
+		txHash := fundWallet(10 * dcrutil.AtomsPerCoin)
+		mineBlocks(5)
+
+		sync()
+		waitForTip()
+
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(int32(1)))
+		balance, err := wallet.GetAccountBalance(0)
+		Expect(err).To(BeNil())
+		Expect(balance.Total).To(Equal(int64(10 * dcrutil.AtomsPerCoin)))
+	})
+
+	It("receives transactions relayed and blocks announced after sync", func() {
+		mineBlocks(2)
+		sync()
+
+		txHash := fundWallet(5 * dcrutil.AtomsPerCoin)
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(BlockHeightInvalid))
+
+		mineBlocks(1)
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(int32(3)))
+		Expect(recorder.eventTypes(txHash)).To(ContainElement(TxEventReceived))
+	})
+
+	It("publishes transactions to the peer", func() {
+		fundWallet(10 * dcrutil.AtomsPerCoin)
+		mineBlocks(5)
+		sync()
+		waitForTip()
+
+		destination, err := wallet.NextAddress(0)
+		Expect(err).To(BeNil())
+
+		txAuthor := mw.NewUnsignedTx(wallet, 0)
+		txAuthor.AddSendDestination(destination, 2*dcrutil.AtomsPerCoin, false)
+		hash, err := txAuthor.Broadcast([]byte(passphrase))
+		Expect(err).To(BeNil())
+
+		txHash, err := chainhash.NewHash(hash)
+		Expect(err).To(BeNil())
+		Eventually(inMempool(txHash.String()), syncTimeout).Should(BeTrue())
+
+		mineBlocks(1)
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash.String()), syncTimeout).Should(Equal(int32(6)))
+	})
+
+	It("rolls back transactions of blocks removed by a reorg", func() {
+		mineBlocks(2)
+		sync()
+
+		txHash := fundWallet(5 * dcrutil.AtomsPerCoin)
+		mineBlocks(1)
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(int32(3)))
+
+		_, err := peer.Reorg(2, 2)
+		Expect(err).To(BeNil())
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(BlockHeightInvalid))
+		Expect(recorder.eventTypes(txHash)).To(ContainElement(TxEventRemovedByReorg))
+
+		// the peer returned the transaction to its mempool, mine it again
+		mineBlocks(1)
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(int32(5)))
+	})
+
+	It("purchases tickets", func() {
+		fundWallet(10 * dcrutil.AtomsPerCoin)
+		mineBlocks(5)
+		sync()
+		waitForTip()
+
+		ticketHashes, err := wallet.PurchaseTickets(context.Background(), &PurchaseTicketsRequest{
+			RequiredConfirmations: 1,
+			NumTickets:            2,
+			Passphrase:            []byte(passphrase),
+		}, "")
+		Expect(err).To(BeNil())
+		Expect(ticketHashes).To(HaveLen(2))
+		for _, ticketHash := range ticketHashes {
+			Eventually(inMempool(ticketHash), syncTimeout).Should(BeTrue())
+		}
+
+		mineBlocks(1)
+		waitForTip()
+		Eventually(func() uint32 {
+			stakeInfo, err := wallet.StakeInfo()
+			Expect(err).To(BeNil())
+			return stakeInfo.Immature
+		}, syncTimeout).Should(Equal(uint32(2)))
+	})
+
+	Context("with a 2-of-2 multisig account", func() {
+		var (
+			cosigner                    *Wallet
+			ownAccount, cosignerAccount *MultisigAccount
+		)
+
+		BeforeEach(func() {
+			var err error
+			cosigner, err = mw.CreateNewWallet("cosigner", passphrase, PassphraseTypePass)
+			Expect(err).To(BeNil())
+
+			// account xpubs are final once the wallets synced
+			mineBlocks(2)
+			sync()
+			Eventually(cosigner.IsSynced, syncTimeout).Should(BeTrue())
+
+			ownXPub, err := wallet.AccountXPub(0, []byte(passphrase))
+			Expect(err).To(BeNil())
+			cosignerXPub, err := cosigner.AccountXPub(0, []byte(passphrase))
+			Expect(err).To(BeNil())
+
+			ownAccount, err = mw.CreateMultisigAccount(wallet.ID, "shared", 2,
+				`["`+cosignerXPub+`"]`, 0, []byte(passphrase))
+			Expect(err).To(BeNil())
+
+			// the cosigner passes both xpubs, in a different order
+			cosignerAccount, err = mw.CreateMultisigAccountRaw(cosigner.ID, "shared", 2,
+				[]string{cosignerXPub, ownXPub}, 0, []byte(passphrase))
+			Expect(err).To(BeNil())
+		})
+
+		multisigBalance := func(multisigAccountID int) func() int64 {
+			return func() int64 {
+				balance, err := mw.MultisigAccountBalance(multisigAccountID)
+				Expect(err).To(BeNil())
+				return balance.Total
+			}
+		}
+
+		It("derives the same addresses for all cosigners", func() {
+			Expect(ownAccount.XPubs).To(Equal(cosignerAccount.XPubs))
+
+			for i := 0; i < 3; i++ {
+				ownAddress, err := mw.NextMultisigAddress(ownAccount.ID, []byte(passphrase))
+				Expect(err).To(BeNil())
+				cosignerAddress, err := mw.NextMultisigAddress(cosignerAccount.ID, []byte(passphrase))
+				Expect(err).To(BeNil())
+				Expect(ownAddress).To(Equal(cosignerAddress))
+			}
+
+			_, err := mw.CreateMultisigAccountRaw(wallet.ID, "shared", 1, nil, 0, []byte(passphrase))
+			Expect(err).To(MatchError(ErrExist))
+			_, err = mw.CreateMultisigAccountRaw(wallet.ID, "single", 1, nil, 0, []byte(passphrase))
+			Expect(err).To(MatchError(ErrInvalid))
+			_, err = mw.CreateMultisigAccount(wallet.ID, "single", 1, "not json", 0, []byte(passphrase))
+			Expect(err).To(MatchError(ErrInvalid))
+
+			accountsJSON, err := mw.MultisigAccounts(wallet.ID)
+			Expect(err).To(BeNil())
+			var accounts []*MultisigAccount
+			Expect(json.Unmarshal([]byte(accountsJSON), &accounts)).To(Succeed())
+			Expect(accounts).To(HaveLen(1))
+			Expect(accounts[0].ID).To(Equal(ownAccount.ID))
+			Expect(accounts[0].NextAddressIndex).To(Equal(uint32(3)))
+		})
+
+		It("spends outputs once both cosigners signed", func() {
+			address, err := mw.CurrentMultisigAddress(ownAccount.ID)
+			Expect(err).To(BeNil())
+			_, err = peer.SendToAddress(address, 10*dcrutil.AtomsPerCoin)
+			Expect(err).To(BeNil())
+			mineBlocks(2)
+			waitForTip()
+			Eventually(multisigBalance(ownAccount.ID), syncTimeout).Should(Equal(int64(10 * dcrutil.AtomsPerCoin)))
+			Eventually(multisigBalance(cosignerAccount.ID), syncTimeout).Should(Equal(int64(10 * dcrutil.AtomsPerCoin)))
+
+			destination, err := wallet.CurrentAddress(0)
+			Expect(err).To(BeNil())
+			txAuthor, err := mw.NewUnsignedMultisigTx(ownAccount.ID)
+			Expect(err).To(BeNil())
+			txAuthor.AddSendDestination(destination, 3*dcrutil.AtomsPerCoin, false)
+
+			unsignedTx, err := txAuthor.UnsignedMultisigTx()
+			Expect(err).To(BeNil())
+			Expect(unsignedTx.Signatures).To(Equal(int32(0)))
+
+			// change goes to a change address, not back to the receiving address
+			msgTx, err := txhelpers.MsgTxFromHex(unsignedTx.Hex)
+			Expect(err).To(BeNil())
+			Expect(msgTx.TxOut).To(HaveLen(2))
+			for _, output := range msgTx.TxOut {
+				_, addresses, _, err := txscript.ExtractPkScriptAddrs(output.Version, output.PkScript, wallet.chainParams)
+				Expect(err).To(BeNil())
+				Expect(addresses[0].Address()).NotTo(Equal(address))
+			}
+			_, err = mw.BroadcastMultisigTx(ownAccount.ID, unsignedTx.Hex)
+			Expect(err).To(MatchError(ErrMultisigThresholdNotMet))
+
+			// both cosigners sign the unsigned tx, then the signatures are combined
+			ownSigned, err := mw.SignMultisigTx(ownAccount.ID, unsignedTx.Hex, []byte(passphrase))
+			Expect(err).To(BeNil())
+			Expect(ownSigned.Signatures).To(Equal(int32(1)))
+			Expect(ownSigned.Complete).To(BeFalse())
+			cosignerSigned, err := mw.SignMultisigTx(cosignerAccount.ID, unsignedTx.Hex, []byte(passphrase))
+			Expect(err).To(BeNil())
+			Expect(cosignerSigned.Signatures).To(Equal(int32(1)))
+
+			signedTxs, err := json.Marshal([]string{ownSigned.Hex, cosignerSigned.Hex})
+			Expect(err).To(BeNil())
+			combined, err := mw.CombineMultisigTxs(ownAccount.ID, string(signedTxs))
+			Expect(err).To(BeNil())
+			Expect(combined.Complete).To(BeTrue())
+			Expect(combined.Hash).To(Equal(unsignedTx.Hash))
+
+			// signing on top of the other cosigner's signature completes the tx too
+			signedInTurn, err := mw.SignMultisigTx(cosignerAccount.ID, ownSigned.Hex, []byte(passphrase))
+			Expect(err).To(BeNil())
+			Expect(signedInTurn.Complete).To(BeTrue())
+
+			txHash, err := mw.BroadcastMultisigTx(ownAccount.ID, combined.Hex)
+			Expect(err).To(BeNil())
+			Expect(txHash).To(Equal(unsignedTx.Hash))
+			Eventually(inMempool(txHash), syncTimeout).Should(BeTrue())
+
+			mineBlocks(1)
+			waitForTip()
+			Eventually(indexedBlockHeight(txHash), syncTimeout).Should(Equal(int32(5)))
+			Eventually(multisigBalance(cosignerAccount.ID), syncTimeout).Should(BeNumerically("<", 7*dcrutil.AtomsPerCoin))
+			Expect(multisigBalance(cosignerAccount.ID)()).To(BeNumerically(">", 7*dcrutil.AtomsPerCoin-dcrutil.AtomsPerCent))
+
+			// the change output is signed with the keys of the change branch
+			Eventually(multisigBalance(ownAccount.ID), syncTimeout).Should(BeNumerically("<", 7*dcrutil.AtomsPerCoin))
+			mineBlocks(1)
+			waitForTip()
+			txAuthor, err = mw.NewUnsignedMultisigTx(ownAccount.ID)
+			Expect(err).To(BeNil())
+			txAuthor.AddSendDestination(destination, 0, true)
+			unsignedTx, err = txAuthor.UnsignedMultisigTx()
+			Expect(err).To(BeNil())
+			ownSigned, err = mw.SignMultisigTx(ownAccount.ID, unsignedTx.Hex, []byte(passphrase))
+			Expect(err).To(BeNil())
+			signedInTurn, err = mw.SignMultisigTx(cosignerAccount.ID, ownSigned.Hex, []byte(passphrase))
+			Expect(err).To(BeNil())
+			Expect(signedInTurn.Complete).To(BeTrue())
+			txHash, err = mw.BroadcastMultisigTx(ownAccount.ID, signedInTurn.Hex)
+			Expect(err).To(BeNil())
+			Eventually(inMempool(txHash), syncTimeout).Should(BeTrue())
+		})
+	})
+
+	It("watches account xpubs exported from a spending wallet", func() {
+		mineBlocks(2)
+		sync()
+		waitForTip()
+
+		_, err := wallet.AccountXPub(0, []byte("wrong passphrase"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+
+		savingsAccount, err := wallet.NextAccount("savings", []byte(passphrase))
+		Expect(err).To(BeNil())
+		defaultXPub, err := wallet.AccountXPub(0, []byte(passphrase))
+		Expect(err).To(BeNil())
+		savingsXPub, err := wallet.AccountXPub(savingsAccount, []byte(passphrase))
+		Expect(err).To(BeNil())
+
+		mw.CancelSync()
+		_, err = mw.CreateMultiAccountWatchOnlyWallet("watch-only", `[null]`)
+		Expect(err).To(MatchError(ErrInvalid))
+
+		accounts, err := json.Marshal([]*WatchOnlyAccount{
+			{Name: "spending", ExtendedPublicKey: defaultXPub},
+			{Name: "savings", ExtendedPublicKey: savingsXPub},
+		})
+		Expect(err).To(BeNil())
+		watchOnlyWallet, err := mw.CreateMultiAccountWatchOnlyWallet("watch-only", string(accounts))
+		Expect(err).To(BeNil())
+		Expect(watchOnlyWallet.IsWatchingOnlyWallet()).To(BeTrue())
+
+		watchedSavingsAccount, err := watchOnlyWallet.AccountNumber("savings")
+		Expect(err).To(BeNil())
+		watchedXPub, err := watchOnlyWallet.AccountXPub(int32(watchedSavingsAccount), nil)
+		Expect(err).To(BeNil())
+		Expect(watchedXPub).To(Equal(savingsXPub))
+
+		address, err := wallet.CurrentAddress(savingsAccount)
+		Expect(err).To(BeNil())
+		watchedAddress, err := watchOnlyWallet.CurrentAddress(int32(watchedSavingsAccount))
+		Expect(err).To(BeNil())
+		Expect(watchedAddress).To(Equal(address))
+
+		_, err = peer.SendToAddress(address, 4*dcrutil.AtomsPerCoin)
+		Expect(err).To(BeNil())
+		mineBlocks(1)
+		sync()
+		Eventually(watchOnlyWallet.GetBestBlock, syncTimeout).Should(Equal(int32(3)))
+
+		Eventually(func() int64 {
+			balance, err := watchOnlyWallet.GetAccountBalance(int32(watchedSavingsAccount))
+			Expect(err).To(BeNil())
+			return balance.Total
+		}, syncTimeout).Should(Equal(int64(4 * dcrutil.AtomsPerCoin)))
+	})
+
+	It("signs watch-only wallet transactions and messages with an external signer", func() {
+		device, err := signer.NewMockDevice(bytes.Repeat([]byte{0x38}, 32), chaincfg.SimNetParams())
+		Expect(err).To(BeNil())
+		xpub, err := device.AccountXPub(0)
+		Expect(err).To(BeNil())
+
+		Expect(wallet.SetSignerTransport(device)).To(MatchError(ErrInvalid))
+		hardwareWallet, err := mw.CreateWatchOnlyWallet("hardware", xpub)
+		Expect(err).To(BeNil())
+		Expect(hardwareWallet.SetSignerTransport(device)).To(Succeed())
+
+		address, err := hardwareWallet.CurrentAddress(0)
+		Expect(err).To(BeNil())
+		_, err = peer.SendToAddress(address, 10*dcrutil.AtomsPerCoin)
+		Expect(err).To(BeNil())
+		mineBlocks(2)
+		sync()
+		Eventually(hardwareWallet.GetBestBlock, syncTimeout).Should(Equal(int32(2)))
+
+		destination, err := wallet.CurrentAddress(0)
+		Expect(err).To(BeNil())
+		txAuthor := mw.NewUnsignedTx(hardwareWallet, 0)
+		txAuthor.AddSendDestination(destination, 2*dcrutil.AtomsPerCoin, false)
+		hash, err := txAuthor.Broadcast(nil)
+		Expect(err).To(BeNil())
+		Expect(device.Requests()).To(Equal(1))
+
+		txHash, err := chainhash.NewHash(hash)
+		Expect(err).To(BeNil())
+		Eventually(inMempool(txHash.String()), syncTimeout).Should(BeTrue())
+		mineBlocks(1)
+		waitForTip()
+		Eventually(indexedBlockHeight(txHash.String()), syncTimeout).Should(Equal(int32(3)))
+
+		signature, err := hardwareWallet.SignMessage(nil, address, "signed on device")
+		Expect(err).To(BeNil())
+		valid, err := mw.VerifyMessage(address, "signed on device", EncodeBase64(signature))
+		Expect(err).To(BeNil())
+		Expect(valid).To(BeTrue())
+
+		device.RejectRequests(true)
+		_, err = hardwareWallet.SignMessage(nil, address, "rejected on device")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("with a private key", func() {
+		var wif string
+
+		BeforeEach(func() {
+			privKey, _ := chainec.Secp256k1.PrivKeyFromBytes(bytes.Repeat([]byte{0x39}, 32))
+			wif = dcrutil.NewWIF(privKey, chaincfg.SimNetParams().PrivateKeyID, dcrec.STEcdsaSecp256k1).String()
+		})
+
+		It("imports the key and rescans for its transactions", func() {
+			sweepTx, err := mw.NewSweepTx(wallet, 0, wif)
+			Expect(err).To(BeNil())
+			_, err = peer.SendToAddress(sweepTx.Address(), 5*dcrutil.AtomsPerCoin)
+			Expect(err).To(BeNil())
+			mineBlocks(2)
+			sync()
+			waitForTip()
+
+			_, err = wallet.ImportPrivateKey(wif, []byte("wrong passphrase"), -1)
+			Expect(err).To(MatchError(ErrInvalidPassphrase))
+			address, err := wallet.ImportPrivateKey(wif, []byte(passphrase), 0)
+			Expect(err).To(BeNil())
+			Expect(address).To(Equal(sweepTx.Address()))
+			_, err = wallet.ImportPrivateKey(wif, []byte(passphrase), -1)
+			Expect(err).To(MatchError(ErrExist))
+
+			accounts, err := wallet.GetAccountsRaw()
+			Expect(err).To(BeNil())
+			var importedBalance int64
+			for _, account := range accounts.Acc {
+				if account.ImportedKeyCount > 0 {
+					importedBalance += account.TotalBalance
+				}
+			}
+			Expect(importedBalance).To(Equal(int64(5 * dcrutil.AtomsPerCoin)))
+		})
+
+		It("sweeps the outputs of the key to the wallet", func() {
+			mineBlocks(2)
+			sync()
+			waitForTip()
+
+			sweepTx, err := mw.NewSweepTx(wallet, 0, wif)
+			Expect(err).To(BeNil())
+			Expect(sweepTx.Scan(0)).To(Succeed())
+			_, err = sweepTx.Broadcast()
+			Expect(err).To(MatchError(ErrInsufficientBalance))
+
+			for _, amount := range []dcrutil.Amount{3 * dcrutil.AtomsPerCoin, 4 * dcrutil.AtomsPerCoin} {
+				_, err = peer.SendToAddress(sweepTx.Address(), amount)
+				Expect(err).To(BeNil())
+			}
+			mineBlocks(1)
+			waitForTip()
+
+			Expect(sweepTx.Scan(0)).To(Succeed())
+			Expect(sweepTx.TotalAmount().AtomValue).To(Equal(int64(7 * dcrutil.AtomsPerCoin)))
+			feeAndSize, err := sweepTx.EstimateFeeAndSize()
+			Expect(err).To(BeNil())
+
+			hash, err := sweepTx.Broadcast()
+			Expect(err).To(BeNil())
+			txHash, err := chainhash.NewHash(hash)
+			Expect(err).To(BeNil())
+			Eventually(inMempool(txHash.String()), syncTimeout).Should(BeTrue())
+			mineBlocks(1)
+			waitForTip()
+
+			Eventually(func() int64 {
+				balance, err := wallet.GetAccountBalance(0)
+				Expect(err).To(BeNil())
+				return balance.Total
+			}, syncTimeout).Should(Equal(7*dcrutil.AtomsPerCoin - feeAndSize.Fee.AtomValue))
+
+			// the swept outputs are spent
+			Expect(sweepTx.Scan(0)).To(Succeed())
+			Expect(sweepTx.TotalAmount().AtomValue).To(BeZero())
+		})
+	})
+
+	It("skips blocks before the birthday of restored wallets", func() {
+		fundWallet(3 * dcrutil.AtomsPerCoin)
+		mineBlocks(2)
+		fundWallet(5 * dcrutil.AtomsPerCoin)
+		mineBlocks(2)
+
+		seed, err := wallet.DecryptSeed([]byte(passphrase))
+		Expect(err).To(BeNil())
+		_, err = mw.RestoreWallet("restored", seed, passphrase, PassphraseTypePass, time.Now().Add(time.Hour).Unix(), 0)
+		Expect(err).To(MatchError(ErrInvalid))
+		restored, err := mw.RestoreWallet("restored", seed, passphrase, PassphraseTypePass, 0, 3)
+		Expect(err).To(BeNil())
+		Expect(restored.BirthdayHeight).To(Equal(int32(3)))
+
+		sync()
+		waitForTip()
+		Eventually(restored.GetBestBlock, syncTimeout).Should(Equal(int32(4)))
+
+		restoredBalance := func() int64 {
+			balance, err := restored.GetAccountBalance(0)
+			Expect(err).To(BeNil())
+			return balance.Total
+		}
+		Expect(restoredBalance()).To(Equal(int64(5 * dcrutil.AtomsPerCoin)))
+
+		Expect(mw.RescanBlocksFromHeight(restored.ID, 0)).To(Succeed())
+		Eventually(restoredBalance, syncTimeout).Should(Equal(int64(8 * dcrutil.AtomsPerCoin)))
+	})
+})
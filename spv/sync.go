@@ -73,8 +73,25 @@ type Syncer struct {
 
 	// Holds all potential callbacks used to notify clients
 	notifications *Notifications
+
+	// birthdays are the wallet birthdays set with SetBirthdays.
+	birthdays map[int]*Birthday
+}
+
+// Birthday is the time or block height before which a wallet is known to have
+// no transactions. Address discovery and the rescan of a wallet that has not
+// been synced yet start from its birthday block instead of the genesis block.
+// Height takes precedence over Time if both are set.
+type Birthday struct {
+	Time   time.Time
+	Height int32
 }
 
+// birthdayTimeMargin is subtracted from the time of a birthday to find its
+// block, as block timestamps may be off by some time and the birthday may be
+// given as a date without a time of day.
+const birthdayTimeMargin = 48 * time.Hour
+
 // Notifications struct to contain all of the upcoming callbacks that will
 // be used to update the rpc streams for syncing.
 type Notifications struct {
@@ -93,6 +110,10 @@ type Notifications struct {
 	RescanProgress               func(walletID int, rescannedThrough int32)
 	RescanFinished               func(walletID int)
 
+	// BirthdayBlockFound is called with the height of the birthday block of
+	// a wallet once it was found from the birthday time.
+	BirthdayBlockFound func(walletID int, height int32)
+
 	// MempoolTxs is called whenever new relevant unmined transactions are
 	// observed and saved.
 	MempoolTxs func(walletID int, txs []*wire.MsgTx)
@@ -138,6 +159,11 @@ func (s *Syncer) SetPersistentPeers(peers []string) {
 	s.persistentPeers = peers
 }
 
+// SetBirthdays sets the birthdays of the wallets, mapped by wallet ID.
+func (s *Syncer) SetBirthdays(birthdays map[int]*Birthday) {
+	s.birthdays = birthdays
+}
+
 // SetNotifications sets the possible various callbacks that are used
 // to notify interested parties to the syncing progress.
 func (s *Syncer) SetNotifications(ntfns *Notifications) {
@@ -243,6 +269,12 @@ func (s *Syncer) rescanFinished(walletID int) {
 	}
 }
 
+func (s *Syncer) birthdayBlockFound(walletID int, height int32) {
+	if s.notifications != nil && s.notifications.BirthdayBlockFound != nil {
+		s.notifications.BirthdayBlockFound(walletID, height)
+	}
+}
+
 func (s *Syncer) mempoolTxs(walletID int, txs []*wire.MsgTx) {
 	if s.notifications != nil && s.notifications.MempoolTxs != nil {
 		s.notifications.MempoolTxs(walletID, txs)
@@ -1306,6 +1338,25 @@ func (s *Syncer) startupSync(ctx context.Context, rp *p2p.RemotePeer) error {
 				// check to see if it was previously synced
 				s.unsynced(walletID)
 
+				rescanBlock, err := w.BlockHeader(ctx, rescanPoint)
+				if err != nil {
+					return err
+				}
+				rescanHeight := int32(rescanBlock.Height)
+
+				// blocks before the birthday of the wallet are skipped
+				if birthday := s.birthdays[walletID]; birthday != nil {
+					birthdayHash, birthdayHeight, err := s.birthdayBlock(ctx, walletID, w, birthday)
+					if err != nil {
+						return err
+					}
+					if birthdayHeight > rescanHeight {
+						log.Infof("[%d] Skipping blocks before birthday block %v height %d",
+							walletID, birthdayHash, birthdayHeight)
+						rescanPoint, rescanHeight = birthdayHash, birthdayHeight
+					}
+				}
+
 				s.discoverAddressesStart(walletID)
 				err = w.DiscoverActiveAddresses(ctx, rp, rescanPoint, !w.Locked())
 				if err != nil {
@@ -1322,12 +1373,8 @@ func (s *Syncer) startupSync(ctx context.Context, rp *p2p.RemotePeer) error {
 
 				s.rescanStart(walletID)
 
-				rescanBlock, err := w.BlockHeader(ctx, rescanPoint)
-				if err != nil {
-					return err
-				}
 				progress := make(chan wallet.RescanProgress, 1)
-				go w.RescanProgressFromHeight(ctx, walletBackend, int32(rescanBlock.Height), progress)
+				go w.RescanProgressFromHeight(ctx, walletBackend, rescanHeight, progress)
 
 				for p := range progress {
 					if p.Err != nil {
@@ -1367,3 +1414,42 @@ func (s *Syncer) startupSync(ctx context.Context, rp *p2p.RemotePeer) error {
 
 	return nil
 }
+
+// birthdayBlock returns the main chain block of `birthday`, the first block
+// mined after the birthday time less birthdayTimeMargin if the height of the
+// birthday is not set. The block is capped to the main chain tip of the wallet.
+func (s *Syncer) birthdayBlock(ctx context.Context, walletID int, w *wallet.Wallet,
+	birthday *Birthday) (*chainhash.Hash, int32, error) {
+
+	_, tipHeight := w.MainChainTip(ctx)
+	height := birthday.Height
+	if height <= 0 && !birthday.Time.IsZero() {
+		birthdayTime := birthday.Time.Add(-birthdayTimeMargin).Unix()
+
+		// binary search for the first block mined after birthdayTime
+		low, high := int32(0), tipHeight
+		for low < high {
+			mid := low + (high-low)/2
+			blockInfo, err := w.BlockInfo(ctx, wallet.NewBlockIdentifierFromHeight(mid))
+			if err != nil {
+				return nil, 0, err
+			}
+			if blockInfo.Timestamp < birthdayTime {
+				low = mid + 1
+			} else {
+				high = mid
+			}
+		}
+		height = low
+		s.birthdayBlockFound(walletID, height)
+	}
+	if height > tipHeight {
+		height = tipHeight
+	}
+
+	blockInfo, err := w.BlockInfo(ctx, wallet.NewBlockIdentifierFromHeight(height))
+	if err != nil {
+		return nil, 0, err
+	}
+	return &blockInfo.Hash, height, nil
+}
@@ -22,6 +22,7 @@ type syncData struct {
 
 	synced       bool
 	syncing      bool
+	syncContext  context.Context
 	cancelSync   context.CancelFunc
 	cancelRescan context.CancelFunc
 	syncCanceled chan bool
@@ -216,6 +217,7 @@ func (mw *MultiWallet) SpvSync() error {
 
 	syncer := spv.NewSyncer(wallets, lp)
 	syncer.SetNotifications(mw.spvSyncNotificationCallbacks())
+	syncer.SetBirthdays(mw.walletBirthdays())
 	if len(validPeerAddresses) > 0 {
 		syncer.SetPersistentPeers(validPeerAddresses)
 	}
@@ -230,6 +232,7 @@ func (mw *MultiWallet) SpvSync() error {
 	restartSyncRequested = mw.syncData.restartSyncRequested
 	mw.syncData.restartSyncRequested = false
 	mw.syncData.syncing = true
+	mw.syncData.syncContext = ctx
 	mw.syncData.cancelSync = cancel
 	mw.syncData.mu.Unlock()
 
@@ -4,6 +4,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/decred/dcrd/wire"
 	"github.com/planetdecred/dcrlibwallet/spv"
 	"golang.org/x/sync/errgroup"
 )
@@ -28,6 +29,8 @@ func (mw *MultiWallet) spvSyncNotificationCallbacks() *spv.Notifications {
 		RescanStarted:                mw.rescanStarted,
 		RescanProgress:               mw.rescanProgress,
 		RescanFinished:               mw.rescanFinished,
+		BirthdayBlockFound:           mw.birthdayBlockFound,
+		TipChanged:                   mw.tipChanged,
 	}
 }
 
@@ -474,6 +477,15 @@ func (mw *MultiWallet) rescanFinished(walletID int) {
 	mw.publishHeadersRescanProgress()
 }
 
+// Tip Changed Callback
+
+func (mw *MultiWallet) tipChanged(tip *wire.BlockHeader, reorgDepth int32, txs []*wire.MsgTx) {
+	err := mw.recordTicketPrice(tip)
+	if err != nil {
+		log.Errorf("Error recording ticket price for block %d: %v", tip.Height, err)
+	}
+}
+
 func (mw *MultiWallet) publishDebugInfo(debugInfo *DebugInfo) {
 	for _, syncProgressListener := range mw.syncProgressListeners() {
 		syncProgressListener.Debug(debugInfo)
@@ -518,6 +530,7 @@ func (mw *MultiWallet) resetSyncData() {
 	mw.syncData.mu.Lock()
 	mw.syncData.syncing = false
 	mw.syncData.synced = false
+	mw.syncData.syncContext = nil
 	mw.syncData.cancelSync = nil
 	mw.syncData.activeSyncData = nil
 	mw.syncData.mu.Unlock()
@@ -569,6 +582,11 @@ func (mw *MultiWallet) synced(walletID int, synced bool) {
 				log.Errorf("Tx Index Error: %v", err)
 			}
 
+			err = mw.updateTicketPriceHistory()
+			if err != nil {
+				log.Errorf("Ticket price history update error: %v", err)
+			}
+
 			for _, syncProgressListener := range mw.syncProgressListeners() {
 				if synced {
 					syncProgressListener.OnSyncCompleted()
@@ -240,9 +240,9 @@ func (wallet *Wallet) PurchaseTickets(ctx context.Context, request *PurchaseTick
 	defer func() {
 		lock <- time.Time{} // send matters, not the value
 	}()
-	err = wallet.internal.Unlock(ctx, request.Passphrase, lock)
+	err = wallet.unlock(ctx, request.Passphrase, lock)
 	if err != nil {
-		return nil, translateError(err)
+		return nil, err
 	}
 
 	purchaseTicketsRequest := &w.PurchaseTicketsRequest{
@@ -298,13 +298,25 @@ func (wallet *Wallet) updateTicketPurchaseRequestWithVSPInfo(vspHost string, req
 
 	// unlock wallet and import the decoded script
 	lock := make(chan time.Time, 1)
-	wallet.internal.Unlock(ctx, request.Passphrase, lock)
+	defer func() {
+		lock <- time.Time{} // send matters, not the value
+	}()
+	err = wallet.unlock(ctx, request.Passphrase, lock)
+	if err != nil {
+		return err
+	}
+
 	err = wallet.internal.ImportScript(ctx, rs)
-	lock <- time.Time{}
 	if err != nil && !errors.Is(errors.Exist, err) {
 		return fmt.Errorf("error importing vsp redeem script: %s", err.Error())
 	}
 
+	// save the vsp and the pubkey address used with it so that
+	// vote preferences can be updated on the vsp later.
+	wallet.SetStringConfigValueForKey(VSPTicketsHostConfigKey, vspHost)
+	wallet.SetStringConfigValueForKey(VSPAPITokenConfigKey, request.VSPAPIToken)
+	wallet.SetStringConfigValueForKey(VSPUserPubKeyAddrConfigKey, pubKeyAddr)
+
 	request.TicketAddress = ticketPurchaseInfo.TicketAddress
 	request.PoolAddress = ticketPurchaseInfo.PoolAddress
 	request.PoolFees = ticketPurchaseInfo.PoolFees
@@ -0,0 +1,268 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrwallet/errors/v2"
+	w "github.com/decred/dcrwallet/wallet/v3"
+	"github.com/planetdecred/dcrlibwallet/utils"
+)
+
+// recordTicketPrice saves the stake difficulty in the provided block header
+// as the ticket price for the stake difficulty window that the block is in,
+// with the timestamp of the first block of the window. The first window is
+// not recorded, its ticket price is always the network's minimum stake
+// difficulty and storm does not accept a zero id.
+func (mw *MultiWallet) recordTicketPrice(header *wire.BlockHeader) error {
+	windowSize := int32(mw.chainParams.StakeDiffWindowSize)
+	height := int32(header.Height)
+	windowStartHeight := height - height%windowSize
+	if windowStartHeight == 0 {
+		return nil
+	}
+
+	if height != windowStartHeight {
+		err := mw.db.One("WindowStartHeight", windowStartHeight, &TicketPriceHistory{})
+		if err != storm.ErrNotFound {
+			// the window is already recorded
+			return err
+		}
+
+		wallet := mw.bestSyncedWallet()
+		if wallet == nil {
+			return errors.New(ErrNotConnected)
+		}
+		header, err = wallet.blockHeaderAtHeight(windowStartHeight)
+		if err != nil {
+			return err
+		}
+	}
+
+	return mw.db.Save(&TicketPriceHistory{
+		WindowStartHeight: windowStartHeight,
+		TicketPrice:       header.SBits,
+		Timestamp:         header.Timestamp.Unix(),
+	})
+}
+
+// updateTicketPriceHistory records the ticket price of every stake difficulty
+// window from the last recorded window up to the current best block, using
+// block headers that were fetched during sync.
+func (mw *MultiWallet) updateTicketPriceHistory() error {
+	wallet := mw.bestSyncedWallet()
+	if wallet == nil {
+		return errors.New(ErrNotConnected)
+	}
+
+	windowSize := int32(mw.chainParams.StakeDiffWindowSize)
+
+	var lastEntry TicketPriceHistory
+	startHeight := windowSize
+	err := mw.db.Select(q.True()).OrderBy("WindowStartHeight").Reverse().First(&lastEntry)
+	if err == nil {
+		startHeight = lastEntry.WindowStartHeight
+	} else if err != storm.ErrNotFound {
+		return err
+	}
+
+	ctx := wallet.shutdownContext()
+	_, tipHeight := wallet.internal.MainChainTip(ctx)
+	for height := startHeight; height <= tipHeight; height += windowSize {
+		header, err := wallet.blockHeaderAtHeight(height)
+		if err != nil {
+			return err
+		}
+
+		err = mw.recordTicketPrice(header)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+
+	return nil
+}
+
+// TicketPriceHistory returns the json-encoded ticket price of each stake
+// difficulty window recorded from block headers during sync.
+func (mw *MultiWallet) TicketPriceHistory(offset, limit int32, newestFirst bool) (string, error) {
+	history, err := mw.TicketPriceHistoryRaw(offset, limit, newestFirst)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(history)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (mw *MultiWallet) TicketPriceHistoryRaw(offset, limit int32, newestFirst bool) ([]TicketPriceHistory, error) {
+	query := mw.db.Select(q.True())
+	if offset > 0 {
+		query = query.Skip(int(offset))
+	}
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if newestFirst {
+		query = query.OrderBy("WindowStartHeight").Reverse()
+	} else {
+		query = query.OrderBy("WindowStartHeight")
+	}
+
+	history := make([]TicketPriceHistory, 0)
+	err := query.Find(&history)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// EstimateNextTicketPrice estimates the ticket price for the next stake
+// difficulty window using the headers of blocks mined so far.
+// The minimum estimate assumes that no more tickets are purchased in the
+// current window, the maximum estimate assumes that the maximum number of
+// tickets are purchased in each remaining block and the expected estimate
+// assumes that tickets continue to be purchased at the current window's rate.
+// May be incorrect if blockchain sync is ongoing or if blockchain is not up-to-date.
+func (mw *MultiWallet) EstimateNextTicketPrice() (*TicketPriceEstimate, error) {
+	wallet := mw.bestSyncedWallet()
+	if wallet == nil {
+		return nil, errors.New(ErrNotConnected)
+	}
+
+	params := mw.chainParams
+	windowSize := params.StakeDiffWindowSize
+	ticketMaturity := int64(params.TicketMaturity)
+
+	_, tip := wallet.internal.MainChainTip(wallet.shutdownContext())
+	tipHeight := int64(tip)
+	nextRetargetHeight := (tipHeight/windowSize + 1) * windowSize
+	remainingBlocks := nextRetargetHeight - tipHeight - 1
+
+	estimate := &TicketPriceEstimate{
+		NextWindowHeight: int32(nextRetargetHeight),
+		BlocksRemaining:  int32(remainingBlocks),
+	}
+
+	if nextRetargetHeight < int64(params.CoinbaseMaturity)+1 {
+		estimate.CurrentTicketPrice = params.MinimumStakeDiff
+		estimate.MinTicketPrice = params.MinimumStakeDiff
+		estimate.ExpectedTicketPrice = params.MinimumStakeDiff
+		estimate.MaxTicketPrice = params.MinimumStakeDiff
+		return estimate, nil
+	}
+
+	// read the headers needed to compute the previous and current pool sizes
+	prevRetargetHeight := nextRetargetHeight - windowSize - 1
+	firstHeight := prevRetargetHeight - ticketMaturity + 1
+	if tipHeight-ticketMaturity+1 < firstHeight {
+		firstHeight = tipHeight - ticketMaturity + 1
+	}
+	if firstHeight < 0 {
+		firstHeight = 0
+	}
+
+	headers := make(map[int64]*wire.BlockHeader)
+	for height := firstHeight; height <= tipHeight; height++ {
+		header, err := wallet.blockHeaderAtHeight(int32(height))
+		if err != nil {
+			return nil, err
+		}
+		headers[height] = header
+	}
+
+	sumPurchasedTickets := func(endHeight int64) (total int64) {
+		for height := endHeight - ticketMaturity + 1; height <= endHeight; height++ {
+			if header, ok := headers[height]; ok {
+				total += int64(header.FreshStake)
+			}
+		}
+		return
+	}
+
+	curDiff := headers[tipHeight].SBits
+	estimate.CurrentTicketPrice = curDiff
+
+	var prevPoolSizeAll int64
+	if prevRetargetHeight >= 0 {
+		prevPoolSizeAll = int64(headers[prevRetargetHeight].PoolSize) + sumPurchasedTickets(prevRetargetHeight)
+	}
+
+	var windowTickets int64
+	for height := nextRetargetHeight - windowSize; height <= tipHeight; height++ {
+		windowTickets += int64(headers[height].FreshStake)
+	}
+	blocksMinedInWindow := tipHeight - (nextRetargetHeight - windowSize) + 1
+
+	curPoolSizeAll := int64(headers[tipHeight].PoolSize) + sumPurchasedTickets(tipHeight)
+	votes := remainingBlocks * int64(params.TicketsPerBlock)
+	maxNewTickets := remainingBlocks * int64(params.MaxFreshStakePerBlock)
+	expectedNewTickets := windowTickets * remainingBlocks / blocksMinedInWindow
+	if expectedNewTickets > maxNewTickets {
+		expectedNewTickets = maxNewTickets
+	}
+
+	estimatePrice := func(newTickets int64) int64 {
+		if prevPoolSizeAll == 0 {
+			return curDiff
+		}
+
+		poolSizeAll := curPoolSizeAll + newTickets - votes
+		if poolSizeAll < 0 {
+			poolSizeAll = 0
+		}
+		return utils.CalcNextStakeDiff(params, nextRetargetHeight, curDiff, prevPoolSizeAll, poolSizeAll)
+	}
+
+	estimate.MinTicketPrice = estimatePrice(0)
+	estimate.ExpectedTicketPrice = estimatePrice(expectedNewTickets)
+	estimate.MaxTicketPrice = estimatePrice(maxNewTickets)
+
+	return estimate, nil
+}
+
+// bestSyncedWallet returns the opened wallet with the highest best block.
+func (mw *MultiWallet) bestSyncedWallet() (bestWallet *Wallet) {
+	var bestBlock int32 = -1
+	for _, wallet := range mw.wallets {
+		if !wallet.WalletOpened() {
+			continue
+		}
+
+		walletBestBlock := wallet.GetBestBlock()
+		if walletBestBlock > bestBlock {
+			bestBlock = walletBestBlock
+			bestWallet = wallet
+		}
+	}
+	return
+}
+
+func (wallet *Wallet) blockHeaderAtHeight(height int32) (*wire.BlockHeader, error) {
+	blockInfo, err := wallet.internal.BlockInfo(wallet.shutdownContext(), w.NewBlockIdentifierFromHeight(height))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	var header wire.BlockHeader
+	err = header.Deserialize(bytes.NewReader(blockInfo.Header))
+	if err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
@@ -0,0 +1,66 @@
+package dcrlibwallet
+
+import (
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v2"
+	"github.com/decred/dcrd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/planetdecred/dcrlibwallet/utils"
+)
+
+var _ = Describe("TicketPrice", func() {
+	Context("CalcNextStakeDiff", func() {
+		params := chaincfg.MainNetParams()
+		targetPoolSizeAll := int64(params.TicketsPerBlock) * int64(params.TicketPoolSize+params.TicketMaturity)
+		const nextHeight = 400000
+
+		It("keeps the price when the pool size is at target and unchanged", func() {
+			price := utils.CalcNextStakeDiff(params, nextHeight, 100e8, targetPoolSizeAll, targetPoolSizeAll)
+			Expect(price).To(BeEquivalentTo(100e8))
+		})
+
+		It("raises the price when the pool grows and lowers it when the pool shrinks", func() {
+			higher := utils.CalcNextStakeDiff(params, nextHeight, 100e8, targetPoolSizeAll, targetPoolSizeAll+1000)
+			lower := utils.CalcNextStakeDiff(params, nextHeight, 100e8, targetPoolSizeAll, targetPoolSizeAll-1000)
+			Expect(higher).To(BeNumerically(">", 100e8))
+			Expect(lower).To(BeNumerically("<", 100e8))
+		})
+
+		It("never returns less than the minimum stake difficulty", func() {
+			price := utils.CalcNextStakeDiff(params, nextHeight, params.MinimumStakeDiff, targetPoolSizeAll, 1)
+			Expect(price).To(Equal(params.MinimumStakeDiff))
+		})
+	})
+
+	Context("recordTicketPrice", func() {
+		var mw *MultiWallet
+
+		BeforeEach(func() {
+			mw = newTestMultiWallet("testnet3")
+		})
+
+		AfterEach(func() {
+			removeTestMultiWallet(mw)
+		})
+
+		It("records each window with the timestamp of its first block", func() {
+			windowSize := uint32(mw.chainParams.StakeDiffWindowSize)
+			firstBlockTime := time.Unix(1577836800, 0)
+
+			Expect(mw.recordTicketPrice(&wire.BlockHeader{Height: 1, SBits: 2e8})).To(Succeed())
+			Expect(mw.recordTicketPrice(&wire.BlockHeader{Height: windowSize, SBits: 3e8, Timestamp: firstBlockTime})).To(Succeed())
+			Expect(mw.recordTicketPrice(&wire.BlockHeader{Height: windowSize + 1, SBits: 3e8,
+				Timestamp: firstBlockTime.Add(5 * time.Minute)})).To(Succeed())
+
+			history, err := mw.TicketPriceHistoryRaw(0, 0, false)
+			Expect(err).To(BeNil())
+			Expect(history).To(Equal([]TicketPriceHistory{{
+				WindowStartHeight: int32(windowSize),
+				TicketPrice:       3e8,
+				Timestamp:         firstBlockTime.Unix(),
+			}}))
+		})
+	})
+})
@@ -1,25 +1,79 @@
 package dcrlibwallet
 
 import (
+	"context"
 	"encoding/json"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrwallet/errors/v2"
+	w "github.com/decred/dcrwallet/wallet/v3"
 )
 
 func (mw *MultiWallet) listenForTransactions(walletID int) {
-	go func() {
+	// Stop listening when the current sync ends. The sync goroutine's
+	// syncCanceled signal is meant for CancelSync and must not be consumed here.
+	mw.syncData.mu.RLock()
+	syncContext := mw.syncData.syncContext
+	mw.syncData.mu.RUnlock()
+	if syncContext == nil {
+		return
+	}
 
+	go func() {
 		wallet := mw.wallets[walletID]
 		n := wallet.internal.NtfnServer.TransactionNotifications()
+		mw.handleTransactionNotifications(syncContext, wallet, n.C)
+		n.Done()
+	}()
+}
+
+// handleTransactionNotifications indexes and publishes the transactions and
+// blocks received on `notifications` until ctx is canceled or an error occurs.
+func (mw *MultiWallet) handleTransactionNotifications(ctx context.Context, wallet *Wallet, notifications <-chan *w.TransactionNotifications) {
+	for {
+		select {
+		case v := <-notifications:
+			if v == nil {
+				return
+			}
+			for _, transaction := range v.UnminedTransactions {
+				tempTransaction, err := wallet.decodeTransactionWithTxSummary(&transaction, nil)
+				if err != nil {
+					log.Errorf("[%d] Error ntfn parse tx: %v", wallet.ID, err)
+					return
+				}
 
-		for {
-			select {
-			case v := <-n.C:
-				if v == nil {
+				overwritten, err := wallet.txDB.SaveOrUpdate(&Transaction{}, tempTransaction)
+				if err != nil {
+					log.Errorf("[%d] New Tx save err: %v", wallet.ID, err)
 					return
 				}
-				for _, transaction := range v.UnminedTransactions {
-					tempTransaction, err := wallet.decodeTransactionWithTxSummary(&transaction, nil)
+
+				if !overwritten {
+					log.Infof("[%d] New Transaction %s", wallet.ID, tempTransaction.Hash)
+
+					result, err := json.Marshal(tempTransaction)
+					if err != nil {
+						log.Error(err)
+					} else {
+						mw.mempoolTransactionNotification(string(result))
+					}
+
+					mw.publishTxEvent(newTxEvent(wallet.ID, tempTransaction))
+				}
+			}
+
+			forkHeight := BlockHeightInvalid
+			if len(v.AttachedBlocks) > 0 {
+				forkHeight = int32(v.AttachedBlocks[0].Header.Height)
+			}
+			mw.handleDetachedBlocks(wallet, v.DetachedBlocks, forkHeight)
+
+			minedTxHashes := make(map[string]bool)
+			for _, block := range v.AttachedBlocks {
+				blockHash := block.Header.BlockHash()
+				for _, transaction := range block.Transactions {
+					tempTransaction, err := wallet.decodeTransactionWithTxSummary(&transaction, &blockHash)
 					if err != nil {
 						log.Errorf("[%d] Error ntfn parse tx: %v", wallet.ID, err)
 						return
@@ -27,47 +81,160 @@ func (mw *MultiWallet) listenForTransactions(walletID int) {
 
 					overwritten, err := wallet.txDB.SaveOrUpdate(&Transaction{}, tempTransaction)
 					if err != nil {
-						log.Errorf("[%d] New Tx save err: %v", wallet.ID, err)
+						log.Errorf("[%d] Incoming block replace tx error :%v", wallet.ID, err)
 						return
 					}
+					mw.publishTransactionConfirmed(wallet.ID, transaction.Hash.String(), int32(block.Header.Height))
 
 					if !overwritten {
-						log.Infof("[%d] New Transaction %s", wallet.ID, tempTransaction.Hash)
-
-						result, err := json.Marshal(tempTransaction)
-						if err != nil {
-							log.Error(err)
-						} else {
-							mw.mempoolTransactionNotification(string(result))
-						}
+						// this tx was not seen in the mempool before being mined
+						mw.publishTxEvent(newTxEvent(wallet.ID, tempTransaction))
 					}
-				}
 
-				for _, block := range v.AttachedBlocks {
-					blockHash := block.Header.BlockHash()
-					for _, transaction := range block.Transactions {
-						tempTransaction, err := wallet.decodeTransactionWithTxSummary(&transaction, &blockHash)
-						if err != nil {
-							log.Errorf("[%d] Error ntfn parse tx: %v", wallet.ID, err)
-							return
-						}
-
-						_, err = wallet.txDB.SaveOrUpdate(&Transaction{}, tempTransaction)
-						if err != nil {
-							log.Errorf("[%d] Incoming block replace tx error :%v", wallet.ID, err)
-							return
-						}
-						mw.publishTransactionConfirmed(wallet.ID, transaction.Hash.String(), int32(block.Header.Height))
+					err = mw.trackTxConfirmations(wallet.ID, tempTransaction.Hash, tempTransaction.BlockHeight)
+					if err != nil {
+						log.Errorf("[%d] Error tracking tx confirmations: %v", wallet.ID, err)
 					}
 
-					mw.publishBlockAttached(wallet.ID, int32(block.Header.Height))
+					minedTxHashes[tempTransaction.Hash] = true
 				}
 
-			case <-mw.syncData.syncCanceled:
-				n.Done()
+				mw.publishBlockAttached(wallet.ID, int32(block.Header.Height))
+				mw.publishBlockEvent(&BlockEvent{
+					Type:        BlockEventAttached,
+					WalletID:    wallet.ID,
+					BlockHeight: int32(block.Header.Height),
+					BlockHash:   blockHash.String(),
+					ReorgDepth:  int32(len(v.DetachedBlocks)),
+				})
+
+				mw.publishTxConfirmations(wallet, int32(block.Header.Height))
+			}
+
+			if len(v.AttachedBlocks) > 0 {
+				mw.handleRemovedUnminedTransactions(wallet, v.UnminedTransactionHashes, minedTxHashes)
 			}
+
+		case <-ctx.Done():
+			return
 		}
-	}()
+	}
+}
+
+// handleDetachedBlocks rolls back the indexed transactions mined in blocks
+// removed from the main chain and publishes a block detached event for each
+// removed block and a removed-by-reorg event for each rolled back transaction.
+// Detached blocks are received in reverse height order starting from the
+// previous tip and the transactions of the new main chain are re-applied from
+// the attached blocks of the same notification.
+func (mw *MultiWallet) handleDetachedBlocks(wallet *Wallet, detachedBlocks []*chainhash.Hash, forkHeight int32) {
+	reorgDepth := int32(len(detachedBlocks))
+	if reorgDepth == 0 {
+		return
+	}
+
+	log.Infof("[%d] Reorganize %d block(s) from height %d", wallet.ID, reorgDepth, forkHeight)
+
+	for i, blockHash := range detachedBlocks {
+		blockHeight := BlockHeightInvalid
+		if forkHeight != BlockHeightInvalid {
+			blockHeight = forkHeight + reorgDepth - 1 - int32(i)
+		}
+
+		rolledBack, err := wallet.rollbackBlockTransactions(blockHash.String())
+		if err != nil {
+			log.Errorf("[%d] Error rolling back txs in detached block %s: %v", wallet.ID, blockHash, err)
+		}
+
+		for _, tx := range rolledBack {
+			mw.publishTxEvent(&TxEvent{
+				Type:        TxEventRemovedByReorg,
+				WalletID:    wallet.ID,
+				Transaction: tx,
+			})
+		}
+
+		mw.publishBlockEvent(&BlockEvent{
+			Type:        BlockEventDetached,
+			WalletID:    wallet.ID,
+			BlockHeight: blockHeight,
+			BlockHash:   blockHash.String(),
+			ReorgDepth:  reorgDepth,
+		})
+	}
+
+	if forkHeight == BlockHeightInvalid {
+		return
+	}
+
+	err := wallet.txDB.RollbackLastIndexPoint(forkHeight - 1)
+	if err != nil {
+		log.Errorf("[%d] Error rolling back tx index end block: %v", wallet.ID, err)
+	}
+
+	err = mw.untrackReorgedTxConfirmations(wallet.ID, forkHeight)
+	if err != nil {
+		log.Errorf("[%d] Error untracking txs removed by reorg: %v", wallet.ID, err)
+	}
+}
+
+// handleRemovedUnminedTransactions publishes a double spent event for each
+// indexed unmined transaction that was neither mined nor kept by the wallet as
+// unmined after blocks were attached, and removes the transaction from the index.
+func (mw *MultiWallet) handleRemovedUnminedTransactions(wallet *Wallet, unminedTxHashes []*chainhash.Hash,
+	minedTxHashes map[string]bool) {
+
+	unmined := make(map[string]bool, len(unminedTxHashes))
+	for _, hash := range unminedTxHashes {
+		unmined[hash.String()] = true
+	}
+
+	var transactions []Transaction
+	err := wallet.txDB.FindAll("BlockHeight", BlockHeightInvalid, &transactions)
+	if err != nil {
+		log.Errorf("[%d] Error reading unmined txs: %v", wallet.ID, err)
+		return
+	}
+
+	for i := range transactions {
+		tx := &transactions[i]
+		if unmined[tx.Hash] || minedTxHashes[tx.Hash] {
+			continue
+		}
+
+		err = wallet.txDB.DeleteTx(&Transaction{}, tx.Hash)
+		if err != nil {
+			log.Errorf("[%d] Error removing double spent tx %s: %v", wallet.ID, tx.Hash, err)
+			continue
+		}
+
+		log.Infof("[%d] Transaction %s was removed by a conflicting transaction", wallet.ID, tx.Hash)
+		mw.publishTxEvent(&TxEvent{
+			Type:        TxEventDoubleSpent,
+			WalletID:    wallet.ID,
+			Transaction: tx,
+		})
+	}
+}
+
+// newTxEvent returns a received or sent event for a newly seen transaction.
+func newTxEvent(walletID int, tx *Transaction) *TxEvent {
+	eventType := TxEventSent
+	if tx.Direction == TxDirectionReceived {
+		eventType = TxEventReceived
+	}
+
+	var confirmations int32
+	if tx.BlockHeight != BlockHeightInvalid {
+		confirmations = 1
+	}
+
+	return &TxEvent{
+		Type:          eventType,
+		WalletID:      walletID,
+		Transaction:   tx,
+		Confirmations: confirmations,
+	}
 }
 
 func (mw *MultiWallet) AddTxAndBlockNotificationListener(txAndBlockNotificationListener TxAndBlockNotificationListener, uniqueIdentifier string) error {
@@ -91,6 +258,27 @@ func (mw *MultiWallet) RemoveTxAndBlockNotificationListener(uniqueIdentifier str
 	delete(mw.txAndBlockNotificationListeners, uniqueIdentifier)
 }
 
+func (mw *MultiWallet) AddTxAndBlockEventListener(txAndBlockEventListener TxAndBlockEventListener, uniqueIdentifier string) error {
+	mw.notificationListenersMu.Lock()
+	defer mw.notificationListenersMu.Unlock()
+
+	_, ok := mw.txAndBlockEventListeners[uniqueIdentifier]
+	if ok {
+		return errors.New(ErrListenerAlreadyExist)
+	}
+
+	mw.txAndBlockEventListeners[uniqueIdentifier] = txAndBlockEventListener
+
+	return nil
+}
+
+func (mw *MultiWallet) RemoveTxAndBlockEventListener(uniqueIdentifier string) {
+	mw.notificationListenersMu.Lock()
+	defer mw.notificationListenersMu.Unlock()
+
+	delete(mw.txAndBlockEventListeners, uniqueIdentifier)
+}
+
 func (mw *MultiWallet) mempoolTransactionNotification(transaction string) {
 	mw.notificationListenersMu.RLock()
 	defer mw.notificationListenersMu.RUnlock()
@@ -117,3 +305,21 @@ func (mw *MultiWallet) publishBlockAttached(walletID int, blockHeight int32) {
 		txAndBlockNotifcationListener.OnBlockAttached(walletID, blockHeight)
 	}
 }
+
+func (mw *MultiWallet) publishTxEvent(event *TxEvent) {
+	mw.notificationListenersMu.RLock()
+	defer mw.notificationListenersMu.RUnlock()
+
+	for _, txAndBlockEventListener := range mw.txAndBlockEventListeners {
+		txAndBlockEventListener.OnTxEvent(event)
+	}
+}
+
+func (mw *MultiWallet) publishBlockEvent(event *BlockEvent) {
+	mw.notificationListenersMu.RLock()
+	defer mw.notificationListenersMu.RUnlock()
+
+	for _, txAndBlockEventListener := range mw.txAndBlockEventListeners {
+		txAndBlockEventListener.OnBlockEvent(event)
+	}
+}
@@ -0,0 +1,41 @@
+package dcrlibwallet
+
+import (
+	"context"
+
+	w "github.com/decred/dcrwallet/wallet/v3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tx and block notifications", func() {
+	var mw *MultiWallet
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("stops handling transaction notifications when the sync ends", func() {
+		wallet, err := mw.CreateNewWallet("wallet", "passphrase", PassphraseTypePass)
+		Expect(err).To(BeNil())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		notifications := make(chan *w.TransactionNotifications)
+		done := make(chan struct{})
+		go func() {
+			mw.handleTransactionNotifications(ctx, wallet, notifications)
+			close(done)
+		}()
+
+		notifications <- &w.TransactionNotifications{}
+		Consistently(done).ShouldNot(BeClosed())
+
+		// the notifications channel stays open until the listener is done
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -21,6 +21,10 @@ type TxAuthor struct {
 	sourceAccountNumber uint32
 	destinations        []TransactionDestination
 	changeAddress       string
+
+	// multisigAccount is set if the tx spends outputs of a multisig account,
+	// see NewUnsignedMultisigTx.
+	multisigAccount *MultisigAccount
 }
 
 func (mw *MultiWallet) NewUnsignedTx(sourceWallet *Wallet, sourceAccountNumber int32) *TxAuthor {
@@ -93,9 +97,18 @@ func (tx *TxAuthor) EstimateMaxSendAmount() (*Amount, error) {
 		return nil, err
 	}
 
-	spendableAccountBalance, err := tx.sourceWallet.SpendableForAccount(int32(tx.sourceAccountNumber))
-	if err != nil {
-		return nil, err
+	var spendableAccountBalance int64
+	if tx.multisigAccount != nil {
+		balance, err := tx.multisigAccount.balance(tx.sourceWallet)
+		if err != nil {
+			return nil, err
+		}
+		spendableAccountBalance = balance.Spendable
+	} else {
+		spendableAccountBalance, err = tx.sourceWallet.SpendableForAccount(int32(tx.sourceAccountNumber))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	maxSendableAmount := spendableAccountBalance - txFeeAndSize.Fee.AtomValue
@@ -113,6 +126,11 @@ func (tx *TxAuthor) Broadcast(privatePassphrase []byte) ([]byte, error) {
 		}
 	}()
 
+	if tx.multisigAccount != nil {
+		// multisig txs are signed by the cosigners, see UnsignedMultisigTx
+		return nil, errors.New(ErrInvalid)
+	}
+
 	n, err := tx.sourceWallet.internal.NetworkBackend()
 	if err != nil {
 		log.Error(err)
@@ -144,29 +162,18 @@ func (tx *TxAuthor) Broadcast(privatePassphrase []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	lock := make(chan time.Time, 1)
-	defer func() {
-		lock <- time.Time{}
-	}()
-
 	ctx := tx.sourceWallet.shutdownContext()
-	err = tx.sourceWallet.internal.Unlock(ctx, privatePassphrase, lock)
-	if err != nil {
-		log.Error(err)
-		return nil, errors.New(ErrInvalidPassphrase)
-	}
-
-	var additionalPkScripts map[wire.OutPoint][]byte
-
-	invalidSigs, err := tx.sourceWallet.internal.SignTransaction(ctx, &msgTx, txscript.SigHashAll, additionalPkScripts, nil, nil)
-	if err != nil {
-		log.Error(err)
-		return nil, err
-	}
-
-	invalidInputIndexes := make([]uint32, len(invalidSigs))
-	for i, e := range invalidSigs {
-		invalidInputIndexes[i] = e.InputIndex
+	if externalSigner := tx.sourceWallet.currentSigner(); externalSigner != nil {
+		err = tx.sourceWallet.signWithExternalSigner(ctx, externalSigner, &msgTx)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+	} else {
+		err = tx.signTransaction(ctx, &msgTx, privatePassphrase)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var serializedTransaction bytes.Buffer
@@ -191,7 +198,41 @@ func (tx *TxAuthor) Broadcast(privatePassphrase []byte) ([]byte, error) {
 	return txHash[:], nil
 }
 
+// signTransaction unlocks the source wallet with `privatePassphrase` to sign
+// the inputs of `msgTx`.
+func (tx *TxAuthor) signTransaction(ctx context.Context, msgTx *wire.MsgTx, privatePassphrase []byte) error {
+	lock := make(chan time.Time, 1)
+	defer func() {
+		lock <- time.Time{}
+	}()
+
+	err := tx.sourceWallet.unlock(ctx, privatePassphrase, lock)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	var additionalPkScripts map[wire.OutPoint][]byte
+
+	invalidSigs, err := tx.sourceWallet.internal.SignTransaction(ctx, msgTx, txscript.SigHashAll, additionalPkScripts, nil, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	invalidInputIndexes := make([]uint32, len(invalidSigs))
+	for i, e := range invalidSigs {
+		invalidInputIndexes[i] = e.InputIndex
+	}
+
+	return nil
+}
+
 func (tx *TxAuthor) constructTransaction() (*txauthor.AuthoredTx, error) {
+	if tx.multisigAccount != nil {
+		return tx.constructMultisigTransaction()
+	}
+
 	var err error
 	var outputs = make([]*wire.TxOut, 0)
 	var outputSelectionAlgorithm w.OutputSelectionAlgorithm = w.OutputSelectionAlgorithmDefault
@@ -61,6 +61,12 @@ func (wallet *Wallet) IndexTransactions() error {
 		return err
 	}
 
+	_, err = wallet.rollbackOrphanedTransactions(beginHeight)
+	if err != nil {
+		log.Errorf("[%d] Tx index reorg rollback error: %v", wallet.ID, err)
+		return err
+	}
+
 	endHeight := wallet.GetBestBlock()
 
 	startBlock := w.NewBlockIdentifierFromHeight(beginHeight)
@@ -87,3 +93,87 @@ func (wallet *Wallet) reindexTransactions() error {
 
 	return wallet.IndexTransactions()
 }
+
+// rollbackOrphanedTransactions rolls back indexed transactions mined at or
+// above `fromHeight` in blocks that are no longer part of the main chain.
+// This catches reorgs that happened while transaction notifications were not
+// being processed. The rolled back transactions are returned.
+func (wallet *Wallet) rollbackOrphanedTransactions(fromHeight int32) ([]*Transaction, error) {
+	var transactions []Transaction
+	err := wallet.txDB.ReadFromBlockHeight(fromHeight, &transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := wallet.shutdownContext()
+	orphanedBlocks := make(map[string]bool)
+	var rolledBack []*Transaction
+	for i := range transactions {
+		tx := &transactions[i]
+
+		orphaned, checked := orphanedBlocks[tx.BlockHash]
+		if !checked {
+			blockHash, err := chainhash.NewHashFromStr(tx.BlockHash)
+			if err != nil {
+				return nil, err
+			}
+
+			haveBlock, _, err := wallet.internal.BlockInMainChain(ctx, blockHash)
+			if err != nil {
+				return nil, err
+			}
+
+			orphaned = !haveBlock
+			orphanedBlocks[tx.BlockHash] = orphaned
+		}
+
+		if !orphaned {
+			continue
+		}
+
+		err = wallet.rollbackTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		rolledBack = append(rolledBack, tx)
+	}
+
+	return rolledBack, nil
+}
+
+// rollbackBlockTransactions rolls back the indexed transactions mined in the
+// block with `blockHash` after the block was removed from the main chain.
+// The rolled back transactions are returned.
+func (wallet *Wallet) rollbackBlockTransactions(blockHash string) ([]*Transaction, error) {
+	var transactions []Transaction
+	err := wallet.txDB.FindAll("BlockHash", blockHash, &transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	rolledBack := make([]*Transaction, len(transactions))
+	for i := range transactions {
+		err = wallet.rollbackTransaction(&transactions[i])
+		if err != nil {
+			return nil, err
+		}
+		rolledBack[i] = &transactions[i]
+	}
+
+	return rolledBack, nil
+}
+
+// rollbackTransaction updates an indexed transaction whose block was removed
+// from the main chain. Coinbase and vote transactions are only valid in the
+// block they were mined in and are deleted from the index. Other transactions
+// are marked as unmined until they are mined again in the new main chain.
+func (wallet *Wallet) rollbackTransaction(tx *Transaction) error {
+	if tx.Type == TxTypeCoinBase || tx.Type == TxTypeVote {
+		return wallet.txDB.DeleteTx(&Transaction{}, tx.Hash)
+	}
+
+	tx.BlockHeight = BlockHeightInvalid
+	tx.BlockHash = ""
+	_, err := wallet.txDB.SaveOrUpdate(&Transaction{}, tx)
+	return err
+}
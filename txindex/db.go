@@ -2,6 +2,7 @@ package txindex
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/asdine/storm"
@@ -16,7 +17,7 @@ const (
 
 	// Necessary to force re-indexing if changes are made to the structure of data being stored.
 	// Increment this version number if db structure changes such that client apps need to re-index.
-	TxDbVersion uint32 = 1
+	TxDbVersion uint32 = 3
 )
 
 type DB struct {
@@ -104,3 +105,12 @@ func ensureDatabaseVersion(txDB *storm.DB, dbPath string) (*storm.DB, error) {
 
 	return txDB, nil
 }
+
+// Copy writes a consistent copy of the database to w in a read transaction,
+// the database can be written to during the copy.
+func (db *DB) Copy(w io.Writer) error {
+	return db.txDB.Bolt.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
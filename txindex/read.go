@@ -2,6 +2,7 @@ package txindex
 
 import (
 	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
 )
 
 const MaxReOrgBlocks = 6
@@ -63,3 +64,23 @@ func (db *DB) Count(txFilter int32, txObj interface{}) (int, error) {
 func (db *DB) FindOne(fieldName string, value interface{}, txObj interface{}) error {
 	return db.txDB.One(fieldName, value, txObj)
 }
+
+// FindAll queries the db for all transactions with `fieldName` matching `value`
+// and saves the transactions found to the received `transactions` object.
+func (db *DB) FindAll(fieldName string, value interface{}, transactions interface{}) error {
+	err := db.txDB.Find(fieldName, value, transactions)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// ReadFromBlockHeight queries the db for all mined transactions at or above
+// `blockHeight` and saves the transactions found to the received `transactions` object.
+func (db *DB) ReadFromBlockHeight(blockHeight int32, transactions interface{}) error {
+	err := db.txDB.Select(q.Gte("BlockHeight", blockHeight)).Find(transactions)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	return nil
+}
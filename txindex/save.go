@@ -49,3 +49,32 @@ func (db *DB) ClearSavedTransactions(emptyTxPointer interface{}) error {
 
 	return db.SaveLastIndexPoint(0)
 }
+
+// DeleteTx removes the transaction with the specified hash from the database.
+func (db *DB) DeleteTx(emptyTxPointer interface{}, txHash string) error {
+	err := db.txDB.One("Hash", txHash, emptyTxPointer)
+	if err != nil {
+		return err
+	}
+
+	return db.txDB.DeleteStruct(emptyTxPointer)
+}
+
+// RollbackLastIndexPoint lowers the block height saved from the last indexing
+// operation to `blockHeight` if it is higher, so that blocks above
+// `blockHeight` are indexed again.
+func (db *DB) RollbackLastIndexPoint(blockHeight int32) error {
+	var endBlockHeight int32
+	err := db.txDB.Get(TxBucketName, KeyEndBlock, &endBlockHeight)
+	if err != nil && err != storm.ErrNotFound {
+		return fmt.Errorf("error reading block height for last indexed tx: %s", err.Error())
+	}
+
+	if blockHeight < 0 {
+		blockHeight = 0
+	}
+	if endBlockHeight <= blockHeight {
+		return nil
+	}
+	return db.SaveLastIndexPoint(blockHeight)
+}
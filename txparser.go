@@ -98,5 +98,9 @@ func (wallet *Wallet) decodeTransactionWithTxSummary(txSummary *w.TransactionSum
 		decodedTx.VoteReward = reward
 	}
 
+	if blockHeight != BlockHeightInvalid {
+		decodedTx.BlockHash = blockHash.String()
+	}
+
 	return decodedTx, nil
 }
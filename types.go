@@ -51,6 +51,12 @@ type Account struct {
 	ImportedKeyCount int32
 }
 
+// WatchOnlyAccount is an account xpub watched by a watch-only wallet.
+type WatchOnlyAccount struct {
+	Name              string `json:"name"`
+	ExtendedPublicKey string `json:"extendedPublicKey"`
+}
+
 type AccountsIterator struct {
 	currentIndex int
 	accounts     []*Account
@@ -122,6 +128,57 @@ type TxAndBlockNotificationListener interface {
 	OnTransactionConfirmed(walletID int, hash string, blockHeight int32)
 }
 
+// TxAndBlockEventListener receives typed transaction and block events, a
+// detailed alternative to the notifications of TxAndBlockNotificationListener.
+type TxAndBlockEventListener interface {
+	OnTxEvent(event *TxEvent)
+	OnBlockEvent(event *BlockEvent)
+}
+
+const (
+	TxEventReceived int32 = iota
+	TxEventSent
+	TxEventConfirmed
+	TxEventDoubleSpent
+	TxEventRemovedByReorg
+)
+
+// TxEvent is published to TxAndBlockEventListeners when a wallet
+// transaction is first seen, confirmed, double spent or removed by a reorg.
+type TxEvent struct {
+	Type          int32        `json:"type"`
+	WalletID      int          `json:"walletID"`
+	Transaction   *Transaction `json:"transaction"`
+	Confirmations int32        `json:"confirmations"`
+}
+
+const (
+	BlockEventAttached int32 = iota
+	BlockEventDetached
+)
+
+// BlockEvent is published to TxAndBlockEventListeners when a block is
+// attached to or detached from the main chain of a wallet.
+type BlockEvent struct {
+	Type        int32  `json:"type"`
+	WalletID    int    `json:"walletID"`
+	BlockHeight int32  `json:"blockHeight"`
+	BlockHash   string `json:"blockHash"`
+	ReorgDepth  int32  `json:"reorgDepth"`
+}
+
+// TxConfirmation tracks the confirmation count last published for a mined
+// wallet transaction so that confirmed events are neither duplicated nor
+// lost across restarts. Hash is not indexed, storm saves the values of
+// indexes in plaintext in encrypted databases.
+type TxConfirmation struct {
+	ID                    int `storm:"id,increment"`
+	WalletID              int `storm:"index"`
+	Hash                  string
+	BlockHeight           int32
+	NotifiedConfirmations int32
+}
+
 type BlocksRescanProgressListener interface {
 	OnBlocksRescanStarted(walletID int)
 	OnBlocksRescanProgress(*HeadersRescanProgressReport)
@@ -129,14 +186,15 @@ type BlocksRescanProgressListener interface {
 }
 
 // Transaction is used with storm for tx indexing operations.
-// For faster queries, the `Hash`, `Type` and `Direction` fields are indexed.
+// For faster queries, the `Hash`, `Type`, `BlockHeight`, `BlockHash` and `Direction` fields are indexed.
 type Transaction struct {
 	WalletID    int    `json:"walletID"`
 	Hash        string `storm:"id,unique" json:"hash"`
 	Type        string `storm:"index" json:"type"`
 	Hex         string `json:"hex"`
 	Timestamp   int64  `json:"timestamp"`
-	BlockHeight int32  `json:"block_height"`
+	BlockHeight int32  `storm:"index" json:"block_height"`
+	BlockHash   string `storm:"index" json:"block_hash"`
 
 	Version  int32 `json:"version"`
 	LockTime int32 `json:"lock_time"`
@@ -151,12 +209,19 @@ type Transaction struct {
 	Outputs   []*TxOutput `json:"outputs"`
 
 	// Vote Info
-	VoteVersion        int32  `json:"vote_version"`
-	LastBlockValid     bool   `json:"last_block_valid"`
-	VoteBits           string `json:"vote_bits"`
-	VoteReward         int64  `json:"vote_reward"`
-	TicketSpentHash    string `storm:"unique" json:"ticket_spent_hash"`
-	DaysToVoteOrRevoke int32  `json:"days_to_vote_revoke"`
+	VoteVersion        int32         `json:"vote_version"`
+	LastBlockValid     bool          `json:"last_block_valid"`
+	VoteBits           string        `json:"vote_bits"`
+	VoteReward         int64         `json:"vote_reward"`
+	TicketSpentHash    string        `storm:"unique" json:"ticket_spent_hash"`
+	DaysToVoteOrRevoke int32         `json:"days_to_vote_revoke"`
+	VoteChoices        []*VoteChoice `json:"vote_choices"`
+}
+
+// VoteChoice is the choice cast by a vote transaction for a consensus agenda.
+type VoteChoice struct {
+	AgendaID string `json:"agenda_id"`
+	ChoiceID string `json:"choice_id"`
 }
 
 type TxInput struct {
@@ -217,6 +282,19 @@ type TransactionDestination struct {
 
 /** end tx-related types */
 
+/** begin wallet lock types */
+
+// WalletLockListener is notified when a wallet is unlocked with UnlockWallet
+// or UnlockWalletWithTimeout and when it is locked again, with one of the
+// WalletLockReason constants. unlockedUntil is 0 for wallets unlocked until
+// they are locked with LockWallet.
+type WalletLockListener interface {
+	OnWalletUnlocked(walletID int, unlockedUntil int64)
+	OnWalletLocked(walletID int, reason int32)
+}
+
+/** end wallet lock types */
+
 /** begin ticket-related types */
 
 type PurchaseTicketsRequest struct {
@@ -230,6 +308,7 @@ type PurchaseTicketsRequest struct {
 	PoolAddress           string
 	PoolFees              float64
 	TicketFee             int64
+	VSPAPIToken           string
 }
 
 type GetTicketsRequest struct {
@@ -252,6 +331,23 @@ type TicketPriceResponse struct {
 	Height      int32
 }
 
+// TicketPriceHistory is the ticket price of a stake difficulty window
+// recorded with storm from the first block header in the window.
+type TicketPriceHistory struct {
+	WindowStartHeight int32 `storm:"id" json:"window_start_height"`
+	TicketPrice       int64 `json:"ticket_price"`
+	Timestamp         int64 `json:"timestamp"`
+}
+
+type TicketPriceEstimate struct {
+	CurrentTicketPrice  int64
+	MinTicketPrice      int64
+	ExpectedTicketPrice int64
+	MaxTicketPrice      int64
+	NextWindowHeight    int32
+	BlocksRemaining     int32
+}
+
 type VSPTicketPurchaseInfo struct {
 	PoolAddress   string
 	PoolFees      float64
@@ -260,3 +356,231 @@ type VSPTicketPurchaseInfo struct {
 }
 
 /** end ticket-related types */
+
+/** begin consensus-related types */
+
+// Agenda is a consensus deployment agenda that tickets may vote on.
+type Agenda struct {
+	AgendaID         string          `json:"agenda_id"`
+	Description      string          `json:"description"`
+	Mask             uint32          `json:"mask"`
+	Choices          []*AgendaChoice `json:"choices"`
+	VotingPreference string          `json:"voting_preference"`
+	StartTime        int64           `json:"start_time"`
+	ExpireTime       int64           `json:"expire_time"`
+	StakeVersion     uint32          `json:"stake_version"`
+}
+
+type AgendaChoice struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Bits        uint32 `json:"bits"`
+	IsAbstain   bool   `json:"is_abstain"`
+	IsNo        bool   `json:"is_no"`
+}
+
+/** end consensus-related types */
+
+/** begin politeia-related types */
+
+// Proposal is a Politeia proposal cached locally with storm.
+// The `Token` and `Category` fields are indexed for faster queries.
+type Proposal struct {
+	ID          int    `storm:"id,increment" json:"id"`
+	Token       string `storm:"unique" json:"token"`
+	Category    int32  `storm:"index" json:"category"`
+	Name        string `json:"name"`
+	State       int32  `json:"state"`
+	Status      int32  `json:"status"`
+	Timestamp   int64  `json:"timestamp"`
+	UserID      string `json:"userid"`
+	Username    string `json:"username"`
+	NumComments int32  `json:"numcomments"`
+	Version     string `json:"version"`
+	PublishedAt int64  `json:"publishedat"`
+
+	VoteStatus       int32                 `json:"votestatus"`
+	Approved         bool                  `json:"approved"`
+	EligibleTickets  int32                 `json:"eligibletickets"`
+	EndHeight        int32                 `json:"endheight"`
+	QuorumPercentage int32                 `json:"quorumpercentage"`
+	PassPercentage   int32                 `json:"passpercentage"`
+	TotalVotes       int64                 `json:"totalvotes"`
+	VoteOptions      []*ProposalVoteOption `json:"voteoptions"`
+}
+
+type ProposalVoteOption struct {
+	ID            string `json:"id"`
+	Description   string `json:"description"`
+	Bits          uint64 `json:"bits"`
+	VotesReceived int64  `json:"votesreceived"`
+}
+
+// ProposalVote records the outcome of a vote cast on a proposal by a wallet
+// ticket. The fields are not indexed, storm saves the values of indexes in
+// plaintext in encrypted databases.
+type ProposalVote struct {
+	ID        int    `storm:"id,increment" json:"id"`
+	WalletID  int    `json:"walletID"`
+	Token     string `json:"token"`
+	Ticket    string `json:"ticket"`
+	OptionID  string `json:"option_id"`
+	State     string `json:"state"`
+	Error     string `json:"error"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+/** end politeia-related types */
+
+/** begin webhook-related types */
+
+// Webhook is a url to which wallet events are sent as signed JSON POST requests.
+// The secret is persisted with storm but cleared when webhooks are listed. URL
+// is not indexed, storm saves the values of indexes in plaintext in encrypted
+// databases.
+type Webhook struct {
+	ID        int    `storm:"id,increment" json:"id"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// WebhookDelivery is a wallet event saved to the webhook outbox
+// until it is delivered to the webhook with `WebhookID`.
+type WebhookDelivery struct {
+	ID          int `storm:"id,increment"`
+	WebhookID   int `storm:"index"`
+	Event       string
+	Payload     []byte
+	Attempts    int32
+	NextAttempt int64 `storm:"index"`
+	CreatedAt   int64
+}
+
+/** end webhook-related types */
+
+/** begin multisig-related types */
+
+// MultisigAccount is an m-of-n multisig account shared by the cosigners whose
+// account extended public keys are listed in `XPubs`. `Account` is the wallet
+// account that signs for this wallet.
+type MultisigAccount struct {
+	ID                      int      `storm:"id,increment" json:"id"`
+	WalletID                int      `storm:"index" json:"walletID"`
+	Name                    string   `json:"name"`
+	RequiredSigs            int32    `json:"requiredSigs"`
+	XPubs                   []string `json:"xpubs"`
+	Account                 int32    `json:"account"`
+	NextAddressIndex        uint32   `json:"nextAddressIndex"`
+	ImportedAddresses       uint32   `json:"importedAddresses"`
+	NextChangeIndex         uint32   `json:"nextChangeIndex"`
+	ImportedChangeAddresses uint32   `json:"importedChangeAddresses"`
+	CreatedAt               int64    `json:"createdAt"`
+}
+
+// MultisigTx is a tx spending outputs of a multisig account. `Signatures` is
+// the lowest number of signatures on any of its inputs.
+type MultisigTx struct {
+	Hex                string `json:"hex"`
+	Hash               string `json:"hash"`
+	Signatures         int32  `json:"signatures"`
+	RequiredSignatures int32  `json:"requiredSignatures"`
+	Complete           bool   `json:"complete"`
+}
+
+/** end multisig-related types */
+
+/** begin seed backup quiz types */
+
+// SeedBackupQuiz asks for the seed words of a wallet at random positions to
+// verify that the seed was backed up. Each question offers the seed word at
+// its position among decoy words.
+type SeedBackupQuiz struct {
+	WalletID  int                   `json:"walletID"`
+	Questions []*SeedBackupQuestion `json:"questions"`
+	Attempts  int32                 `json:"attempts"`
+	CreatedAt int64                 `json:"createdAt"`
+}
+
+// SeedBackupQuestion asks for the seed word at the 0-based `Position`, which
+// is one of `Choices`.
+type SeedBackupQuestion struct {
+	Position int32    `json:"position"`
+	Choices  []string `json:"choices"`
+}
+
+// SeedBackupQuizResult is the result of an attempt at a SeedBackupQuiz.
+type SeedBackupQuizResult struct {
+	Passed            bool    `json:"passed"`
+	WrongPositions    []int32 `json:"wrongPositions"`
+	RemainingAttempts int32   `json:"remainingAttempts"`
+	// LockedUntil is the unix time until which no new quiz can be
+	// generated, set once no attempts remain.
+	LockedUntil int64 `json:"lockedUntil"`
+}
+
+/** end seed backup quiz types */
+
+/** begin seed share types */
+
+// SeedShareInfo describes a seed share created with SplitSeed. `GroupID` is
+// the same for all the shares of a split, any `Threshold` of the `Count`
+// shares of a group recover the seed. `Index` is 1-based.
+type SeedShareInfo struct {
+	GroupID   int32 `json:"groupID"`
+	Threshold int32 `json:"threshold"`
+	Count     int32 `json:"count"`
+	Index     int32 `json:"index"`
+}
+
+/** end seed share types */
+
+/** begin seed input types */
+
+// SeedInput describes a seed entered by the user, see ParseSeedInput.
+// `Format` is one of the SeedFormat constants. `Valid` is true if the seed
+// can be restored from. `ChecksumMismatch` is true if all the words are valid
+// but the checksum does not match, e.g. because of swapped or wrong words.
+type SeedInput struct {
+	Format           string                `json:"format"`
+	WordCount        int32                 `json:"wordCount"`
+	Valid            bool                  `json:"valid"`
+	ChecksumMismatch bool                  `json:"checksumMismatch"`
+	Corrections      []*SeedWordCorrection `json:"corrections"`
+}
+
+// SeedWordCorrection is a word of a seed at the 0-based `Index` that is not
+// valid there, along with the closest valid words.
+type SeedWordCorrection struct {
+	Index       int32    `json:"index"`
+	Word        string   `json:"word"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// SeedWordsVerification is the result of VerifySeedWords. `ChecksumIndex` is
+// the index of the checksum word if it does not match the other words and -1
+// otherwise.
+type SeedWordsVerification struct {
+	Valid         bool                  `json:"valid"`
+	InvalidWords  []*SeedWordCorrection `json:"invalidWords"`
+	ChecksumIndex int32                 `json:"checksumIndex"`
+}
+
+/** end seed input types */
+
+/** begin passphrase attempts types */
+
+// PassphraseAttempts is the state of the failed attempts in a row at the
+// startup passphrase or at the private passphrase of a wallet.
+type PassphraseAttempts struct {
+	FailedAttempts int32 `json:"failedAttempts"`
+	// RemainingAttempts is the number of failed attempts left before the
+	// wallets are deleted, -1 if they are never deleted.
+	RemainingAttempts int32 `json:"remainingAttempts"`
+	// LockedUntil is the unix time until which no attempt can be made, 0 if
+	// the passphrase is not locked out, and LockoutSeconds the seconds left.
+	LockedUntil    int64 `json:"lockedUntil"`
+	LockoutSeconds int64 `json:"lockoutSeconds"`
+}
+
+/** end passphrase attempts types */
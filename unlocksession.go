@@ -0,0 +1,241 @@
+package dcrlibwallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+// A wallet unlocked with UnlockWallet stays unlocked until it is locked with
+// LockWallet, one unlocked with UnlockWalletWithTimeout is locked again once
+// the timeout passes. Both are locked when the app goes to the background and
+// on shutdown. While a wallet is unlocked, the operations that take the
+// private passphrase, such as Broadcast, SignMessage and NextAccount, can be
+// called with an empty passphrase.
+
+const (
+	WalletLockReasonRequested int32 = iota
+	WalletLockReasonTimeout
+	WalletLockReasonBackground
+	WalletLockReasonShutdown
+	WalletLockReasonInvalidPassphrase
+)
+
+type lockStateFn = func(wallet *Wallet, locked bool, reason int32)
+
+func (mw *MultiWallet) UnlockWalletWithTimeout(walletID int, privPass []byte, timeoutSeconds int64) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	return wallet.UnlockWalletWithTimeout(privPass, timeoutSeconds)
+}
+
+// AppBackgrounded locks the unlocked wallets, it should be called when the
+// app goes to the background.
+func (mw *MultiWallet) AppBackgrounded() {
+	for _, wallet := range mw.wallets {
+		wallet.lock(WalletLockReasonBackground)
+	}
+}
+
+func (mw *MultiWallet) AddWalletLockListener(walletLockListener WalletLockListener, uniqueIdentifier string) error {
+	mw.notificationListenersMu.Lock()
+	defer mw.notificationListenersMu.Unlock()
+
+	_, ok := mw.walletLockListeners[uniqueIdentifier]
+	if ok {
+		return errors.New(ErrListenerAlreadyExist)
+	}
+
+	mw.walletLockListeners[uniqueIdentifier] = walletLockListener
+
+	return nil
+}
+
+func (mw *MultiWallet) RemoveWalletLockListener(uniqueIdentifier string) {
+	mw.notificationListenersMu.Lock()
+	defer mw.notificationListenersMu.Unlock()
+
+	delete(mw.walletLockListeners, uniqueIdentifier)
+}
+
+func (mw *MultiWallet) publishWalletLockState(wallet *Wallet, locked bool, reason int32) {
+	mw.notificationListenersMu.RLock()
+	defer mw.notificationListenersMu.RUnlock()
+
+	for _, walletLockListener := range mw.walletLockListeners {
+		if locked {
+			walletLockListener.OnWalletLocked(wallet.ID, reason)
+		} else {
+			walletLockListener.OnWalletUnlocked(wallet.ID, wallet.UnlockedUntil())
+		}
+	}
+}
+
+// UnlockWalletWithTimeout unlocks the wallet for `timeoutSeconds`, replacing
+// the timeout of a wallet that is already unlocked.
+func (wallet *Wallet) UnlockWalletWithTimeout(privPass []byte, timeoutSeconds int64) error {
+	if timeoutSeconds <= 0 {
+		return errors.New(ErrInvalid)
+	}
+
+	return wallet.unlockWallet(privPass, time.Duration(timeoutSeconds)*time.Second)
+}
+
+// UnlockedUntil returns the unix time at which the wallet will be locked if it
+// was unlocked with UnlockWalletWithTimeout, 0 otherwise.
+func (wallet *Wallet) UnlockedUntil() int64 {
+	wallet.unlockMu.Lock()
+	defer wallet.unlockMu.Unlock()
+
+	if wallet.unlockedUntil.IsZero() {
+		return 0
+	}
+	return wallet.unlockedUntil.Unix()
+}
+
+// unlockWallet unlocks the wallet until LockWallet is called if timeout is 0.
+func (wallet *Wallet) unlockWallet(privPass []byte, timeout time.Duration) error {
+	loadedWallet, ok := wallet.loader.LoadedWallet()
+	if !ok {
+		return fmt.Errorf("wallet has not been loaded")
+	}
+
+	defer func() {
+		for i := range privPass {
+			privPass[i] = 0
+		}
+	}()
+
+	ctx, _ := wallet.shutdownContextWithCancel()
+	err := wallet.verifyPassphraseAttempt(func() error {
+		return translateError(loadedWallet.Unlock(ctx, privPass, nil))
+	})
+	if err != nil {
+		wallet.lockedByFailedUnlock()
+		return err
+	}
+
+	wallet.unlockMu.Lock()
+	wallet.unlocked = true
+	wallet.unlockSession++
+	wallet.unlockedUntil = time.Time{}
+	if timeout > 0 {
+		session := wallet.unlockSession
+		wallet.unlockedUntil = time.Now().Add(timeout)
+		time.AfterFunc(timeout, func() {
+			wallet.endUnlockSession(session)
+		})
+	}
+	wallet.unlockMu.Unlock()
+
+	wallet.publishLockState(wallet, false, 0)
+	return nil
+}
+
+// unlock unlocks the wallet until `lock` receives, counting the attempt at
+// the private passphrase. An empty passphrase is accepted while the wallet is
+// unlocked with UnlockWallet or UnlockWalletWithTimeout, the wallet then
+// stays unlocked after `lock` receives.
+func (wallet *Wallet) unlock(ctx context.Context, privPass []byte, lock <-chan time.Time) error {
+	if len(privPass) == 0 && wallet.isUnlocked() {
+		return nil
+	}
+
+	return wallet.unlockWithPassphrase(ctx, privPass, lock)
+}
+
+// unlockWithPassphrase checks privPass, even while the wallet is unlocked
+// with UnlockWallet, and keeps the wallet unlocked until `lock` receives. The
+// wallet is only locked again once every such unlock has ended and it isn't
+// unlocked with UnlockWallet or UnlockWalletWithTimeout, so concurrent sends
+// and rescans don't lock each other's wallet.
+func (wallet *Wallet) unlockWithPassphrase(ctx context.Context, privPass []byte, lock <-chan time.Time) error {
+	wallet.unlockMu.Lock()
+	wallet.unlocksInUse++
+	wallet.unlockMu.Unlock()
+
+	// the wallet is locked by releaseUnlock, not by dcrwallet
+	err := wallet.verifyPassphraseAttempt(func() error {
+		return translateError(wallet.internal.Unlock(ctx, privPass, nil))
+	})
+	if err != nil {
+		wallet.releaseUnlock()
+		wallet.lockedByFailedUnlock()
+		return err
+	}
+
+	go func() {
+		<-lock
+		wallet.releaseUnlock()
+	}()
+	return nil
+}
+
+// releaseUnlock ends an unlock started by unlockWithPassphrase and locks the
+// wallet if it was the last one.
+func (wallet *Wallet) releaseUnlock() {
+	wallet.unlockMu.Lock()
+	defer wallet.unlockMu.Unlock()
+
+	wallet.unlocksInUse--
+	if wallet.unlocksInUse == 0 && !wallet.unlocked && wallet.internal != nil {
+		wallet.internal.Lock()
+	}
+}
+
+func (wallet *Wallet) isUnlocked() bool {
+	wallet.unlockMu.Lock()
+	defer wallet.unlockMu.Unlock()
+	return wallet.unlocked
+}
+
+// lockedByFailedUnlock ends the unlock session of a wallet locked by an
+// attempt with an invalid passphrase.
+func (wallet *Wallet) lockedByFailedUnlock() {
+	if wallet.internal.Locked() {
+		wallet.lock(WalletLockReasonInvalidPassphrase)
+	}
+}
+
+// endUnlockSession locks the wallet when the timeout of an unlock session
+// passes, unless the wallet was locked or unlocked again since.
+func (wallet *Wallet) endUnlockSession(session uint64) {
+	wallet.unlockMu.Lock()
+	wasUnlocked := session == wallet.unlockSession && wallet.lockAndEndSession()
+	wallet.unlockMu.Unlock()
+
+	if wasUnlocked {
+		wallet.publishLockState(wallet, true, WalletLockReasonTimeout)
+	}
+}
+
+func (wallet *Wallet) lock(reason int32) {
+	wallet.unlockMu.Lock()
+	wasUnlocked := wallet.lockAndEndSession()
+	wallet.unlockMu.Unlock()
+
+	if wasUnlocked {
+		wallet.publishLockState(wallet, true, reason)
+	}
+}
+
+// lockAndEndSession locks the wallet and returns true if it was unlocked with
+// UnlockWallet or UnlockWalletWithTimeout. A wallet still used by a send or a
+// rescan is locked by releaseUnlock once they end. unlockMu must be held.
+func (wallet *Wallet) lockAndEndSession() bool {
+	if wallet.internal != nil && wallet.unlocksInUse == 0 && !wallet.internal.Locked() {
+		wallet.internal.Lock()
+	}
+
+	wasUnlocked := wallet.unlocked
+	wallet.unlocked = false
+	wallet.unlockedUntil = time.Time{}
+	// the timers of earlier sessions must not lock the wallet
+	wallet.unlockSession++
+	return wasUnlocked
+}
@@ -0,0 +1,132 @@
+package dcrlibwallet
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type walletLockEvent struct {
+	walletID      int
+	locked        bool
+	unlockedUntil int64
+	reason        int32
+}
+
+type walletLockRecorder chan *walletLockEvent
+
+func (r walletLockRecorder) OnWalletUnlocked(walletID int, unlockedUntil int64) {
+	r <- &walletLockEvent{walletID: walletID, unlockedUntil: unlockedUntil}
+}
+
+func (r walletLockRecorder) OnWalletLocked(walletID int, reason int32) {
+	r <- &walletLockEvent{walletID: walletID, locked: true, reason: reason}
+}
+
+var _ = Describe("Unlock sessions", func() {
+	const passphrase = "passphrase"
+
+	var (
+		mw     *MultiWallet
+		wallet *Wallet
+		events walletLockRecorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		mw = newTestMultiWallet("testnet3")
+
+		wallet, err = mw.CreateNewWallet("wallet", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+
+		events = make(walletLockRecorder, 10)
+		Expect(mw.AddWalletLockListener(events, "test")).To(BeNil())
+		Expect(mw.AddWalletLockListener(events, "test")).To(MatchError(ErrListenerAlreadyExist))
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("locks wallets once the timeout passes", func() {
+		Expect(mw.UnlockWalletWithTimeout(wallet.ID, []byte(passphrase), 0)).To(MatchError(ErrInvalid))
+
+		Expect(mw.UnlockWalletWithTimeout(wallet.ID, []byte(passphrase), 1)).To(BeNil())
+		event := <-events
+		Expect(event.walletID).To(Equal(wallet.ID))
+		Expect(event.locked).To(BeFalse())
+		Expect(event.unlockedUntil).To(BeNumerically("~", time.Now().Add(time.Second).Unix(), 1))
+		Expect(wallet.IsLocked()).To(BeFalse())
+
+		// no passphrase is needed while the wallet is unlocked
+		_, err := wallet.NextAccount("unlocked", nil)
+		Expect(err).To(BeNil())
+		Expect(wallet.IsLocked()).To(BeFalse())
+
+		Eventually(events, 3*time.Second).Should(Receive(Equal(&walletLockEvent{
+			walletID: wallet.ID,
+			locked:   true,
+			reason:   WalletLockReasonTimeout,
+		})))
+		Expect(wallet.IsLocked()).To(BeTrue())
+		Expect(wallet.UnlockedUntil()).To(BeZero())
+		_, err = wallet.NextAccount("locked", nil)
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+
+		By("Ignoring the timeout of a replaced session")
+		Expect(wallet.UnlockWalletWithTimeout([]byte(passphrase), 1)).To(BeNil())
+		Expect(wallet.UnlockWallet([]byte(passphrase))).To(BeNil())
+		Expect((<-events).unlockedUntil).To(BeNumerically(">", 0))
+		Expect((<-events).unlockedUntil).To(BeZero())
+		Consistently(events, 2*time.Second).ShouldNot(Receive())
+		Expect(wallet.IsLocked()).To(BeFalse())
+	})
+
+	It("locks wallets on request, on background, on invalid passphrases and on shutdown", func() {
+		expectLocked := func(reason int32) {
+			Expect(mw.UnlockWallet(wallet.ID, []byte(passphrase))).To(BeNil())
+			Expect((<-events).locked).To(BeFalse())
+
+			switch reason {
+			case WalletLockReasonRequested:
+				wallet.LockWallet()
+			case WalletLockReasonBackground:
+				mw.AppBackgrounded()
+			case WalletLockReasonInvalidPassphrase:
+				_, err := wallet.NextAccount("account", []byte("wrong"))
+				Expect(err).To(MatchError(ErrInvalidPassphrase))
+			case WalletLockReasonShutdown:
+				mw.Shutdown()
+			}
+
+			Expect(<-events).To(Equal(&walletLockEvent{walletID: wallet.ID, locked: true, reason: reason}))
+			Expect(events).NotTo(Receive())
+		}
+
+		expectLocked(WalletLockReasonRequested)
+		expectLocked(WalletLockReasonBackground)
+		expectLocked(WalletLockReasonInvalidPassphrase)
+		expectLocked(WalletLockReasonShutdown)
+
+		mw = openTestMultiWallet(testRootDir(mw), "testnet3")
+	})
+	It("keeps wallets unlocked until every operation using the private passphrase ends", func() {
+		ctx := wallet.shutdownContext()
+		sendLock := make(chan time.Time, 1)
+		Expect(wallet.unlock(ctx, []byte(passphrase), sendLock)).To(Succeed())
+		rescanLock := make(chan time.Time, 1)
+		Expect(wallet.unlock(ctx, []byte(passphrase), rescanLock)).To(Succeed())
+
+		// checking the passphrase must not lock the wallet used by the send
+		Expect(wallet.checkPrivatePassphrase([]byte(passphrase))).To(Succeed())
+		Consistently(wallet.IsLocked, time.Second).Should(BeFalse())
+
+		rescanLock <- time.Time{}
+		Consistently(wallet.IsLocked, time.Second).Should(BeFalse())
+
+		sendLock <- time.Time{}
+		Eventually(wallet.IsLocked, time.Second).Should(BeTrue())
+		Expect(events).NotTo(Receive())
+	})
+})
@@ -57,7 +57,7 @@ func (wallet *Wallet) RequiredConfirmations() int32 {
 	if spendUnconfirmed {
 		return 0
 	}
-	return DefaultRequiredConfirmations
+	return wallet.confirmationTarget()
 }
 
 func (mw *MultiWallet) listenForShutdown() {
@@ -131,7 +131,7 @@ func GenerateSeed() (string, error) {
 }
 
 func VerifySeed(seedMnemonic string) bool {
-	_, err := walletseed.DecodeUserInput(seedMnemonic)
+	_, err := decodeSeedInput(seedMnemonic, "")
 	return err == nil
 }
 
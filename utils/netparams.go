@@ -10,6 +10,7 @@ import (
 var (
 	mainnetParams = chaincfg.MainNetParams()
 	testnetParams = chaincfg.TestNet3Params()
+	simnetParams  = chaincfg.SimNetParams()
 )
 
 func ChainParams(netType string) (*chaincfg.Params, error) {
@@ -18,6 +19,8 @@ func ChainParams(netType string) (*chaincfg.Params, error) {
 		return mainnetParams, nil
 	case strings.ToLower(testnetParams.Name):
 		return testnetParams, nil
+	case strings.ToLower(simnetParams.Name):
+		return simnetParams, nil
 	default:
 		return nil, errors.New("invalid net type")
 	}
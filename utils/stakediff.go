@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"math/big"
+
+	"github.com/decred/dcrd/chaincfg/v2"
+)
+
+// CalcNextStakeDiff calculates the stake difficulty for the block at
+// `nextHeight` as defined by DCP0001 given the pool sizes (including
+// immature tickets) at the current and previous retarget intervals.
+func CalcNextStakeDiff(params *chaincfg.Params, nextHeight, curDiff, prevPoolSizeAll, curPoolSizeAll int64) int64 {
+	votesPerBlock := int64(params.TicketsPerBlock)
+	ticketPoolSize := int64(params.TicketPoolSize)
+	ticketMaturity := int64(params.TicketMaturity)
+
+	//                   curDiff * curPoolSizeAll^2
+	//   nextDiff = -----------------------------------
+	//              prevPoolSizeAll * targetPoolSizeAll
+	targetPoolSizeAll := votesPerBlock * (ticketPoolSize + ticketMaturity)
+	curPoolSizeAllBig := big.NewInt(curPoolSizeAll)
+	nextDiffBig := big.NewInt(curDiff)
+	nextDiffBig.Mul(nextDiffBig, curPoolSizeAllBig)
+	nextDiffBig.Mul(nextDiffBig, curPoolSizeAllBig)
+	nextDiffBig.Div(nextDiffBig, big.NewInt(prevPoolSizeAll))
+	nextDiffBig.Div(nextDiffBig, big.NewInt(targetPoolSizeAll))
+
+	// Limit the new stake difficulty between the minimum allowed stake
+	// difficulty and a maximum value that is relative to the total supply.
+	nextDiff := nextDiffBig.Int64()
+	maximumStakeDiff := estimateSupply(params, nextHeight) / ticketPoolSize
+	if nextDiff > maximumStakeDiff {
+		nextDiff = maximumStakeDiff
+	}
+	if nextDiff < params.MinimumStakeDiff {
+		nextDiff = params.MinimumStakeDiff
+	}
+	return nextDiff
+}
+
+// estimateSupply returns an estimate of the coin supply for the provided block
+// height by calculating the full block subsidy for each reduction interval.
+func estimateSupply(params *chaincfg.Params, height int64) int64 {
+	if height <= 0 {
+		return 0
+	}
+
+	supply := params.BlockOneSubsidy()
+	reductions := height / params.SubsidyReductionInterval
+	subsidy := params.BaseSubsidy
+	for i := int64(0); i < reductions; i++ {
+		supply += params.SubsidyReductionInterval * subsidy
+
+		subsidy *= params.MulSubsidy
+		subsidy /= params.DivSubsidy
+	}
+	supply += (1 + height%params.SubsidyReductionInterval) * subsidy
+
+	// Blocks 0 and 1 have special subsidy amounts that have already been
+	// added above, so remove what their subsidies would have normally been
+	// which were also added above.
+	supply -= params.BaseSubsidy * 2
+
+	return supply
+}
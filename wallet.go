@@ -2,36 +2,65 @@ package dcrlibwallet
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/v2"
 	"github.com/decred/dcrwallet/errors/v2"
 	w "github.com/decred/dcrwallet/wallet/v3"
-	"github.com/decred/dcrwallet/walletseed"
 	"github.com/planetdecred/dcrlibwallet/internal/loader"
+	"github.com/planetdecred/dcrlibwallet/signer"
 	"github.com/planetdecred/dcrlibwallet/txindex"
 )
 
 type Wallet struct {
-	ID                    int       `storm:"id,increment"`
-	Name                  string    `storm:"unique"`
-	CreatedAt             time.Time `storm:"index"`
+	ID int `storm:"id,increment"`
+
+	// Name and CreatedAt are not indexed, storm saves the values of indexes
+	// in plaintext in encrypted databases.
+	Name                  string
+	CreatedAt             time.Time
 	DbDriver              string
 	EncryptedSeed         []byte
 	IsRestored            bool
 	HasDiscoveredAccounts bool
 	PrivatePassphraseType int32
 
+	// Birthday and BirthdayHeight are the time and block height before
+	// which the wallet has no transactions, if known. Address discovery and
+	// rescans of the wallet start from the block of the birthday. The height
+	// of a birthday set as a time is found during the first sync.
+	Birthday       time.Time
+	BirthdayHeight int32
+
+	// ArchivedAt and DeletedAt are the times the wallet was archived or
+	// deleted, zero for wallets that are not hidden, see walletarchive.go.
+	ArchivedAt time.Time
+	DeletedAt  time.Time
+
+	// DisplayOrder is the position of the wallet in AllWallets, wallets with
+	// the same position are ordered by ID. Color, Icon, Description and
+	// Group are set by users to tell wallets apart, see walletmetadata.go.
+	DisplayOrder int32
+	Color        string
+	Icon         string
+	Description  string
+	Group        string
+
 	internal    *w.Wallet
 	chainParams *chaincfg.Params
 	dataDir     string
 	loader      *loader.Loader
 	txDB        *txindex.DB
 
+	// externalSigner signs the transactions and messages of a watch-only
+	// wallet, see SetSigner. It is read with currentSigner.
+	externalSignerMu sync.RWMutex
+	externalSigner   signer.Signer
+
 	synced  bool
 	syncing bool
 	waiting bool
@@ -49,18 +78,42 @@ type Wallet struct {
 	// This function is ideally assigned when the `wallet.prepare` method is
 	// called from a MultiWallet instance.
 	readUserConfigValue configReadFn
+
+	// verifyPassphraseAttempt counts the failed attempts at the private
+	// passphrase and rejects attempts while the passphrase is locked out.
+	// This function is assigned when the `wallet.prepare` method is called
+	// from a MultiWallet instance.
+	verifyPassphraseAttempt passphraseAttemptFn
+
+	// publishLockState notifies the wallet lock listeners when the wallet is
+	// unlocked or locked. This function is assigned when the
+	// `wallet.prepare` method is called from a MultiWallet instance.
+	publishLockState lockStateFn
+
+	// unlocked is true while the wallet is unlocked with UnlockWallet or
+	// UnlockWalletWithTimeout, see unlocksession.go.
+	unlockMu      sync.Mutex
+	unlocked      bool
+	unlockedUntil time.Time
+	unlockSession uint64
+	// unlocksInUse counts the sends, rescans and other operations that
+	// unlocked the wallet with the private passphrase and haven't ended.
+	unlocksInUse int
 }
 
 // prepare gets a wallet ready for use by opening the transactions index database
 // and initializing the wallet loader which can be used subsequently to create,
 // load and unload the wallet.
 func (wallet *Wallet) prepare(rootDir string, chainParams *chaincfg.Params,
-	setUserConfigValueFn configSaveFn, readUserConfigValueFn configReadFn) (err error) {
+	setUserConfigValueFn configSaveFn, readUserConfigValueFn configReadFn,
+	verifyPassphraseAttemptFn passphraseAttemptFn, publishLockStateFn lockStateFn) (err error) {
 
 	wallet.chainParams = chainParams
 	wallet.dataDir = filepath.Join(rootDir, strconv.Itoa(wallet.ID))
 	wallet.setUserConfigValue = setUserConfigValueFn
 	wallet.readUserConfigValue = readUserConfigValueFn
+	wallet.verifyPassphraseAttempt = verifyPassphraseAttemptFn
+	wallet.publishLockState = publishLockStateFn
 
 	// open database for indexing transactions for faster loading
 	txDBPath := filepath.Join(wallet.dataDir, txindex.DbName)
@@ -93,6 +146,8 @@ func (wallet *Wallet) Shutdown() {
 	wallet.shuttingDown <- true
 
 	if _, loaded := wallet.loader.LoadedWallet(); loaded {
+		wallet.lock(WalletLockReasonShutdown)
+
 		err := wallet.loader.UnloadWallet()
 		if err != nil {
 			log.Errorf("Failed to close wallet: %v", err)
@@ -129,7 +184,7 @@ func (wallet *Wallet) WalletExists() (bool, error) {
 	return wallet.loader.WalletExists()
 }
 
-func (wallet *Wallet) createWallet(privatePassphrase, seedMnemonic string) error {
+func (wallet *Wallet) createWallet(privatePassphrase, seedMnemonic, seedPassphrase string) error {
 	log.Info("Creating Wallet")
 	if len(seedMnemonic) == 0 {
 		return errors.New(ErrEmptySeed)
@@ -137,7 +192,7 @@ func (wallet *Wallet) createWallet(privatePassphrase, seedMnemonic string) error
 
 	pubPass := []byte(w.InsecurePubPassphrase)
 	privPass := []byte(privatePassphrase)
-	seed, err := walletseed.DecodeUserInput(seedMnemonic)
+	seed, err := decodeSeedInput(seedMnemonic, seedPassphrase)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -197,30 +252,11 @@ func (wallet *Wallet) WalletOpened() bool {
 }
 
 func (wallet *Wallet) UnlockWallet(privPass []byte) error {
-	loadedWallet, ok := wallet.loader.LoadedWallet()
-	if !ok {
-		return fmt.Errorf("wallet has not been loaded")
-	}
-
-	defer func() {
-		for i := range privPass {
-			privPass[i] = 0
-		}
-	}()
-
-	ctx, _ := wallet.shutdownContextWithCancel()
-	err := loadedWallet.Unlock(ctx, privPass, nil)
-	if err != nil {
-		return translateError(err)
-	}
-
-	return nil
+	return wallet.unlockWallet(privPass, 0)
 }
 
 func (wallet *Wallet) LockWallet() {
-	if !wallet.internal.Locked() {
-		wallet.internal.Lock()
-	}
+	wallet.lock(WalletLockReasonRequested)
 }
 
 func (wallet *Wallet) IsLocked() bool {
@@ -238,11 +274,9 @@ func (wallet *Wallet) changePrivatePassphrase(oldPass []byte, newPass []byte) er
 		}
 	}()
 
-	err := wallet.internal.ChangePrivatePassphrase(wallet.shutdownContext(), oldPass, newPass)
-	if err != nil {
-		return translateError(err)
-	}
-	return nil
+	return wallet.verifyPassphraseAttempt(func() error {
+		return translateError(wallet.internal.ChangePrivatePassphrase(wallet.shutdownContext(), oldPass, newPass))
+	})
 }
 
 func (wallet *Wallet) deleteWallet(privatePassphrase []byte) error {
@@ -252,29 +286,50 @@ func (wallet *Wallet) deleteWallet(privatePassphrase []byte) error {
 		}
 	}()
 
+	err := wallet.checkPrivatePassphrase(privatePassphrase)
+	if err != nil {
+		return err
+	}
+
+	wallet.Shutdown()
+
+	log.Info("Deleting Wallet")
+	return os.RemoveAll(wallet.dataDir)
+}
+
+// checkPrivatePassphrase returns an error if the wallet is not loaded or if
+// privatePassphrase is not the private passphrase of the wallet.
+func (wallet *Wallet) checkPrivatePassphrase(privatePassphrase []byte) error {
 	if _, loaded := wallet.loader.LoadedWallet(); !loaded {
 		return errors.New(ErrWalletNotLoaded)
 	}
 
 	if !wallet.IsWatchingOnlyWallet() {
-		err := wallet.internal.Unlock(wallet.shutdownContext(), privatePassphrase, nil)
+		// the passphrase is always checked, even while the wallet is
+		// unlocked with UnlockWallet
+		lock := make(chan time.Time, 1)
+		defer func() {
+			lock <- time.Time{} // send matters, not the value
+		}()
+		err := wallet.unlockWithPassphrase(wallet.shutdownContext(), privatePassphrase, lock)
 		if err != nil {
-			return translateError(err)
+			return err
 		}
-		wallet.internal.Lock()
 	}
 
-	wallet.Shutdown()
-
-	log.Info("Deleting Wallet")
-	return os.RemoveAll(wallet.dataDir)
+	return nil
 }
 
 // DecryptSeed decrypts wallet.EncryptedSeed using privatePassphrase
-func (wallet *Wallet) DecryptSeed(privatePassphrase []byte) (string, error) {
+// and counts the attempt at the private passphrase.
+func (wallet *Wallet) DecryptSeed(privatePassphrase []byte) (seed string, err error) {
 	if wallet.EncryptedSeed == nil {
 		return "", errors.New(ErrInvalid)
 	}
 
-	return decryptWalletSeed(privatePassphrase, wallet.EncryptedSeed)
+	err = wallet.verifyPassphraseAttempt(func() error {
+		seed, err = decryptWalletSeed(privatePassphrase, wallet.EncryptedSeed)
+		return err
+	})
+	return seed, err
 }
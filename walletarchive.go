@@ -0,0 +1,221 @@
+package dcrlibwallet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+// Archived and deleted wallets are hidden, they are not synced nor listed by
+// AllWallets and WalletWithID until they are restored. Their files are kept,
+// archived wallets until they are unarchived and deleted wallets for the
+// DeletedWalletsRecoveryDaysConfigKey days after which they are purged.
+
+const (
+	// DeletedWalletsRecoveryDaysConfigKey is the number of days deleted
+	// wallets can be restored for. Wallets are deleted right away if it is
+	// set to 0.
+	DeletedWalletsRecoveryDaysConfigKey = "deleted_wallets_recovery_days"
+
+	defaultDeletedWalletsRecoveryDays = 30
+)
+
+func (wallet *Wallet) IsArchived() bool {
+	return !wallet.ArchivedAt.IsZero()
+}
+
+func (wallet *Wallet) IsDeleted() bool {
+	return !wallet.DeletedAt.IsZero()
+}
+
+// ArchiveWallet hides the wallet until it is unarchived with UnarchiveWallet.
+func (mw *MultiWallet) ArchiveWallet(walletID int) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	return mw.hideWallet(wallet, func() {
+		wallet.ArchivedAt = time.Now()
+	})
+}
+
+func (mw *MultiWallet) UnarchiveWallet(walletID int) error {
+	wallet, ok := mw.hiddenWallets[walletID]
+	if !ok || !wallet.IsArchived() {
+		return errors.New(ErrNotExist)
+	}
+
+	return mw.showWallet(wallet)
+}
+
+// RestoreDeletedWallet restores a wallet deleted with DeleteWallet that was
+// not purged yet. The wallet is renamed "<name> (2)", "<name> (3)" and so on
+// if its name was given to another wallet since it was deleted.
+func (mw *MultiWallet) RestoreDeletedWallet(walletID int) error {
+	wallet, ok := mw.hiddenWallets[walletID]
+	if !ok || !wallet.IsDeleted() {
+		return errors.New(ErrNotExist)
+	}
+
+	name, err := mw.restoredWalletName(wallet.Name)
+	if err != nil {
+		return translateError(err)
+	}
+
+	deletedName := wallet.Name
+	wallet.Name = name
+	err = mw.showWallet(wallet)
+	if err != nil {
+		wallet.Name = deletedName
+	}
+	return err
+}
+
+// restoredWalletName returns the first of name, "<name> (2)", "<name> (3)"...
+// that isn't used by another wallet.
+func (mw *MultiWallet) restoredWalletName(name string) (string, error) {
+	for i := 1; ; i++ {
+		restoredName := name
+		if i > 1 {
+			restoredName = fmt.Sprintf("%s (%d)", name, i)
+		}
+
+		used, err := mw.walletNameUsed(restoredName)
+		if err != nil || !used {
+			return restoredName, err
+		}
+	}
+}
+
+// PurgeWallet deletes the files and the records of a deleted wallet before its
+// recovery period ends.
+func (mw *MultiWallet) PurgeWallet(walletID int) error {
+	wallet, ok := mw.hiddenWallets[walletID]
+	if !ok || !wallet.IsDeleted() {
+		return errors.New(ErrNotExist)
+	}
+
+	return mw.purgeWallet(wallet)
+}
+
+func (mw *MultiWallet) ArchivedWallets() []*Wallet {
+	return mw.hiddenWalletsWhere((*Wallet).IsArchived)
+}
+
+// DeletedWallets returns the deleted wallets that can be restored, a wallet is
+// purged DeletedWalletsRecoveryDaysConfigKey days after its DeletedAt time.
+func (mw *MultiWallet) DeletedWallets() []*Wallet {
+	return mw.hiddenWalletsWhere((*Wallet).IsDeleted)
+}
+
+func (mw *MultiWallet) hiddenWalletsWhere(filter func(*Wallet) bool) []*Wallet {
+	wallets := make([]*Wallet, 0)
+	for _, wallet := range mw.hiddenWallets {
+		if filter(wallet) {
+			wallets = append(wallets, wallet)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].ID < wallets[j].ID
+	})
+	return wallets
+}
+
+func (mw *MultiWallet) deletedWalletsRecoveryPeriod() time.Duration {
+	days := mw.ReadInt32ConfigValueForKey(DeletedWalletsRecoveryDaysConfigKey, defaultDeletedWalletsRecoveryDays)
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// hideWallet saves the wallet after `hide` sets its archived or deleted time
+// and closes it.
+func (mw *MultiWallet) hideWallet(wallet *Wallet, hide func()) error {
+	if mw.IsConnectedToDecredNetwork() {
+		return errors.New(ErrSyncAlreadyInProgress)
+	}
+
+	archivedAt, deletedAt := wallet.ArchivedAt, wallet.DeletedAt
+	hide()
+	err := mw.db.Save(wallet)
+	if err != nil {
+		wallet.ArchivedAt, wallet.DeletedAt = archivedAt, deletedAt
+		return translateError(err)
+	}
+
+	wallet.Shutdown()
+	delete(mw.wallets, wallet.ID)
+	mw.hiddenWallets[wallet.ID] = wallet
+	mw.saveWalletsCount()
+
+	return nil
+}
+
+// showWallet opens a hidden wallet and saves it as neither archived nor
+// deleted.
+func (mw *MultiWallet) showWallet(wallet *Wallet) error {
+	if mw.IsConnectedToDecredNetwork() {
+		return errors.New(ErrSyncAlreadyInProgress)
+	}
+
+	// the wallet of a closed loader can't be used
+	wallet.internal = nil
+	err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+		mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
+	if err != nil {
+		return err
+	}
+
+	err = wallet.openWallet()
+	if err != nil {
+		wallet.Shutdown()
+		return err
+	}
+
+	archivedAt, deletedAt := wallet.ArchivedAt, wallet.DeletedAt
+	wallet.ArchivedAt, wallet.DeletedAt = time.Time{}, time.Time{}
+	err = mw.db.Save(wallet)
+	if err != nil {
+		wallet.ArchivedAt, wallet.DeletedAt = archivedAt, deletedAt
+		wallet.Shutdown()
+		return translateError(err)
+	}
+
+	delete(mw.hiddenWallets, wallet.ID)
+	mw.wallets[wallet.ID] = wallet
+	mw.saveWalletsCount()
+
+	return nil
+}
+
+func (mw *MultiWallet) purgeWallet(wallet *Wallet) error {
+	err := os.RemoveAll(filepath.Join(mw.rootDir, strconv.Itoa(wallet.ID)))
+	if err != nil {
+		return err
+	}
+
+	err = mw.deleteWalletRecords(wallet)
+	if err != nil {
+		return err
+	}
+
+	delete(mw.hiddenWallets, wallet.ID)
+	return nil
+}
+
+// loadHiddenWallet keeps an archived or deleted wallet loaded from the
+// database as hidden, purging it if it was deleted before the recovery
+// period.
+func (mw *MultiWallet) loadHiddenWallet(wallet *Wallet) error {
+	if wallet.IsDeleted() && time.Since(wallet.DeletedAt) >= mw.deletedWalletsRecoveryPeriod() {
+		log.Infof("[%d] Purging wallet deleted on %s", wallet.ID, wallet.DeletedAt.Format(time.RFC3339))
+		return mw.purgeWallet(wallet)
+	}
+
+	mw.hiddenWallets[wallet.ID] = wallet
+	return nil
+}
@@ -0,0 +1,133 @@
+package dcrlibwallet
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wallet archiving", func() {
+	const passphrase = "passphrase"
+
+	var (
+		mw     *MultiWallet
+		wallet *Wallet
+		other  *Wallet
+	)
+
+	restart := func() {
+		mw = reopenTestMultiWallet(mw)
+		Expect(mw.OpenWallets(nil)).To(BeNil())
+	}
+
+	dirExists := func(dir string) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		mw = newTestMultiWallet("testnet3")
+
+		wallet, err = mw.CreateNewWallet("wallet", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+		other, err = mw.CreateNewWallet("other", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("hides archived wallets until they are unarchived", func() {
+		Expect(mw.ArchiveWallet(wallet.ID)).To(BeNil())
+		Expect(mw.ArchiveWallet(wallet.ID)).To(MatchError(ErrNotExist))
+		Expect(mw.WalletWithID(wallet.ID)).To(BeNil())
+		Expect(mw.AllWallets()).To(ConsistOf(other))
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.ArchivedWallets()).To(ConsistOf(wallet))
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+		Expect(mw.RestoreDeletedWallet(wallet.ID)).To(MatchError(ErrNotExist))
+
+		restart()
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.ArchivedWallets()).To(HaveLen(1))
+		Expect(mw.ArchivedWallets()[0].IsArchived()).To(BeTrue())
+
+		Expect(mw.UnarchiveWallet(wallet.ID)).To(BeNil())
+		Expect(mw.ArchivedWallets()).To(BeEmpty())
+		unarchived := mw.WalletWithID(wallet.ID)
+		Expect(unarchived).NotTo(BeNil())
+		Expect(unarchived.IsArchived()).To(BeFalse())
+		_, err := unarchived.CurrentAddress(0)
+		Expect(err).To(BeNil())
+
+		restart()
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(2)))
+	})
+
+	It("keeps deleted wallets for the recovery period", func() {
+		Expect(mw.DeleteWallet(wallet.ID, []byte("wrong"))).To(MatchError(ErrInvalidPassphrase))
+		Expect(mw.DeleteWallet(wallet.ID, []byte(passphrase))).To(BeNil())
+		Expect(mw.AllWallets()).To(ConsistOf(other))
+		Expect(mw.DeletedWallets()).To(ConsistOf(wallet))
+		Expect(wallet.IsDeleted()).To(BeTrue())
+		Expect(dirExists(wallet.dataDir)).To(BeTrue())
+		Expect(mw.UnarchiveWallet(wallet.ID)).To(MatchError(ErrNotExist))
+
+		Expect(mw.RestoreDeletedWallet(wallet.ID)).To(BeNil())
+		Expect(mw.WalletWithID(wallet.ID).IsDeleted()).To(BeFalse())
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+
+		By("Purging deleted wallets")
+		Expect(mw.DeleteWallet(wallet.ID, []byte(passphrase))).To(BeNil())
+		Expect(mw.PurgeWallet(other.ID)).To(MatchError(ErrNotExist))
+		Expect(mw.PurgeWallet(wallet.ID)).To(BeNil())
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+		Expect(dirExists(wallet.dataDir)).To(BeFalse())
+		restart()
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(1)))
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+
+		By("Purging wallets once the recovery period ends")
+		Expect(mw.DeleteWallet(other.ID, []byte(passphrase))).To(BeNil())
+		deleted := mw.DeletedWallets()[0]
+		deleted.DeletedAt = time.Now().Add(-defaultDeletedWalletsRecoveryDays*24*time.Hour - time.Minute)
+		Expect(mw.db.Save(deleted)).To(BeNil())
+		restart()
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+		Expect(dirExists(other.dataDir)).To(BeFalse())
+
+		By("Deleting wallets right away without a recovery period")
+		mw.SetInt32ConfigValueForKey(DeletedWalletsRecoveryDaysConfigKey, 0)
+		newWallet, err := mw.CreateNewWallet("new", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+		Expect(mw.DeleteWallet(newWallet.ID, []byte(passphrase))).To(BeNil())
+		Expect(mw.DeletedWallets()).To(BeEmpty())
+		Expect(dirExists(newWallet.dataDir)).To(BeFalse())
+	})
+	It("lets the names of deleted wallets be reused", func() {
+		Expect(mw.ArchiveWallet(other.ID)).To(BeNil())
+		_, err := mw.CreateNewWallet("other", passphrase, PassphraseTypePass)
+		Expect(err).To(MatchError(ErrExist))
+
+		Expect(mw.DeleteWallet(wallet.ID, []byte(passphrase))).To(BeNil())
+		exists, err := mw.WalletNameExists("wallet")
+		Expect(err).To(BeNil())
+		Expect(exists).To(BeFalse())
+		reused, err := mw.CreateNewWallet("wallet", passphrase, PassphraseTypePass)
+		Expect(err).To(BeNil())
+		Expect(reused.ID).NotTo(Equal(wallet.ID))
+
+		By("Renaming restored wallets whose name was reused")
+		Expect(mw.CreateNewWallet("wallet (2)", passphrase, PassphraseTypePass)).NotTo(BeNil())
+		Expect(mw.RestoreDeletedWallet(wallet.ID)).To(BeNil())
+		Expect(mw.WalletWithID(wallet.ID).Name).To(Equal("wallet (3)"))
+		Expect(mw.WalletWithID(reused.ID).Name).To(Equal("wallet"))
+
+		restart()
+		Expect(mw.WalletWithID(wallet.ID).Name).To(Equal("wallet (3)"))
+	})
+})
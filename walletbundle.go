@@ -0,0 +1,630 @@
+package dcrlibwallet
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrwallet/errors/v2"
+	"github.com/kevinburke/nacl"
+	"github.com/kevinburke/nacl/secretbox"
+	"github.com/planetdecred/dcrlibwallet/txindex"
+	bolt "go.etcd.io/bbolt"
+)
+
+// A wallet bundle holds one wallet to move it to another device without
+// restoring it from its seed and rescanning the blockchain. It contains the
+// wallet database, the transaction index, the wallet config values, the
+// multisig accounts and the metadata of the wallet, encrypted with a key
+// derived from an export passphrase. The private keys in the wallet database
+// remain encrypted with the private passphrase of the wallet.
+//
+// Bundles are a header, the magic, version and key salt, followed by a tar.gz
+// archive encrypted in chunks with secretbox. The nonce of a chunk is its
+// index and a flag set on the last chunk, so chunks can't be reordered or
+// dropped. The key is derived with a new salt for each bundle, nonces are
+// never reused with the same key. Wallets are exported and imported through
+// files, their databases are not held in memory.
+//
+// Only wallets using the default database driver can be exported, their
+// database is a single file.
+
+const (
+	walletBundleVersion      = 2
+	walletBundleMetadataName = "wallet.json"
+	walletBundleChunkSize    = 64 * 1024
+)
+
+var walletBundleMagic = []byte("dcrlibwallet-wallet-bundle")
+
+// walletBundleFiles are the files of the wallet data directory saved in
+// wallet bundles, other files in bundles are ignored.
+var walletBundleFiles = []string{walletDbName, txindex.DbName}
+
+type walletBundleMetadata struct {
+	Network               string
+	Name                  string
+	CreatedAt             time.Time
+	EncryptedSeed         []byte
+	IsRestored            bool
+	HasDiscoveredAccounts bool
+	PrivatePassphraseType int32
+	Birthday              time.Time
+	BirthdayHeight        int32
+	Color                 string
+	Icon                  string
+	Description           string
+	Group                 string
+	Config                map[string]json.RawMessage
+	MultisigAccounts      []*MultisigAccount
+}
+
+// ExportWallet saves the wallet as a bundle encrypted with exportPassphrase
+// at bundlePath, the bundle can be imported on another device with
+// ImportWallet. Wallets can't be exported during sync.
+func (mw *MultiWallet) ExportWallet(walletID int, exportPassphrase []byte, bundlePath string) error {
+	if len(exportPassphrase) == 0 {
+		return errors.New(ErrPassphraseRequired)
+	}
+
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	if !isDefaultDbDriver(wallet.DbDriver) {
+		return errors.New(ErrInvalid)
+	}
+
+	if _, loaded := wallet.loader.LoadedWallet(); !loaded {
+		return errors.New(ErrWalletNotLoaded)
+	}
+
+	// the wallet database and the transaction index are copied in separate
+	// transactions, they would not match if blocks were processed between
+	// the copies
+	if mw.IsConnectedToDecredNetwork() {
+		return errors.New(ErrSyncAlreadyInProgress)
+	}
+
+	metadata := &walletBundleMetadata{
+		Network:               mw.chainParams.Name,
+		Name:                  wallet.Name,
+		CreatedAt:             wallet.CreatedAt,
+		EncryptedSeed:         wallet.EncryptedSeed,
+		IsRestored:            wallet.IsRestored,
+		HasDiscoveredAccounts: wallet.HasDiscoveredAccounts,
+		PrivatePassphraseType: wallet.PrivatePassphraseType,
+		Birthday:              wallet.Birthday,
+		BirthdayHeight:        wallet.BirthdayHeight,
+		Color:                 wallet.Color,
+		Icon:                  wallet.Icon,
+		Description:           wallet.Description,
+		Group:                 wallet.Group,
+	}
+
+	var err error
+	metadata.Config, err = mw.walletConfigValues(walletID)
+	if err != nil {
+		return err
+	}
+
+	err = mw.db.Select(q.Eq("WalletID", walletID)).Find(&metadata.MultisigAccounts)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	// the bundle is renamed to bundlePath once it is complete
+	bundleDir := filepath.Dir(bundlePath)
+	bundleFile, err := ioutil.TempFile(bundleDir, ".bundle")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(bundleFile.Name())
+	defer bundleFile.Close()
+
+	salt := make([]byte, dbEncryptionSaltSize)
+	if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	key, err := dbEncryptionKey(exportPassphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	header := append(append([]byte(nil), walletBundleMagic...), walletBundleVersion)
+	if _, err = bundleFile.Write(append(header, salt...)); err != nil {
+		return err
+	}
+
+	encrypter := newWalletBundleEncrypter(bundleFile, key)
+	gzipWriter := gzip.NewWriter(encrypter)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = writeTarFile(tarWriter, walletBundleMetadataName, int64(len(metadataJSON)), bytes.NewReader(metadataJSON))
+	if err != nil {
+		return err
+	}
+
+	// the databases are open, they are copied in read transactions rather
+	// than read from their files which may be written to meanwhile
+	copyDbs := map[string]func(io.Writer) error{
+		walletDbName:   wallet.loader.CopyDB,
+		txindex.DbName: wallet.txDB.Copy,
+	}
+	for _, name := range walletBundleFiles {
+		err = writeTarDbCopy(tarWriter, name, bundleDir, copyDbs[name])
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return err
+	}
+	if err = encrypter.Close(); err != nil {
+		return err
+	}
+	if err = bundleFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(bundleFile.Name(), bundlePath)
+}
+
+// ImportWallet adds the wallet of a bundle saved by ExportWallet. The wallet
+// keeps the name it was exported with if walletName is empty.
+func (mw *MultiWallet) ImportWallet(walletName, bundlePath string, exportPassphrase []byte) (*Wallet, error) {
+	if !isDefaultDbDriver(mw.dbDriver) {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	// the files are extracted next to the wallet data directories and moved
+	// into the directory of the imported wallet
+	filesDir, err := ioutil.TempDir(mw.rootDir, ".import")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filesDir)
+
+	metadata, files, err := readWalletBundle(bundlePath, exportPassphrase, filesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Network != mw.chainParams.Name || files[walletDbName] == "" {
+		return nil, errors.New(ErrInvalid)
+	}
+
+	if walletName == "" {
+		walletName = metadata.Name
+	}
+
+	wallet := &Wallet{
+		Name:                  walletName,
+		CreatedAt:             metadata.CreatedAt,
+		EncryptedSeed:         metadata.EncryptedSeed,
+		IsRestored:            metadata.IsRestored,
+		HasDiscoveredAccounts: metadata.HasDiscoveredAccounts,
+		PrivatePassphraseType: metadata.PrivatePassphraseType,
+		Birthday:              metadata.Birthday,
+		BirthdayHeight:        metadata.BirthdayHeight,
+		Color:                 metadata.Color,
+		Icon:                  metadata.Icon,
+		Description:           metadata.Description,
+		Group:                 metadata.Group,
+	}
+
+	wallet, err = mw.saveNewWallet(wallet, func() error {
+		for name, path := range files {
+			err := os.Rename(path, filepath.Join(wallet.dataDir, name))
+			if err != nil {
+				return err
+			}
+		}
+
+		if files[txindex.DbName] != "" {
+			err := setTxIndexWalletID(filepath.Join(wallet.dataDir, txindex.DbName), wallet.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := wallet.prepare(mw.rootDir, mw.chainParams, mw.walletConfigSetFn(wallet.ID), mw.walletConfigReadFn(wallet.ID),
+			mw.walletPassphraseAttemptFn(wallet.ID), mw.publishWalletLockState)
+		if err != nil {
+			return err
+		}
+
+		return wallet.openWallet()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the records are saved once the wallet is saved, the batch transaction
+	// of saveNewWallet holds the database until then
+	err = mw.importWalletRecords(wallet, metadata)
+	if err != nil {
+		log.Errorf("[%d] Error importing wallet records: %v", wallet.ID, err)
+		if err2 := mw.wipeWallet(wallet.ID); err2 != nil {
+			log.Errorf("[%d] Error deleting partially imported wallet: %v", wallet.ID, err2)
+		}
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func (mw *MultiWallet) importWalletRecords(wallet *Wallet, metadata *walletBundleMetadata) error {
+	setConfigValue := mw.walletConfigSetFn(wallet.ID)
+	for key, value := range metadata.Config {
+		err := setConfigValue(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, account := range metadata.MultisigAccounts {
+		account.ID = 0
+		account.WalletID = wallet.ID
+		err := mw.db.Save(account)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walletConfigValues returns the config values of a wallet by their key
+// without the wallet ID prefix.
+func (mw *MultiWallet) walletConfigValues(walletID int) (map[string]json.RawMessage, error) {
+	prefix := strconv.Itoa(walletID)
+	config := make(map[string]json.RawMessage)
+	err := mw.db.Bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(userConfigBucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			key := string(k)
+			// skip nested buckets and the keys of wallets with IDs that
+			// start with the same digits
+			if v == nil || len(key) <= len(prefix) || key[:len(prefix)] != prefix ||
+				(key[len(prefix)] >= '0' && key[len(prefix)] <= '9') {
+				return nil
+			}
+
+			var value json.RawMessage
+			err := mw.dbCodec.Unmarshal(v, &value)
+			if err != nil {
+				return err
+			}
+			config[key[len(prefix):]] = value
+			return nil
+		})
+	})
+	return config, err
+}
+
+// readWalletBundle decrypts the bundle at bundlePath and extracts its files
+// into filesDir, it returns the metadata of the wallet and the paths of the
+// extracted files by their name.
+func readWalletBundle(bundlePath string, exportPassphrase []byte, filesDir string) (*walletBundleMetadata, map[string]string, error) {
+	bundleFile, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer bundleFile.Close()
+
+	header := make([]byte, len(walletBundleMagic)+1+dbEncryptionSaltSize)
+	_, err = io.ReadFull(bundleFile, header)
+	if err != nil || !bytes.HasPrefix(header, walletBundleMagic) || header[len(walletBundleMagic)] != walletBundleVersion {
+		return nil, nil, errors.New(ErrInvalid)
+	}
+
+	key, err := dbEncryptionKey(exportPassphrase, header[len(walletBundleMagic)+1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decrypter := newWalletBundleDecrypter(bundleFile, key)
+	// readErr returns the error of a chunk that can't be decrypted, the
+	// archive is invalid if the chunks could be decrypted
+	readErr := func() error {
+		if decrypter.err != nil {
+			return decrypter.err
+		}
+		return errors.New(ErrInvalid)
+	}
+
+	gzipReader, err := gzip.NewReader(decrypter)
+	if err != nil {
+		return nil, nil, readErr()
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	var metadata *walletBundleMetadata
+	files := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, readErr()
+		}
+
+		if header.Name == walletBundleMetadataName {
+			data, err := ioutil.ReadAll(tarReader)
+			if err != nil {
+				return nil, nil, readErr()
+			}
+
+			metadata = &walletBundleMetadata{}
+			if err = json.Unmarshal(data, metadata); err != nil {
+				return nil, nil, errors.New(ErrInvalid)
+			}
+			continue
+		}
+
+		for _, name := range walletBundleFiles {
+			if header.Name != name {
+				continue
+			}
+
+			path := filepath.Join(filesDir, name)
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return nil, nil, err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return nil, nil, readErr()
+			}
+			files[name] = path
+		}
+	}
+
+	// reading the rest of the archive checks the gzip checksum and that the
+	// last chunk of the bundle was read
+	if _, err = io.Copy(ioutil.Discard, gzipReader); err != nil {
+		return nil, nil, readErr()
+	}
+
+	if metadata == nil {
+		return nil, nil, errors.New(ErrInvalid)
+	}
+	return metadata, files, nil
+}
+
+func writeTarFile(tarWriter *tar.Writer, name string, size int64, data io.Reader) error {
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarWriter, data)
+	return err
+}
+
+// writeTarDbCopy copies a database with copyDb into a file in tempDir and
+// writes the copy to the archive, tar headers need the size of files.
+func writeTarDbCopy(tarWriter *tar.Writer, name, tempDir string, copyDb func(io.Writer) error) error {
+	dbCopy, err := ioutil.TempFile(tempDir, ".db")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dbCopy.Name())
+	defer dbCopy.Close()
+
+	if err = copyDb(dbCopy); err != nil {
+		return err
+	}
+
+	size, err := dbCopy.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = dbCopy.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return writeTarFile(tarWriter, name, size, dbCopy)
+}
+
+// setTxIndexWalletID sets the wallet ID of the transactions in the tx index at
+// txDbPath, the transactions of an imported wallet have the ID it had on the
+// device it was exported from.
+func setTxIndexWalletID(txDbPath string, walletID int) error {
+	txDB, err := storm.Open(txDbPath)
+	if err != nil {
+		return err
+	}
+	defer txDB.Close()
+
+	var transactions []*Transaction
+	err = txDB.All(&transactions)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	tx, err := txDB.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, transaction := range transactions {
+		transaction.WalletID = walletID
+		if err = tx.Save(transaction); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func isDefaultDbDriver(dbDriver string) bool {
+	return dbDriver == "" || dbDriver == "bdb"
+}
+
+// walletBundleChunkNonce returns the nonce of the chunk at index, the nonce
+// of the last chunk has the final flag set.
+func walletBundleChunkNonce(index uint64, final bool) nacl.Nonce {
+	nonce := new([nacl.NonceSize]byte)
+	binary.BigEndian.PutUint64(nonce[:8], index)
+	if final {
+		nonce[8] = 1
+	}
+	return nonce
+}
+
+// walletBundleEncrypter encrypts the data written to it in chunks of
+// walletBundleChunkSize, Close must be called to write the last chunk.
+type walletBundleEncrypter struct {
+	w     io.Writer
+	key   nacl.Key
+	chunk []byte
+	box   []byte
+	index uint64
+}
+
+func newWalletBundleEncrypter(w io.Writer, key nacl.Key) *walletBundleEncrypter {
+	return &walletBundleEncrypter{
+		w:     w,
+		key:   key,
+		chunk: make([]byte, 0, walletBundleChunkSize),
+		box:   make([]byte, 0, walletBundleChunkSize+secretbox.Overhead),
+	}
+}
+
+func (e *walletBundleEncrypter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// a full chunk is only sealed once more data is written, the
+		// last chunk is sealed by Close
+		if len(e.chunk) == walletBundleChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(e.chunk[len(e.chunk):walletBundleChunkSize], p)
+		e.chunk = e.chunk[:len(e.chunk)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *walletBundleEncrypter) Close() error {
+	return e.seal(true)
+}
+
+func (e *walletBundleEncrypter) seal(final bool) error {
+	e.box = secretbox.Seal(e.box[:0], e.chunk, walletBundleChunkNonce(e.index, final), e.key)
+	e.chunk = e.chunk[:0]
+	e.index++
+	_, err := e.w.Write(e.box)
+	return err
+}
+
+// walletBundleDecrypter decrypts the chunks written by walletBundleEncrypter,
+// it returns io.EOF once the last chunk is read. The error of a chunk that
+// can't be decrypted is kept in err.
+type walletBundleDecrypter struct {
+	r     *bufio.Reader
+	key   nacl.Key
+	box   []byte
+	chunk []byte
+	index uint64
+	final bool
+	err   error
+}
+
+func newWalletBundleDecrypter(r io.Reader, key nacl.Key) *walletBundleDecrypter {
+	return &walletBundleDecrypter{
+		r:     bufio.NewReader(r),
+		key:   key,
+		box:   make([]byte, walletBundleChunkSize+secretbox.Overhead),
+		chunk: make([]byte, 0, walletBundleChunkSize),
+	}
+}
+
+func (d *walletBundleDecrypter) Read(p []byte) (int, error) {
+	for len(d.chunk) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.final {
+			return 0, io.EOF
+		}
+		d.err = d.open()
+	}
+
+	n := copy(p, d.chunk)
+	d.chunk = d.chunk[n:]
+	return n, nil
+}
+
+func (d *walletBundleDecrypter) open() error {
+	n, err := io.ReadFull(d.r, d.box)
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		// only the last chunk can be shorter
+		d.final = true
+	case err == nil:
+		_, err = d.r.Peek(1)
+		if err == io.EOF {
+			d.final = true
+		} else if err != nil {
+			return err
+		}
+	case err == io.EOF:
+		return errors.New(ErrInvalid)
+	default:
+		return err
+	}
+
+	chunk, ok := secretbox.Open(d.chunk[:0], d.box[:n], walletBundleChunkNonce(d.index, d.final), d.key)
+	if !ok {
+		// a chunk that opens with the other flag is at the wrong place in
+		// a truncated or extended bundle, the passphrase is right
+		_, opened := secretbox.Open(d.chunk[:0], d.box[:n], walletBundleChunkNonce(d.index, !d.final), d.key)
+		if d.index == 0 && !opened {
+			return errors.New(ErrInvalidPassphrase)
+		}
+		return errors.New(ErrInvalid)
+	}
+
+	d.chunk = chunk
+	d.index++
+	return nil
+}
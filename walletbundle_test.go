@@ -0,0 +1,151 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/kevinburke/nacl/secretbox"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wallet bundles", func() {
+	const (
+		passphrase       = "1234"
+		exportPassphrase = "export passphrase"
+	)
+
+	var mw *MultiWallet
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("exports and imports wallets with their config and metadata", func() {
+		wallet, err := mw.CreateNewWallet("exported", passphrase, PassphraseTypePin)
+		Expect(err).To(BeNil())
+		Expect(wallet.setBirthday(1577836800, 0)).To(BeNil())
+		Expect(mw.db.Save(wallet)).To(BeNil())
+		wallet.SetStringConfigValueForKey("custom_key", "custom value")
+		Expect(mw.SetWalletMetadata(wallet.ID, "#2970ff", "savings", "Savings")).To(BeNil())
+		Expect(mw.SetWalletGroup(wallet.ID, "personal")).To(BeNil())
+		address, err := wallet.CurrentAddress(0)
+		Expect(err).To(BeNil())
+
+		bundlePath := filepath.Join(testRootDir(mw), "wallet.bundle")
+		Expect(mw.ExportWallet(wallet.ID, nil, bundlePath)).To(MatchError(ErrPassphraseRequired))
+		Expect(mw.ExportWallet(wallet.ID+1, []byte(exportPassphrase), bundlePath)).To(MatchError(ErrNotExist))
+		Expect(mw.ExportWallet(wallet.ID, []byte(exportPassphrase), bundlePath)).To(BeNil())
+
+		_, err = mw.ImportWallet("imported", bundlePath, []byte("wrong"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		_, err = mw.ImportWallet("", bundlePath, []byte(exportPassphrase))
+		Expect(err).To(MatchError(ErrExist))
+
+		imported, err := mw.ImportWallet("imported", bundlePath, []byte(exportPassphrase))
+		Expect(err).To(BeNil())
+		Expect(imported.ID).NotTo(Equal(wallet.ID))
+		Expect(imported.Name).To(Equal("imported"))
+		Expect(imported.CreatedAt.Equal(wallet.CreatedAt)).To(BeTrue())
+		Expect(imported.PrivatePassphraseType).To(Equal(PassphraseTypePin))
+		Expect(imported.HasDiscoveredAccounts).To(Equal(wallet.HasDiscoveredAccounts))
+		Expect(imported.Birthday.Equal(wallet.Birthday)).To(BeTrue())
+		Expect(imported.ReadStringConfigValueForKey("custom_key", "")).To(Equal("custom value"))
+		Expect(imported.Color).To(Equal("#2970ff"))
+		Expect(imported.Icon).To(Equal("savings"))
+		Expect(imported.Description).To(Equal("Savings"))
+		Expect(imported.Group).To(Equal("personal"))
+
+		importedAddress, err := imported.CurrentAddress(0)
+		Expect(err).To(BeNil())
+		Expect(importedAddress).To(Equal(address))
+		Expect(imported.UnlockWallet([]byte(passphrase))).To(BeNil())
+		imported.LockWallet()
+
+		seed, err := wallet.DecryptSeed([]byte(passphrase))
+		Expect(err).To(BeNil())
+		importedSeed, err := imported.DecryptSeed([]byte(passphrase))
+		Expect(err).To(BeNil())
+		Expect(importedSeed).To(Equal(seed))
+
+		By("Loading the imported wallet after a restart")
+		mw = reopenTestMultiWallet(mw)
+		Expect(mw.LoadedWalletsCount()).To(Equal(int32(2)))
+		Expect(mw.WalletWithID(imported.ID).Name).To(Equal("imported"))
+	})
+
+	It("rejects invalid bundles and bundles of other networks", func() {
+		bundlePath := filepath.Join(testRootDir(mw), "invalid.bundle")
+		Expect(ioutil.WriteFile(bundlePath, []byte("not a wallet bundle"), 0600)).To(BeNil())
+		_, err := mw.ImportWallet("", bundlePath, []byte(exportPassphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		wallet, err := mw.CreateNewWallet("testnet", passphrase, PassphraseTypePin)
+		Expect(err).To(BeNil())
+		bundlePath = filepath.Join(testRootDir(mw), "testnet.bundle")
+		Expect(mw.ExportWallet(wallet.ID, []byte(exportPassphrase), bundlePath)).To(BeNil())
+		mw.Shutdown()
+
+		mw = openTestMultiWallet(testRootDir(mw), "mainnet")
+		_, err = mw.ImportWallet("", bundlePath, []byte(exportPassphrase))
+		Expect(err).To(MatchError(ErrInvalid))
+		Expect(mw.LoadedWalletsCount()).To(BeZero())
+	})
+	It("encrypts bundles in chunks that can't be dropped or reordered", func() {
+		key, err := dbEncryptionKey([]byte(exportPassphrase), make([]byte, dbEncryptionSaltSize))
+		Expect(err).To(BeNil())
+		sealedChunkSize := walletBundleChunkSize + secretbox.Overhead
+
+		encrypt := func(data []byte) []byte {
+			var bundle bytes.Buffer
+			encrypter := newWalletBundleEncrypter(&bundle, key)
+			_, err := encrypter.Write(data)
+			Expect(err).To(BeNil())
+			Expect(encrypter.Close()).To(BeNil())
+			return bundle.Bytes()
+		}
+		decrypt := func(bundle []byte) ([]byte, error) {
+			return ioutil.ReadAll(newWalletBundleDecrypter(bytes.NewReader(bundle), key))
+		}
+
+		for _, size := range []int{0, 1, walletBundleChunkSize, 2*walletBundleChunkSize + 1} {
+			data := make([]byte, size)
+			_, err := rand.Read(data)
+			Expect(err).To(BeNil())
+
+			decrypted, err := decrypt(encrypt(data))
+			Expect(err).To(BeNil())
+			Expect(decrypted).To(HaveLen(size))
+			Expect(bytes.Equal(decrypted, data)).To(BeTrue())
+		}
+
+		bundle := encrypt(make([]byte, 2*walletBundleChunkSize+1))
+		_, err = decrypt(bundle[:2*sealedChunkSize])
+		Expect(err).To(MatchError(ErrInvalid))
+		_, err = decrypt(bundle[:len(bundle)-1])
+		Expect(err).To(MatchError(ErrInvalid))
+		reordered := append(append([]byte(nil), bundle[sealedChunkSize:2*sealedChunkSize]...), bundle[:sealedChunkSize]...)
+		_, err = decrypt(append(reordered, bundle[2*sealedChunkSize:]...))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		_, err = decrypt(append(append([]byte(nil), bundle[:sealedChunkSize]...), bundle[2*sealedChunkSize:]...))
+		Expect(err).To(MatchError(ErrInvalid))
+
+		By("Removing the temporary files of exports and imports")
+		wallet, err := mw.CreateNewWallet("wallet", passphrase, PassphraseTypePin)
+		Expect(err).To(BeNil())
+		bundlePath := filepath.Join(testRootDir(mw), "wallet.bundle")
+		Expect(mw.ExportWallet(wallet.ID, []byte(exportPassphrase), bundlePath)).To(BeNil())
+		_, err = mw.ImportWallet("imported", bundlePath, []byte("wrong"))
+		Expect(err).To(MatchError(ErrInvalidPassphrase))
+		tempFiles, err := filepath.Glob(filepath.Join(testRootDir(mw), ".*"))
+		Expect(err).To(BeNil())
+		Expect(tempFiles).To(BeEmpty())
+	})
+})
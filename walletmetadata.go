@@ -0,0 +1,179 @@
+package dcrlibwallet
+
+import (
+	"encoding/json"
+
+	"github.com/asdine/storm"
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+// SetWalletMetadata sets the color, icon and description apps show the wallet
+// with, apps choose the format of the values.
+func (mw *MultiWallet) SetWalletMetadata(walletID int, color, icon, description string) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	oldColor, oldIcon, oldDescription := wallet.Color, wallet.Icon, wallet.Description
+	wallet.Color, wallet.Icon, wallet.Description = color, icon, description
+	err := mw.db.Save(wallet)
+	if err != nil {
+		wallet.Color, wallet.Icon, wallet.Description = oldColor, oldIcon, oldDescription
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// SetWalletGroup adds the wallet to `group`, an empty group removes the
+// wallet from its group.
+func (mw *MultiWallet) SetWalletGroup(walletID int, group string) error {
+	wallet := mw.WalletWithID(walletID)
+	if wallet == nil {
+		return errors.New(ErrNotExist)
+	}
+
+	oldGroup := wallet.Group
+	wallet.Group = group
+	err := mw.db.Save(wallet)
+	if err != nil {
+		wallet.Group = oldGroup
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// WalletGroups returns the json-encoded groups of the wallets, in the order
+// of the first wallet of each group.
+func (mw *MultiWallet) WalletGroups() string {
+	jsonEncoded, _ := json.Marshal(mw.WalletGroupsRaw())
+	return string(jsonEncoded)
+}
+
+// WalletGroupsRaw returns the groups of the wallets, in the order of the
+// first wallet of each group.
+func (mw *MultiWallet) WalletGroupsRaw() []string {
+	groups := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, wallet := range mw.AllWallets() {
+		if wallet.Group != "" && !seen[wallet.Group] {
+			seen[wallet.Group] = true
+			groups = append(groups, wallet.Group)
+		}
+	}
+	return groups
+}
+
+// WalletIDsInGroup returns the json-encoded IDs of the wallets of `group` in
+// the user order, the wallets without a group for an empty group.
+func (mw *MultiWallet) WalletIDsInGroup(group string) string {
+	walletIDs := make([]int, 0)
+	for _, wallet := range mw.WalletsInGroup(group) {
+		walletIDs = append(walletIDs, wallet.ID)
+	}
+
+	jsonEncoded, _ := json.Marshal(walletIDs)
+	return string(jsonEncoded)
+}
+
+// WalletsInGroup returns the wallets of `group` in the user order, the wallets
+// without a group for an empty group.
+func (mw *MultiWallet) WalletsInGroup(group string) []*Wallet {
+	wallets := make([]*Wallet, 0)
+	for _, wallet := range mw.AllWallets() {
+		if wallet.Group == group {
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets
+}
+
+// ReorderWallets sets the order AllWallets and WalletsIterator return the
+// wallets in, walletIDs is a json-encoded list with the ID of every wallet
+// once.
+func (mw *MultiWallet) ReorderWallets(walletIDs string) error {
+	var walletIDList []int
+	if err := json.Unmarshal([]byte(walletIDs), &walletIDList); err != nil {
+		log.Error(err)
+		return errors.New(ErrInvalid)
+	}
+
+	return mw.ReorderWalletsRaw(walletIDList)
+}
+
+// ReorderWalletsRaw sets the order AllWallets and WalletsIterator return the
+// wallets in, walletIDs must have the ID of every wallet once.
+func (mw *MultiWallet) ReorderWalletsRaw(walletIDs []int) error {
+	if len(walletIDs) != len(mw.wallets) {
+		return errors.New(ErrInvalid)
+	}
+
+	seen := make(map[int]bool)
+	for _, walletID := range walletIDs {
+		if mw.WalletWithID(walletID) == nil || seen[walletID] {
+			return errors.New(ErrInvalid)
+		}
+		seen[walletID] = true
+	}
+
+	oldOrders := make(map[int]int32)
+	for _, wallet := range mw.wallets {
+		oldOrders[wallet.ID] = wallet.DisplayOrder
+	}
+
+	err := mw.batchDbTransaction(func(db storm.Node) error {
+		for i, walletID := range walletIDs {
+			wallet := mw.wallets[walletID]
+			wallet.DisplayOrder = int32(i)
+			if err := db.Save(wallet); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for walletID, displayOrder := range oldOrders {
+			mw.wallets[walletID].DisplayOrder = displayOrder
+		}
+		return translateError(err)
+	}
+
+	return nil
+}
+
+// MoveWallet moves the wallet to the 0-based `position` in the order of
+// AllWallets and WalletsIterator.
+func (mw *MultiWallet) MoveWallet(walletID int, position int32) error {
+	if mw.WalletWithID(walletID) == nil {
+		return errors.New(ErrNotExist)
+	}
+	if position < 0 || int(position) >= len(mw.wallets) {
+		return errors.New(ErrInvalid)
+	}
+
+	walletIDs := make([]int, 0, len(mw.wallets))
+	for _, wallet := range mw.AllWallets() {
+		if wallet.ID != walletID {
+			walletIDs = append(walletIDs, wallet.ID)
+		}
+	}
+
+	walletIDs = append(walletIDs[:position], append([]int{walletID}, walletIDs[position:]...)...)
+	return mw.ReorderWalletsRaw(walletIDs)
+}
+
+// nextWalletDisplayOrder returns the display order of new wallets, after the
+// existing wallets.
+func (mw *MultiWallet) nextWalletDisplayOrder() int32 {
+	var displayOrder int32
+	for _, wallets := range []map[int]*Wallet{mw.wallets, mw.hiddenWallets} {
+		for _, wallet := range wallets {
+			if wallet.DisplayOrder >= displayOrder {
+				displayOrder = wallet.DisplayOrder + 1
+			}
+		}
+	}
+	return displayOrder
+}
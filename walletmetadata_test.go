@@ -0,0 +1,97 @@
+package dcrlibwallet
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wallet metadata", func() {
+	var (
+		mw      *MultiWallet
+		wallets []*Wallet
+	)
+
+	walletIDs := func(wallets []*Wallet) []int {
+		ids := make([]int, 0, len(wallets))
+		for _, wallet := range wallets {
+			ids = append(ids, wallet.ID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+
+		wallets = nil
+		for _, name := range []string{"first", "second", "third"} {
+			wallet, err := mw.CreateNewWallet(name, "passphrase", PassphraseTypePass)
+			Expect(err).To(BeNil())
+			wallets = append(wallets, wallet)
+		}
+	})
+
+	AfterEach(func() {
+		removeTestMultiWallet(mw)
+	})
+
+	It("lists wallets in the user order", func() {
+		first, second, third := wallets[0].ID, wallets[1].ID, wallets[2].ID
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{first, second, third}))
+
+		Expect(mw.ReorderWalletsRaw([]int{third, first})).To(MatchError(ErrInvalid))
+		Expect(mw.ReorderWalletsRaw([]int{third, first, first})).To(MatchError(ErrInvalid))
+		Expect(mw.ReorderWalletsRaw([]int{first, third, second})).To(BeNil())
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{first, third, second}))
+
+		Expect(mw.ReorderWallets("not json")).To(MatchError(ErrInvalid))
+		Expect(mw.ReorderWallets(fmt.Sprintf("[%d, %d, %d]", third, first, second))).To(BeNil())
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{third, first, second}))
+
+		Expect(mw.MoveWallet(second, 3)).To(MatchError(ErrInvalid))
+		Expect(mw.MoveWallet(second, 0)).To(BeNil())
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{second, third, first}))
+
+		iterator := mw.WalletsIterator()
+		Expect(iterator.Next().ID).To(Equal(second))
+		Expect(iterator.Next().ID).To(Equal(third))
+		Expect(iterator.Next().ID).To(Equal(first))
+		Expect(iterator.Next()).To(BeNil())
+
+		wallet, err := mw.CreateNewWallet("fourth", "passphrase", PassphraseTypePass)
+		Expect(err).To(BeNil())
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{second, third, first, wallet.ID}))
+
+		mw = reopenTestMultiWallet(mw)
+		Expect(walletIDs(mw.AllWallets())).To(Equal([]int{second, third, first, wallet.ID}))
+	})
+
+	It("saves wallet metadata and groups", func() {
+		first, second, third := wallets[0].ID, wallets[1].ID, wallets[2].ID
+		Expect(mw.SetWalletMetadata(first+10, "#2970ff", "", "")).To(MatchError(ErrNotExist))
+		Expect(mw.SetWalletMetadata(first, "#2970ff", "savings", "Long term savings")).To(BeNil())
+
+		Expect(mw.WalletGroupsRaw()).To(BeEmpty())
+		Expect(mw.SetWalletGroup(third, "personal")).To(BeNil())
+		Expect(mw.SetWalletGroup(first, "business")).To(BeNil())
+		Expect(mw.SetWalletGroup(second, "personal")).To(BeNil())
+		Expect(mw.WalletGroupsRaw()).To(Equal([]string{"business", "personal"}))
+		Expect(walletIDs(mw.WalletsInGroup("personal"))).To(Equal([]int{second, third}))
+		Expect(mw.WalletGroups()).To(MatchJSON(`["business", "personal"]`))
+		Expect(mw.WalletIDsInGroup("personal")).To(MatchJSON(fmt.Sprintf("[%d, %d]", second, third)))
+
+		Expect(mw.SetWalletGroup(first, "")).To(BeNil())
+		Expect(mw.WalletGroupsRaw()).To(Equal([]string{"personal"}))
+		Expect(walletIDs(mw.WalletsInGroup(""))).To(Equal([]int{first}))
+
+		mw = reopenTestMultiWallet(mw)
+
+		wallet := mw.WalletWithID(first)
+		Expect(wallet.Color).To(Equal("#2970ff"))
+		Expect(wallet.Icon).To(Equal("savings"))
+		Expect(wallet.Description).To(Equal("Long term savings"))
+		Expect(wallet.Group).To(BeEmpty())
+		Expect(mw.WalletWithID(third).Group).To(Equal("personal"))
+	})
+})
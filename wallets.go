@@ -1,9 +1,20 @@
 package dcrlibwallet
 
+import "sort"
+
+// AllWallets returns the wallets in the order set with ReorderWallets. No
+// wallets are returned until the wallets of an encrypted database are loaded
+// by OpenWallets, LoadedWalletsCount tells if there are wallets to open.
 func (mw *MultiWallet) AllWallets() (wallets []*Wallet) {
 	for _, wallet := range mw.wallets {
 		wallets = append(wallets, wallet)
 	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].DisplayOrder != wallets[j].DisplayOrder {
+			return wallets[i].DisplayOrder < wallets[j].DisplayOrder
+		}
+		return wallets[i].ID < wallets[j].ID
+	})
 	return wallets
 }
 
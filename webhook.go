@@ -0,0 +1,505 @@
+package dcrlibwallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/decred/dcrwallet/errors/v2"
+)
+
+const (
+	WebhookEventSyncStarted   = "sync_started"
+	WebhookEventSyncProgress  = "sync_progress"
+	WebhookEventSyncCompleted = "sync_completed"
+	WebhookEventSyncCanceled  = "sync_canceled"
+	WebhookEventSyncError     = "sync_error"
+	WebhookEventPeers         = "peers"
+	WebhookEventTransaction   = "transaction"
+	WebhookEventBlock         = "block"
+
+	// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of the request
+	// body, keyed with the secret of the webhook the request is sent to.
+	WebhookSignatureHeader = "X-Dcrlibwallet-Signature"
+	WebhookEventHeader     = "X-Dcrlibwallet-Event"
+	WebhookDeliveryHeader  = "X-Dcrlibwallet-Delivery"
+
+	webhookListenerID     = "webhook_dispatcher"
+	webhookMaxAttempts    = 10
+	webhookRetryBaseDelay = 5 * time.Second
+	webhookRetryMaxDelay  = time.Hour
+	webhookIdleInterval   = time.Minute
+)
+
+// webhookDispatcher forwards wallet events to the registered webhooks.
+// Events are saved to an outbox in the multiwallet db before they are sent
+// and are only removed after a successful delivery or after
+// webhookMaxAttempts failed attempts, so undelivered events survive restarts.
+// The dispatcher runs while there are registered webhooks.
+type webhookDispatcher struct {
+	mw     *MultiWallet
+	client *http.Client
+
+	mu      sync.Mutex
+	started bool
+	wake    chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+
+	// progressMu guards lastSyncProgress, which is read and written by the
+	// sync progress callbacks.
+	progressMu       sync.Mutex
+	lastSyncProgress int32
+
+	// events are the events received by the listener callbacks, the
+	// delivery loop saves them to the outbox. The callbacks are called
+	// while the listeners lock is held and don't write to the db.
+	eventsMu sync.Mutex
+	events   []*webhookEvent
+}
+
+type webhookEvent struct {
+	event     string
+	payload   []byte
+	createdAt int64
+}
+
+type webhookPayload struct {
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+type webhookSyncProgress struct {
+	Stage    string      `json:"stage"`
+	Progress interface{} `json:"progress"`
+}
+
+func newWebhookDispatcher(mw *MultiWallet) *webhookDispatcher {
+	return &webhookDispatcher{
+		mw: mw,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		wake:             make(chan struct{}, 1),
+		lastSyncProgress: -1,
+	}
+}
+
+// AddWebhook registers a url to which wallet events are sent as JSON POST
+// requests signed with `secret`. The id of the new webhook is returned.
+// The secret is saved in the multiwallet db, which is only encrypted if a
+// startup passphrase is set.
+func (mw *MultiWallet) AddWebhook(webhookURL, secret string) (int, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return -1, errors.New(ErrInvalid)
+	}
+
+	var existing Webhook
+	err = mw.db.One("URL", webhookURL, &existing)
+	if err == nil {
+		return -1, errors.New(ErrExist)
+	} else if err != storm.ErrNotFound {
+		return -1, err
+	}
+
+	webhook := &Webhook{
+		URL:       webhookURL,
+		Secret:    secret,
+		CreatedAt: time.Now().Unix(),
+	}
+	err = mw.db.Save(webhook)
+	if err != nil {
+		return -1, err
+	}
+
+	err = mw.webhooks.start()
+	if err != nil {
+		return -1, err
+	}
+
+	return webhook.ID, nil
+}
+
+// RemoveWebhook removes the webhook with `webhookID` and discards the events
+// that are yet to be delivered to it. The dispatcher is stopped when the last
+// webhook is removed.
+func (mw *MultiWallet) RemoveWebhook(webhookID int) error {
+	var webhook Webhook
+	err := mw.db.One("ID", webhookID, &webhook)
+	if err != nil {
+		if err == storm.ErrNotFound {
+			return errors.New(ErrNotExist)
+		}
+		return err
+	}
+
+	err = mw.db.DeleteStruct(&webhook)
+	if err != nil {
+		return err
+	}
+
+	err = mw.db.Select(q.Eq("WebhookID", webhookID)).Delete(&WebhookDelivery{})
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+
+	return mw.webhooks.stopIfUnused()
+}
+
+func (mw *MultiWallet) Webhooks() (string, error) {
+	webhooks, err := mw.WebhooksRaw()
+	if err != nil {
+		return "", err
+	}
+
+	result, _ := json.Marshal(webhooks)
+	return string(result), nil
+}
+
+func (mw *MultiWallet) WebhooksRaw() ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	err := mw.db.All(&webhooks)
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// PendingWebhookDeliveries returns the number of events
+// in the outbox that are yet to be delivered.
+func (mw *MultiWallet) PendingWebhookDeliveries() (int, error) {
+	return mw.db.Count(&WebhookDelivery{})
+}
+
+// start registers the dispatcher as a sync progress and tx and block
+// event listener and begins delivering events from the outbox.
+// It is a no-op if the dispatcher is already started.
+func (d *webhookDispatcher) start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.started {
+		return nil
+	}
+
+	err := d.mw.AddTxAndBlockEventListener(d, webhookListenerID)
+	if err != nil {
+		return err
+	}
+
+	err = d.mw.AddSyncProgressListener(d, webhookListenerID)
+	if err != nil {
+		d.mw.RemoveTxAndBlockEventListener(webhookListenerID)
+		return err
+	}
+
+	d.started = true
+	d.quit = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.run()
+	return nil
+}
+
+// stop ends event delivery and waits for any in-flight request to complete.
+func (d *webhookDispatcher) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopLocked()
+}
+
+// stopIfUnused stops the dispatcher if there are no registered webhooks. The
+// webhooks are counted with d.mu held so that a webhook added meanwhile
+// can't be left without a running dispatcher.
+func (d *webhookDispatcher) stopIfUnused() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	webhooksCount, err := d.mw.db.Count(&Webhook{})
+	if err != nil {
+		return err
+	}
+	if webhooksCount == 0 {
+		d.stopLocked()
+	}
+	return nil
+}
+
+// stopLocked stops the dispatcher, d.mu must be held.
+func (d *webhookDispatcher) stopLocked() {
+	if !d.started {
+		return
+	}
+
+	d.mw.RemoveTxAndBlockEventListener(webhookListenerID)
+	d.mw.RemoveSyncProgressListener(webhookListenerID)
+
+	close(d.quit)
+	<-d.done
+	d.started = false
+}
+
+func (d *webhookDispatcher) run() {
+	defer close(d.done)
+
+	for {
+		d.saveEvents()
+		wait := d.deliverPending()
+
+		select {
+		case <-d.quit:
+			d.saveEvents()
+			return
+		case <-d.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// enqueue queues an event for the delivery loop to save to the outbox and
+// wakes the delivery loop.
+func (d *webhookDispatcher) enqueue(event string, data interface{}) {
+	now := time.Now().Unix()
+	payload, err := json.Marshal(&webhookPayload{
+		Event:     event,
+		Timestamp: now,
+		Data:      data,
+	})
+	if err != nil {
+		log.Errorf("Error encoding %s webhook event: %v", event, err)
+		return
+	}
+
+	d.eventsMu.Lock()
+	d.events = append(d.events, &webhookEvent{event, payload, now})
+	d.eventsMu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// saveEvents saves the queued events to the outbox for each registered
+// webhook.
+func (d *webhookDispatcher) saveEvents() {
+	d.eventsMu.Lock()
+	events := d.events
+	d.events = nil
+	d.eventsMu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	webhooks, err := d.mw.WebhooksRaw()
+	if err != nil {
+		log.Errorf("Error reading webhooks: %v", err)
+		return
+	}
+
+	for _, event := range events {
+		for _, webhook := range webhooks {
+			err = d.mw.db.Save(&WebhookDelivery{
+				WebhookID:   webhook.ID,
+				Event:       event.event,
+				Payload:     event.payload,
+				NextAttempt: event.createdAt,
+				CreatedAt:   event.createdAt,
+			})
+			if err != nil {
+				log.Errorf("Error saving %s webhook event: %v", event.event, err)
+			}
+		}
+	}
+}
+
+// deliverPending sends the outbox events that are due and returns
+// how long to wait before the next event is due.
+func (d *webhookDispatcher) deliverPending() time.Duration {
+	var deliveries []WebhookDelivery
+	err := d.mw.db.Select(q.Lte("NextAttempt", time.Now().Unix())).OrderBy("ID").Find(&deliveries)
+	if err != nil && err != storm.ErrNotFound {
+		log.Errorf("Error reading webhook outbox: %v", err)
+		return webhookIdleInterval
+	}
+
+	webhooks := make(map[int]*Webhook)
+	for i := range deliveries {
+		select {
+		case <-d.quit:
+			return 0
+		default:
+		}
+
+		delivery := &deliveries[i]
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook = &Webhook{}
+			if err = d.mw.db.One("ID", delivery.WebhookID, webhook); err != nil {
+				webhook = nil
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if webhook != nil {
+			err = d.send(webhook, delivery)
+			if err == nil {
+				d.mw.db.DeleteStruct(delivery)
+				continue
+			}
+
+			delivery.Attempts++
+			if delivery.Attempts < webhookMaxAttempts {
+				log.Warnf("Webhook %s delivery %d failed, will retry: %v", webhook.URL, delivery.ID, err)
+				delivery.NextAttempt = time.Now().Add(webhookRetryDelay(delivery.Attempts)).Unix()
+				if err = d.mw.db.Update(delivery); err != nil {
+					log.Errorf("Error updating webhook outbox: %v", err)
+				}
+				continue
+			}
+
+			log.Errorf("Webhook %s delivery %d failed after %d attempts: %v", webhook.URL, delivery.ID, delivery.Attempts, err)
+		}
+
+		// the webhook was removed or the event could not be delivered
+		d.mw.db.DeleteStruct(delivery)
+	}
+
+	var next WebhookDelivery
+	err = d.mw.db.Select(q.True()).OrderBy("NextAttempt").First(&next)
+	if err != nil {
+		return webhookIdleInterval
+	}
+
+	wait := time.Until(time.Unix(next.NextAttempt, 0))
+	if wait < 0 {
+		wait = 0
+	} else if wait > webhookIdleInterval {
+		wait = webhookIdleInterval
+	}
+	return wait
+}
+
+func (d *webhookDispatcher) send(webhook *Webhook, delivery *WebhookDelivery) error {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of `payload` keyed
+// with `secret`, as sent in the WebhookSignatureHeader of webhook requests.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay returns the exponential backoff delay before the next
+// delivery attempt after `attempts` failed attempts.
+func webhookRetryDelay(attempts int32) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMaxDelay {
+			return webhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// publishSyncProgress enqueues a sync progress event
+// only when the total sync progress changes.
+func (d *webhookDispatcher) publishSyncProgress(stage string, generalProgress *GeneralSyncProgress, progress interface{}) {
+	if generalProgress != nil {
+		d.progressMu.Lock()
+		changed := generalProgress.TotalSyncProgress != d.lastSyncProgress
+		d.lastSyncProgress = generalProgress.TotalSyncProgress
+		d.progressMu.Unlock()
+		if !changed {
+			return
+		}
+	}
+
+	d.enqueue(WebhookEventSyncProgress, &webhookSyncProgress{
+		Stage:    stage,
+		Progress: progress,
+	})
+}
+
+func (d *webhookDispatcher) OnSyncStarted(wasRestarted bool) {
+	d.progressMu.Lock()
+	d.lastSyncProgress = -1
+	d.progressMu.Unlock()
+	d.enqueue(WebhookEventSyncStarted, map[string]bool{"was_restarted": wasRestarted})
+}
+
+func (d *webhookDispatcher) OnPeerConnectedOrDisconnected(numberOfConnectedPeers int32) {
+	d.enqueue(WebhookEventPeers, map[string]int32{"connected_peers": numberOfConnectedPeers})
+}
+
+func (d *webhookDispatcher) OnHeadersFetchProgress(headersFetchProgress *HeadersFetchProgressReport) {
+	d.publishSyncProgress("headers_fetch", headersFetchProgress.GeneralSyncProgress, headersFetchProgress)
+}
+
+func (d *webhookDispatcher) OnAddressDiscoveryProgress(addressDiscoveryProgress *AddressDiscoveryProgressReport) {
+	d.publishSyncProgress("address_discovery", addressDiscoveryProgress.GeneralSyncProgress, addressDiscoveryProgress)
+}
+
+func (d *webhookDispatcher) OnHeadersRescanProgress(headersRescanProgress *HeadersRescanProgressReport) {
+	d.publishSyncProgress("headers_rescan", headersRescanProgress.GeneralSyncProgress, headersRescanProgress)
+}
+
+func (d *webhookDispatcher) OnSyncCompleted() {
+	d.enqueue(WebhookEventSyncCompleted, nil)
+}
+
+func (d *webhookDispatcher) OnSyncCanceled(willRestart bool) {
+	d.enqueue(WebhookEventSyncCanceled, map[string]bool{"will_restart": willRestart})
+}
+
+func (d *webhookDispatcher) OnSyncEndedWithError(err error) {
+	d.enqueue(WebhookEventSyncError, map[string]string{"error": err.Error()})
+}
+
+func (d *webhookDispatcher) Debug(debugInfo *DebugInfo) {}
+
+func (d *webhookDispatcher) OnTxEvent(event *TxEvent) {
+	d.enqueue(WebhookEventTransaction, event)
+}
+
+func (d *webhookDispatcher) OnBlockEvent(event *BlockEvent) {
+	d.enqueue(WebhookEventBlock, event)
+}
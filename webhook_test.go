@@ -0,0 +1,118 @@
+package dcrlibwallet
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type receivedWebhook struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+var _ = Describe("Webhooks", func() {
+	var (
+		mw       *MultiWallet
+		server   *httptest.Server
+		received chan *receivedWebhook
+		failing  int32
+	)
+
+	BeforeEach(func() {
+		mw = newTestMultiWallet("testnet3")
+
+		atomic.StoreInt32(&failing, 0)
+		received = make(chan *receivedWebhook, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&failing) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			received <- &receivedWebhook{
+				event:     r.Header.Get(WebhookEventHeader),
+				signature: r.Header.Get(WebhookSignatureHeader),
+				body:      body,
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		removeTestMultiWallet(mw)
+	})
+
+	It("rejects invalid and duplicate webhook urls", func() {
+		_, err := mw.AddWebhook("ftp://localhost", "secret")
+		Expect(err).To(MatchError(ErrInvalid))
+
+		_, err = mw.AddWebhook(server.URL, "secret")
+		Expect(err).To(BeNil())
+
+		_, err = mw.AddWebhook(server.URL, "secret")
+		Expect(err).To(MatchError(ErrExist))
+	})
+
+	It("delivers signed events to registered webhooks", func() {
+		_, err := mw.AddWebhook(server.URL, "secret")
+		Expect(err).To(BeNil())
+
+		webhooks, err := mw.Webhooks()
+		Expect(err).To(BeNil())
+		Expect(webhooks).NotTo(ContainSubstring("secret"))
+
+		mw.webhooks.OnBlockEvent(&BlockEvent{Type: BlockEventAttached, WalletID: 1, BlockHeight: 10})
+
+		var webhook *receivedWebhook
+		Eventually(received, 5*time.Second).Should(Receive(&webhook))
+		Expect(webhook.event).To(Equal(WebhookEventBlock))
+		Expect(webhook.signature).To(Equal(SignWebhookPayload("secret", webhook.body)))
+		Expect(webhook.signature).NotTo(Equal(SignWebhookPayload("other secret", webhook.body)))
+		Expect(string(webhook.body)).To(ContainSubstring(`"blockHeight":10`))
+
+		Eventually(mw.PendingWebhookDeliveries).Should(Equal(0))
+	})
+
+	It("keeps undelivered events in the outbox", func() {
+		atomic.StoreInt32(&failing, 1)
+		_, err := mw.AddWebhook(server.URL, "secret")
+		Expect(err).To(BeNil())
+
+		mw.webhooks.OnSyncCompleted()
+
+		Eventually(func() int32 {
+			var delivery WebhookDelivery
+			mw.db.One("Event", WebhookEventSyncCompleted, &delivery)
+			return delivery.Attempts
+		}, 5*time.Second).Should(Equal(int32(1)))
+		Expect(mw.PendingWebhookDeliveries()).To(Equal(1))
+	})
+
+	It("stops the dispatcher when the last webhook is removed", func() {
+		first, err := mw.AddWebhook(server.URL, "secret")
+		Expect(err).To(BeNil())
+		second, err := mw.AddWebhook(server.URL+"/second", "secret")
+		Expect(err).To(BeNil())
+
+		Expect(mw.RemoveWebhook(first)).To(Succeed())
+		Expect(mw.webhooks.started).To(BeTrue())
+
+		Expect(mw.RemoveWebhook(second)).To(Succeed())
+		Expect(mw.webhooks.started).To(BeFalse())
+		Expect(mw.AddTxAndBlockEventListener(&webhookDispatcher{}, webhookListenerID)).To(Succeed())
+	})
+
+	It("backs off exponentially between delivery attempts", func() {
+		Expect(webhookRetryDelay(1)).To(Equal(webhookRetryBaseDelay))
+		Expect(webhookRetryDelay(3)).To(Equal(4 * webhookRetryBaseDelay))
+		Expect(webhookRetryDelay(20)).To(Equal(webhookRetryMaxDelay))
+	})
+})